@@ -0,0 +1,3 @@
+module go_builder_pattern
+
+go 1.23
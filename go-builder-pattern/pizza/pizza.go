@@ -0,0 +1,440 @@
+// Simple Fluent Builder Pattern Implementation in Go
+//
+// The Fluent Builder pattern is a variation of the Builder pattern that allows
+// method chaining in any order to construct complex objects step by step.
+// It's particularly useful when you need to create objects with many optional
+// parameters or configurations.
+//
+// Key characteristics of this implementation:
+// • Method chaining (fluent interface) for readable code
+// • Runtime validation to ensure mandatory fields are set
+// • Flexible order of method calls
+// • Error handling for invalid states
+// • Director pattern for common configurations, extensible at runtime with named templates
+// • Optional dietary constraints validated at build time
+// • Allergen reporting with warnings (or errors in strict mode) for declared allergies
+// • Size-dependent default toppings seeded by NewSizedPizza, overridable by the chain
+// • Order builder accumulating several pizzas with quantities
+// • Structured logging of every build (built or rejected) through a package-level slog.Logger
+
+package pizza
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DietaryTag describes a dietary property of a pizza
+type DietaryTag string
+
+const (
+	Vegetarian DietaryTag = "Vegetarian" // No meat toppings
+	Vegan      DietaryTag = "Vegan"      // No meat and no animal-derived ingredients
+	GlutenFree DietaryTag = "GlutenFree" // Gluten-free crust
+)
+
+// meatToppings lists the toppings that are not vegetarian
+// Add future meat toppings here so dietary validation picks them up
+var meatToppings = map[string]bool{
+	"Pepperoni": true,
+}
+
+// animalAllergens lists the allergens that come from animal products, so an ingredient
+// containing one of them (e.g. the Dairy of a Stuffed crust) is not vegan
+var animalAllergens = map[string]bool{
+	"Dairy": true,
+	"Egg":   true,
+}
+
+// IngredientAllergens maps every ingredient (crust or topping) to the allergens it contains
+// It is a package variable so a shop can configure its own recipes; Allergens reads it on every call
+var IngredientAllergens = map[string][]string{
+	"Thin":          {"Gluten"},
+	"Thick":         {"Gluten"},
+	"Regular":       {"Gluten"},
+	"Stuffed":       {"Gluten", "Dairy"},
+	"Cheese":        {"Dairy"},
+	"Double Cheese": {"Dairy"},
+	"Pepperoni":     {"Sulphites"},
+}
+
+// SizeDefaults holds the toppings NewSizedPizza seeds for each size (only the topping fields are used)
+// It is a package variable so a shop can configure its own defaults; sizes missing from it get none
+var SizeDefaults = map[string]Pizza{
+	"Small":  {},
+	"Medium": {Cheese: true},
+	"Large":  {Cheese: true, DoubleCheese: true},
+}
+
+// Log receives a structured record for every Build, with the size, crust and toppings or the
+// reason the pizza was rejected. It discards them by default; set it to slog.Default() or any
+// other logger to observe the builders
+var Log = slog.New(discardHandler{})
+
+// discardHandler drops every record; as it enables no level, records are not even built
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// Pizza represents the complex object we want to build
+// It contains various properties that can be set independently
+type Pizza struct {
+	Size         string // Size of the pizza (e.g., "Small", "Medium", "Large")
+	Crust        string // Type of crust (e.g., "Thin", "Thick", "Stuffed")
+	Cheese       bool   // Whether cheese is added
+	DoubleCheese bool   // Whether the cheese portion is doubled, only meaningful together with Cheese
+	Pepperoni    bool   // Whether pepperoni is added
+	Mushrooms    bool   // Whether mushrooms are added
+}
+
+// Toppings returns the names of the toppings on the pizza
+func (p Pizza) Toppings() []string {
+	var toppings []string
+	if p.Cheese && p.DoubleCheese {
+		toppings = append(toppings, "Double Cheese")
+	} else if p.Cheese {
+		toppings = append(toppings, "Cheese")
+	}
+	if p.Pepperoni {
+		toppings = append(toppings, "Pepperoni")
+	}
+	if p.Mushrooms {
+		toppings = append(toppings, "Mushrooms")
+	}
+	return toppings
+}
+
+// DietaryTags derives the dietary tags that apply to the pizza from its crust and toppings
+// A vegetarian pizza is also vegan unless one of its allergens comes from animal products
+func (p Pizza) DietaryTags() []DietaryTag {
+	var tags []DietaryTag
+	if p.meatTopping() == "" {
+		tags = append(tags, Vegetarian)
+		if !slices.ContainsFunc(p.Allergens(), func(a string) bool { return animalAllergens[a] }) {
+			tags = append(tags, Vegan)
+		}
+	}
+	if p.Crust == "GlutenFree" {
+		tags = append(tags, GlutenFree)
+	}
+	return tags
+}
+
+// Allergens derives the allergens of the pizza from its crust and toppings using IngredientAllergens
+// The result is sorted and contains every allergen once; ingredients missing from the map add none
+func (p Pizza) Allergens() []string {
+	seen := make(map[string]bool)
+	var allergens []string
+	for _, ingredient := range append([]string{p.Crust}, p.Toppings()...) {
+		for _, allergen := range IngredientAllergens[ingredient] {
+			if !seen[allergen] {
+				seen[allergen] = true
+				allergens = append(allergens, allergen)
+			}
+		}
+	}
+	sort.Strings(allergens)
+	return allergens
+}
+
+// meatTopping returns the first meat topping on the pizza, or "" if it has none
+func (p Pizza) meatTopping() string {
+	for _, topping := range p.Toppings() {
+		if meatToppings[topping] {
+			return topping
+		}
+	}
+	return ""
+}
+
+// PizzaBuilder defines the interface for building pizza objects
+// Each method returns the builder itself to enable method chaining (fluent interface)
+// This allows for readable and flexible object construction
+type PizzaBuilder interface {
+	SetSize(size string) PizzaBuilder            // Sets the size of the pizza
+	SetCrust(crust string) PizzaBuilder          // Sets the crust type
+	AddCheese() PizzaBuilder                     // Adds cheese to the pizza
+	RemoveCheese() PizzaBuilder                  // Removes the cheese, including a double portion, from the pizza
+	AddPepperoni() PizzaBuilder                  // Adds pepperoni to the pizza
+	AddMushrooms() PizzaBuilder                  // Adds mushrooms to the pizza
+	RequireVegetarian() PizzaBuilder             // Makes Build fail if any meat topping is added
+	DeclareAllergy(allergen string) PizzaBuilder // Records a customer allergy that Build checks the pizza against
+	StrictAllergies() PizzaBuilder               // Makes Build fail instead of warn when a declared allergy matches
+	Build() (Pizza, error)                       // Finalizes and returns the constructed pizza with validation
+}
+
+// ConcretePizzaBuilder is the concrete implementation of the PizzaBuilder interface
+// It maintains the state of the pizza being built and provides methods to configure it
+type ConcretePizzaBuilder struct {
+	pizza      Pizza        // The pizza object being constructed
+	dietaryReq []DietaryTag // Dietary constraints enforced by Build
+	allergies  []string     // Customer allergies declared with DeclareAllergy
+	strict     bool         // Whether a matching allergy makes Build fail
+	warnings   []string     // Allergy warnings produced by the last Build
+}
+
+// NewSizedPizza returns a builder with the size set and the size's default toppings from SizeDefaults
+// applied (e.g. Large gets double cheese). The defaults are only a starting point: every later call
+// in the chain, such as RemoveCheese or AddMushrooms, overrides them
+func NewSizedPizza(size string) PizzaBuilder {
+	defaults := SizeDefaults[size]
+	return &ConcretePizzaBuilder{pizza: Pizza{
+		Size:         size,
+		Cheese:       defaults.Cheese,
+		DoubleCheese: defaults.DoubleCheese,
+		Pepperoni:    defaults.Pepperoni,
+		Mushrooms:    defaults.Mushrooms,
+	}}
+}
+
+// SetSize sets the size of the pizza and returns the builder for method chaining
+func (p *ConcretePizzaBuilder) SetSize(size string) PizzaBuilder {
+	p.pizza.Size = size
+	return p
+}
+
+// SetCrust sets the crust type of the pizza and returns the builder for method chaining
+func (p *ConcretePizzaBuilder) SetCrust(crust string) PizzaBuilder {
+	p.pizza.Crust = crust
+	return p
+}
+
+// AddCheese adds cheese to the pizza and returns the builder for method chaining
+func (p *ConcretePizzaBuilder) AddCheese() PizzaBuilder {
+	p.pizza.Cheese = true
+	return p
+}
+
+// RemoveCheese removes the cheese (single or double) and returns the builder for method chaining
+// Useful to override a default seeded by NewSizedPizza
+func (p *ConcretePizzaBuilder) RemoveCheese() PizzaBuilder {
+	p.pizza.Cheese = false
+	p.pizza.DoubleCheese = false
+	return p
+}
+
+// AddPepperoni adds pepperoni to the pizza and returns the builder for method chaining
+func (p *ConcretePizzaBuilder) AddPepperoni() PizzaBuilder {
+	p.pizza.Pepperoni = true
+	return p
+}
+
+// AddMushrooms adds mushrooms to the pizza and returns the builder for method chaining
+func (p *ConcretePizzaBuilder) AddMushrooms() PizzaBuilder {
+	p.pizza.Mushrooms = true
+	return p
+}
+
+// RequireVegetarian adds a dietary constraint and returns the builder for method chaining
+// Build then fails if any meat topping (e.g. pepperoni) is on the pizza
+func (p *ConcretePizzaBuilder) RequireVegetarian() PizzaBuilder {
+	p.dietaryReq = append(p.dietaryReq, Vegetarian)
+	return p
+}
+
+// DeclareAllergy records a customer allergy (e.g. "Dairy") and returns the builder for method chaining
+// Build then warns, or fails under StrictAllergies, if the pizza contains that allergen
+func (p *ConcretePizzaBuilder) DeclareAllergy(allergen string) PizzaBuilder {
+	p.allergies = append(p.allergies, allergen)
+	return p
+}
+
+// StrictAllergies makes Build fail instead of warn when a declared allergy matches the pizza
+func (p *ConcretePizzaBuilder) StrictAllergies() PizzaBuilder {
+	p.strict = true
+	return p
+}
+
+// Warnings returns the allergy warnings produced by the last Build, empty if none matched
+func (p *ConcretePizzaBuilder) Warnings() []string {
+	return p.warnings
+}
+
+// Build finalizes the construction and returns the completed pizza object
+// Validates that mandatory fields (Size and Crust) are set before building,
+// that the pizza satisfies every required dietary constraint and checks declared allergies
+// The outcome is logged to Log
+func (p *ConcretePizzaBuilder) Build() (Pizza, error) {
+	pizza, err := p.build()
+	if err != nil {
+		Log.Warn("pizza rejected", "size", p.pizza.Size, "crust", p.pizza.Crust, "error", err)
+		return pizza, err
+	}
+	Log.Info("pizza built", "size", pizza.Size, "crust", pizza.Crust, "toppings", pizza.Toppings(), "warnings", len(p.warnings))
+	return pizza, nil
+}
+
+// build validates the pizza and returns it, see Build
+func (p *ConcretePizzaBuilder) build() (Pizza, error) {
+	p.warnings = nil
+
+	// Validate mandatory field: Size
+	if p.pizza.Size == "" {
+		return Pizza{}, errors.New("pizza size is mandatory and cannot be empty")
+	}
+
+	// Validate mandatory field: Crust
+	if p.pizza.Crust == "" {
+		return Pizza{}, errors.New("pizza crust is mandatory and cannot be empty")
+	}
+
+	// Validate dietary constraints, naming the offending topping
+	for _, tag := range p.dietaryReq {
+		if tag == Vegetarian {
+			if meat := p.pizza.meatTopping(); meat != "" {
+				return Pizza{}, fmt.Errorf("pizza must be vegetarian but contains %s", meat)
+			}
+		}
+	}
+
+	// Check declared allergies: a warning per match, or an error in strict mode
+	allergens := p.pizza.Allergens()
+	for _, allergy := range p.allergies {
+		if !slices.Contains(allergens, allergy) {
+			continue
+		}
+		if p.strict {
+			return Pizza{}, fmt.Errorf("pizza contains %s, which the customer is allergic to", allergy)
+		}
+		p.warnings = append(p.warnings, fmt.Sprintf("pizza contains %s, which the customer is allergic to", allergy))
+	}
+
+	return p.pizza, nil
+}
+
+// Current returns a copy of the partially-built pizza without finalizing or validating it
+// Useful for logging the intermediate state while composing an order step by step
+// The returned value is a copy, so changing it does not affect the builder
+func (p *ConcretePizzaBuilder) Current() Pizza {
+	return p.pizza
+}
+
+// PizzaTemplate is a named recipe: the sequence of builder calls producing one kind of pizza
+type PizzaTemplate func(PizzaBuilder) (Pizza, error)
+
+// PizzaDirector provides a high-level interface for constructing specific types of pizzas
+// It encapsulates the logic for creating common pizza configurations
+// This is optional in the Builder pattern but helps create predefined objects easily
+// Recipes form a data-driven menu: Register adds templates at runtime and Create builds them by name
+// The zero value is ready to use and comes with the "margherita" and "mushroom" templates
+type PizzaDirector struct {
+	templates map[string]PizzaTemplate // Registered recipes by name, nil until first use
+}
+
+// menu returns the registered templates, pre-registering the default recipes on first use
+func (d *PizzaDirector) menu() map[string]PizzaTemplate {
+	if d.templates == nil {
+		d.templates = map[string]PizzaTemplate{
+			"margherita": d.CreateMargheritaPizza,
+			"mushroom":   d.CreateMushroomPizza,
+		}
+	}
+	return d.templates
+}
+
+// Register adds a named template to the menu, replacing any template with the same name
+func (d *PizzaDirector) Register(name string, fn func(PizzaBuilder) (Pizza, error)) {
+	d.menu()[name] = fn
+}
+
+// Create builds the pizza of the named template with the provided builder
+// Unknown names return an error listing the available templates
+func (d *PizzaDirector) Create(name string, pizzaBuilder PizzaBuilder) (Pizza, error) {
+	fn, ok := d.menu()[name]
+	if !ok {
+		return Pizza{}, fmt.Errorf("unknown pizza template %q, available: %s", name, strings.Join(d.Menu(), ", "))
+	}
+	return fn(pizzaBuilder)
+}
+
+// Menu returns the names of the registered templates in alphabetical order
+func (d *PizzaDirector) Menu() []string {
+	names := make([]string, 0, len(d.menu()))
+	for name := range d.menu() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CreateMargheritaPizza creates a classic Margherita pizza using the provided builder
+// Margherita pizza: Large size, thin crust, with cheese
+func (d *PizzaDirector) CreateMargheritaPizza(pizzaBuilder PizzaBuilder) (Pizza, error) {
+	return pizzaBuilder.SetSize("Large").SetCrust("Thin").AddCheese().Build()
+}
+
+// CreateMushroomPizza creates a mushroom pizza using the provided builder
+// Mushroom pizza: Large size, thin crust, with mushrooms
+func (d *PizzaDirector) CreateMushroomPizza(pizzaBuilder PizzaBuilder) (Pizza, error) {
+	return pizzaBuilder.SetSize("Large").SetCrust("Thin").AddMushrooms().Build()
+}
+
+// OrderLine is one pizza configuration of an order and how many of it were ordered
+type OrderLine struct {
+	Pizza Pizza // The ordered pizza
+	Qty   int   // Number of identical pizzas, always > 0 in a built order
+}
+
+// Order is the result of the OrderBuilder: the ordered pizzas with their quantities
+type Order []OrderLine
+
+// TotalQuantity returns the total number of pizzas in the order
+// Note: pizzas carry no price yet, so the order total is a count; price it here once they do
+func (o Order) TotalQuantity() int {
+	total := 0
+	for _, line := range o {
+		total += line.Qty
+	}
+	return total
+}
+
+// OrderBuilder accumulates pizzas built with a PizzaBuilder or the director into an Order
+// It is fluent like the pizza builder: errors are collected and reported by Build
+type OrderBuilder struct {
+	lines []OrderLine
+	err   error // First invalid AddPizza call, reported by Build
+}
+
+// NewOrderBuilder creates an empty order builder
+func NewOrderBuilder() *OrderBuilder {
+	return &OrderBuilder{}
+}
+
+// AddPizza adds qty pizzas to the order and returns the builder for method chaining
+// Adding an identical pizza again increases the quantity of its existing line
+func (o *OrderBuilder) AddPizza(p Pizza, qty int) *OrderBuilder {
+	if qty <= 0 {
+		if o.err == nil {
+			o.err = fmt.Errorf("quantity must be greater than 0, got %d for %s %s pizza", qty, p.Size, p.Crust)
+		}
+		return o
+	}
+	for i := range o.lines {
+		if o.lines[i].Pizza == p {
+			o.lines[i].Qty += qty
+			return o
+		}
+	}
+	o.lines = append(o.lines, OrderLine{Pizza: p, Qty: qty})
+	return o
+}
+
+// Build finalizes the order
+// Validates that every quantity was positive and that the order is not empty
+func (o *OrderBuilder) Build() (Order, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+	if len(o.lines) == 0 {
+		return nil, errors.New("order must contain at least one pizza")
+	}
+	return append(Order(nil), o.lines...), nil
+}
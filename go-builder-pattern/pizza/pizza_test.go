@@ -0,0 +1,69 @@
+package pizza_test
+
+import (
+	"slices"
+	"testing"
+
+	"go_builder_pattern/pizza"
+	"go_builder_pattern/pizza/pizzatest"
+)
+
+// TestConcretePizzaBuilderContract checks the package's own builder against the contract
+func TestConcretePizzaBuilderContract(t *testing.T) {
+	pizzatest.AssertBuilderContract(t, func() pizza.PizzaBuilder { return &pizza.ConcretePizzaBuilder{} })
+}
+
+// TestNewSizedPizzaDefaults checks that NewSizedPizza seeds the toppings of its size and that
+// explicit calls in the chain override them
+func TestNewSizedPizzaDefaults(t *testing.T) {
+	tests := []struct {
+		name  string
+		size  string
+		tweak func(b pizza.PizzaBuilder) pizza.PizzaBuilder
+		want  []string
+	}{
+		{"small defaults", "Small", nil, nil},
+		{"medium defaults", "Medium", nil, []string{"Cheese"}},
+		{"large defaults", "Large", nil, []string{"Double Cheese"}},
+		{"unknown size has none", "Family", nil, nil},
+		{"remove overrides double cheese", "Large", func(b pizza.PizzaBuilder) pizza.PizzaBuilder { return b.RemoveCheese() }, nil},
+		{"remove overrides cheese", "Medium", func(b pizza.PizzaBuilder) pizza.PizzaBuilder { return b.RemoveCheese().AddMushrooms() }, []string{"Mushrooms"}},
+		{"add overrides no cheese", "Small", func(b pizza.PizzaBuilder) pizza.PizzaBuilder { return b.AddCheese() }, []string{"Cheese"}},
+		{"re-adding after remove is single cheese", "Large", func(b pizza.PizzaBuilder) pizza.PizzaBuilder { return b.RemoveCheese().AddCheese() }, []string{"Cheese"}},
+		{"toppings add to the defaults", "Large", func(b pizza.PizzaBuilder) pizza.PizzaBuilder { return b.AddPepperoni() }, []string{"Double Cheese", "Pepperoni"}},
+		{"size change keeps the seeded toppings", "Large", func(b pizza.PizzaBuilder) pizza.PizzaBuilder { return b.SetSize("Small") }, []string{"Double Cheese"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := pizza.NewSizedPizza(tt.size).SetCrust("Thin")
+			if tt.tweak != nil {
+				b = tt.tweak(b)
+			}
+			p, err := b.Build()
+			if err != nil {
+				t.Fatalf("Build() = %v", err)
+			}
+			if !slices.Equal(p.Toppings(), tt.want) {
+				t.Errorf("toppings %v, want %v", p.Toppings(), tt.want)
+			}
+		})
+	}
+}
+
+// TestSizeDefaultsConfigurable checks that a shop's own SizeDefaults are seeded
+func TestSizeDefaultsConfigurable(t *testing.T) {
+	previous := pizza.SizeDefaults
+	defer func() { pizza.SizeDefaults = previous }()
+	pizza.SizeDefaults = map[string]pizza.Pizza{"Small": {Mushrooms: true}}
+
+	p, err := pizza.NewSizedPizza("Small").SetCrust("Thin").Build()
+	if err != nil {
+		t.Fatalf("Build() = %v", err)
+	}
+	if want := []string{"Mushrooms"}; !slices.Equal(p.Toppings(), want) {
+		t.Errorf("toppings %v, want %v", p.Toppings(), want)
+	}
+	if p, err = pizza.NewSizedPizza("Large").SetCrust("Thin").Build(); err != nil || len(p.Toppings()) != 0 {
+		t.Errorf("Large without configured defaults: toppings %v, err %v; want none", p.Toppings(), err)
+	}
+}
@@ -0,0 +1,107 @@
+// Package pizzatest provides AssertBuilderContract, which checks a pizza.PizzaBuilder
+// implementation against the rules of the pizza package from the implementer's own tests:
+// • Every setter returns a usable builder so calls can be chained (fluent interface)
+// • Build fails when a mandatory field (Size or Crust) is missing
+// • Build enforces requested dietary constraints
+// • Build rejects declared allergies in strict mode
+// • Build returns a pizza reflecting every method called in the chain
+// • RemoveCheese overrides an earlier AddCheese
+package pizzatest
+
+import (
+	"testing"
+
+	"go_builder_pattern/pizza"
+)
+
+// AssertBuilderContract verifies that builders created by newBuilder honour the PizzaBuilder contract
+// newBuilder must return a fresh, empty builder on every call
+func AssertBuilderContract(t testing.TB, newBuilder func() pizza.PizzaBuilder) {
+	t.Helper()
+
+	// Fluent invariant: every setter returns a non-nil builder
+	chain := map[string]func(pizza.PizzaBuilder) pizza.PizzaBuilder{
+		"SetSize":           func(b pizza.PizzaBuilder) pizza.PizzaBuilder { return b.SetSize("Large") },
+		"SetCrust":          func(b pizza.PizzaBuilder) pizza.PizzaBuilder { return b.SetCrust("Thin") },
+		"AddCheese":         func(b pizza.PizzaBuilder) pizza.PizzaBuilder { return b.AddCheese() },
+		"RemoveCheese":      func(b pizza.PizzaBuilder) pizza.PizzaBuilder { return b.RemoveCheese() },
+		"AddPepperoni":      func(b pizza.PizzaBuilder) pizza.PizzaBuilder { return b.AddPepperoni() },
+		"AddMushrooms":      func(b pizza.PizzaBuilder) pizza.PizzaBuilder { return b.AddMushrooms() },
+		"RequireVegetarian": func(b pizza.PizzaBuilder) pizza.PizzaBuilder { return b.RequireVegetarian() },
+		"DeclareAllergy":    func(b pizza.PizzaBuilder) pizza.PizzaBuilder { return b.DeclareAllergy("Dairy") },
+		"StrictAllergies":   func(b pizza.PizzaBuilder) pizza.PizzaBuilder { return b.StrictAllergies() },
+	}
+	for name, call := range chain {
+		if call(newBuilder()) == nil {
+			t.Errorf("%s returned a nil builder, method chaining is not possible", name)
+		}
+	}
+
+	// Mandatory field: Size
+	if _, err := newBuilder().SetCrust("Thin").AddCheese().Build(); err == nil {
+		t.Errorf("Build succeeded without a size, want an error")
+	}
+
+	// Mandatory field: Crust
+	if _, err := newBuilder().SetSize("Large").AddCheese().Build(); err == nil {
+		t.Errorf("Build succeeded without a crust, want an error")
+	}
+
+	// Empty builder
+	if _, err := newBuilder().Build(); err == nil {
+		t.Errorf("Build succeeded on an empty builder, want an error")
+	}
+
+	// A full chain builds a pizza with every option that was set
+	got, err := newBuilder().SetSize("Medium").SetCrust("Thick").AddCheese().AddPepperoni().AddMushrooms().Build()
+	if err != nil {
+		t.Fatalf("Build failed on a complete pizza: %v", err)
+	}
+	want := pizza.Pizza{Size: "Medium", Crust: "Thick", Cheese: true, Pepperoni: true, Mushrooms: true}
+	if got != want {
+		t.Errorf("Build returned %+v, want %+v", got, want)
+	}
+
+	// Chain order is free: the same options in another order give the same pizza
+	reordered, err := newBuilder().AddMushrooms().AddPepperoni().AddCheese().SetCrust("Thick").SetSize("Medium").Build()
+	if err != nil {
+		t.Fatalf("Build failed on a reordered chain: %v", err)
+	}
+	if reordered != want {
+		t.Errorf("Build with a reordered chain returned %+v, want %+v", reordered, want)
+	}
+
+	// Dietary constraints: a vegetarian requirement rejects meat but accepts a veggie pizza
+	if _, err := newBuilder().RequireVegetarian().SetSize("Large").SetCrust("Thin").AddPepperoni().Build(); err == nil {
+		t.Errorf("Build succeeded with pepperoni on a vegetarian pizza, want an error")
+	}
+	if _, err := newBuilder().RequireVegetarian().SetSize("Large").SetCrust("Thin").AddMushrooms().Build(); err != nil {
+		t.Errorf("Build failed on a vegetarian pizza: %v", err)
+	}
+
+	// Allergies: strict mode rejects a pizza containing a declared allergen, accepts one without it
+	if _, err := newBuilder().DeclareAllergy("Dairy").StrictAllergies().SetSize("Large").SetCrust("Thin").AddCheese().Build(); err == nil {
+		t.Errorf("Build succeeded with cheese despite a strict dairy allergy, want an error")
+	}
+	if _, err := newBuilder().DeclareAllergy("Dairy").StrictAllergies().SetSize("Large").SetCrust("Thin").AddMushrooms().Build(); err != nil {
+		t.Errorf("Build failed on a dairy-free pizza with a strict dairy allergy: %v", err)
+	}
+
+	// Removing cheese undoes adding it, whatever was added before
+	noCheese, err := newBuilder().SetSize("Large").SetCrust("Thin").AddCheese().RemoveCheese().Build()
+	if err != nil {
+		t.Fatalf("Build failed on a pizza with the cheese removed: %v", err)
+	}
+	if noCheese.Cheese || noCheese.DoubleCheese {
+		t.Errorf("Build kept the cheese after RemoveCheese: %+v", noCheese)
+	}
+
+	// Optional toppings stay off unless requested
+	plain, err := newBuilder().SetSize("Small").SetCrust("Thin").Build()
+	if err != nil {
+		t.Fatalf("Build failed on a plain pizza: %v", err)
+	}
+	if plain.Cheese || plain.DoubleCheese || plain.Pepperoni || plain.Mushrooms {
+		t.Errorf("Build added toppings that were not requested: %+v", plain)
+	}
+}
@@ -1,127 +1,30 @@
-// Simple Fluent Builder Pattern Implementation in Go
+// Simple Fluent Builder Pattern demonstration
 //
-// The Fluent Builder pattern is a variation of the Builder pattern that allows
-// method chaining in any order to construct complex objects step by step.
-// It's particularly useful when you need to create objects with many optional
-// parameters or configurations.
-//
-// Key characteristics of this implementation:
-// • Method chaining (fluent interface) for readable code
-// • Runtime validation to ensure mandatory fields are set
-// • Flexible order of method calls
-// • Error handling for invalid states
-// • Director pattern for common configurations
+// Runs the pizza builder of the pizza package through its features: Director recipes, the
+// runtime menu, dietary and allergen checks, size defaults, orders and build logging.
 
 package main
 
 import (
-	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+
+	"go_builder_pattern/pizza"
 )
 
 func main() {
 	demonstrateFluentBuilder()
 }
 
-// Pizza represents the complex object we want to build
-// It contains various properties that can be set independently
-type Pizza struct {
-	Size      string // Size of the pizza (e.g., "Small", "Medium", "Large")
-	Crust     string // Type of crust (e.g., "Thin", "Thick", "Stuffed")
-	Cheese    bool   // Whether cheese is added
-	Pepperoni bool   // Whether pepperoni is added
-	Mushrooms bool   // Whether mushrooms are added
-}
-
-// PizzaBuilder defines the interface for building pizza objects
-// Each method returns the builder itself to enable method chaining (fluent interface)
-// This allows for readable and flexible object construction
-type PizzaBuilder interface {
-	SetSize(size string) PizzaBuilder   // Sets the size of the pizza
-	SetCrust(crust string) PizzaBuilder // Sets the crust type
-	AddCheese() PizzaBuilder            // Adds cheese to the pizza
-	AddPepperoni() PizzaBuilder         // Adds pepperoni to the pizza
-	AddMushrooms() PizzaBuilder         // Adds mushrooms to the pizza
-	Build() (Pizza, error)              // Finalizes and returns the constructed pizza with validation
-}
-
-// ConcretePizzaBuilder is the concrete implementation of the PizzaBuilder interface
-// It maintains the state of the pizza being built and provides methods to configure it
-type ConcretePizzaBuilder struct {
-	pizza Pizza // The pizza object being constructed
-}
-
-// SetSize sets the size of the pizza and returns the builder for method chaining
-func (p *ConcretePizzaBuilder) SetSize(size string) PizzaBuilder {
-	p.pizza.Size = size
-	return p
-}
-
-// SetCrust sets the crust type of the pizza and returns the builder for method chaining
-func (p *ConcretePizzaBuilder) SetCrust(crust string) PizzaBuilder {
-	p.pizza.Crust = crust
-	return p
-}
-
-// AddCheese adds cheese to the pizza and returns the builder for method chaining
-func (p *ConcretePizzaBuilder) AddCheese() PizzaBuilder {
-	p.pizza.Cheese = true
-	return p
-}
-
-// AddPepperoni adds pepperoni to the pizza and returns the builder for method chaining
-func (p *ConcretePizzaBuilder) AddPepperoni() PizzaBuilder {
-	p.pizza.Pepperoni = true
-	return p
-}
-
-// AddMushrooms adds mushrooms to the pizza and returns the builder for method chaining
-func (p *ConcretePizzaBuilder) AddMushrooms() PizzaBuilder {
-	p.pizza.Mushrooms = true
-	return p
-}
-
-// Build finalizes the construction and returns the completed pizza object
-// Validates that mandatory fields (Size and Crust) are set before building
-func (p *ConcretePizzaBuilder) Build() (Pizza, error) {
-	// Validate mandatory field: Size
-	if p.pizza.Size == "" {
-		return Pizza{}, errors.New("pizza size is mandatory and cannot be empty")
-	}
-
-	// Validate mandatory field: Crust
-	if p.pizza.Crust == "" {
-		return Pizza{}, errors.New("pizza crust is mandatory and cannot be empty")
-	}
-
-	return p.pizza, nil
-}
-
-// PizzaDirector provides a high-level interface for constructing specific types of pizzas
-// It encapsulates the logic for creating common pizza configurations
-// This is optional in the Builder pattern but helps create predefined objects easily
-type PizzaDirector struct{}
-
-// CreateMargheritaPizza creates a classic Margherita pizza using the provided builder
-// Margherita pizza: Large size, thin crust, with cheese
-func (d *PizzaDirector) CreateMargheritaPizza(pizzaBuilder PizzaBuilder) (Pizza, error) {
-	return pizzaBuilder.SetSize("Large").SetCrust("Thin").AddCheese().Build()
-}
-
-// CreateMushroomPizza creates a mushroom pizza using the provided builder
-// Mushroom pizza: Large size, thin crust, with mushrooms
-func (d *PizzaDirector) CreateMushroomPizza(pizzaBuilder PizzaBuilder) (Pizza, error) {
-	return pizzaBuilder.SetSize("Large").SetCrust("Thin").AddMushrooms().Build()
-}
-
 // demonstrateFluentBuilder demonstrates the simple fluent builder pattern
 func demonstrateFluentBuilder() {
 	fmt.Println("=== SIMPLE FLUENT BUILDER PATTERN DEMONSTRATION ===")
 	fmt.Println()
 
 	// Create instances of the builder and director
-	builder := &ConcretePizzaBuilder{}
-	director := &PizzaDirector{}
+	builder := &pizza.ConcretePizzaBuilder{}
+	director := &pizza.PizzaDirector{}
 
 	// Example 1: Using the Director to create predefined pizzas
 	// The director encapsulates common pizza configurations
@@ -156,19 +59,139 @@ func demonstrateFluentBuilder() {
 			customPizza.Size, customPizza.Crust, customPizza.Cheese, customPizza.Pepperoni, customPizza.Mushrooms)
 	}
 
+	fmt.Println("\n=== Inspecting a Pizza In Progress ===")
+
+	// Example 3: Log the intermediate state while composing an order from several sources
+	// Current() neither validates nor finalizes, so it works before mandatory fields are set
+	inProgress := &pizza.ConcretePizzaBuilder{}
+	inProgress.SetSize("Medium").AddCheese()
+	fmt.Printf("After size config: %+v\n", inProgress.Current())
+	inProgress.SetCrust("Stuffed").AddPepperoni()
+	fmt.Printf("After crust config: %+v\n", inProgress.Current())
+
+	fmt.Println("\n=== Dietary Constraints ===")
+
+	// Example 4: Enforce a vegetarian order at build time
+	veggie, err := (&pizza.ConcretePizzaBuilder{}).RequireVegetarian().SetSize("Medium").SetCrust("GlutenFree").AddMushrooms().Build()
+	if err != nil {
+		fmt.Printf("Error creating Veggie pizza: %v\n", err)
+	} else {
+		fmt.Printf("Veggie Pizza: Toppings=%v, Tags=%v\n", veggie.Toppings(), veggie.DietaryTags())
+	}
+
+	_, err = (&pizza.ConcretePizzaBuilder{}).RequireVegetarian().SetSize("Medium").SetCrust("Thin").AddCheese().AddPepperoni().Build()
+	if err != nil {
+		fmt.Printf("Validation error (vegetarian): %v\n", err)
+	}
+
+	// A stuffed crust contains dairy, so a pizza without cheese on it is still not vegan
+	stuffed, _ := (&pizza.ConcretePizzaBuilder{}).SetSize("Medium").SetCrust("Stuffed").AddMushrooms().Build()
+	fmt.Printf("Stuffed crust without cheese: Tags=%v\n", stuffed.DietaryTags())
+
+	fmt.Println("\n=== Multi-Pizza Order ===")
+
+	// Example 5: Combine director and builder pizzas into one order with quantities
+	margheritaForOrder, _ := director.CreateMargheritaPizza(&pizza.ConcretePizzaBuilder{})
+	veggieForOrder, _ := (&pizza.ConcretePizzaBuilder{}).SetSize("Medium").SetCrust("Thin").AddMushrooms().Build()
+	order, err := pizza.NewOrderBuilder().
+		AddPizza(margheritaForOrder, 2).
+		AddPizza(veggieForOrder, 1).
+		AddPizza(margheritaForOrder, 1). // Same pizza again: merged into the first line
+		Build()
+	if err != nil {
+		fmt.Printf("Error creating order: %v\n", err)
+	} else {
+		for _, line := range order {
+			fmt.Printf("%dx %s %s pizza with %v\n", line.Qty, line.Pizza.Size, line.Pizza.Crust, line.Pizza.Toppings())
+		}
+		fmt.Printf("Total pizzas: %d\n", order.TotalQuantity())
+	}
+
+	_, err = pizza.NewOrderBuilder().AddPizza(veggieForOrder, 0).Build()
+	if err != nil {
+		fmt.Printf("Validation error (quantity): %v\n", err)
+	}
+
+	fmt.Println("\n=== Data-Driven Menu (Director Templates) ===")
+
+	// Example 6: Extend the director's menu at runtime and build pizzas by name
+	director.Register("pepperoni", func(b pizza.PizzaBuilder) (pizza.Pizza, error) {
+		return b.SetSize("Medium").SetCrust("Thick").AddCheese().AddPepperoni().Build()
+	})
+	fmt.Printf("Menu: %v\n", director.Menu())
+	for _, name := range []string{"pepperoni", "margherita", "hawaiian"} {
+		menuPizza, err := director.Create(name, &pizza.ConcretePizzaBuilder{})
+		if err != nil {
+			fmt.Printf("Error creating %s pizza: %v\n", name, err)
+			continue
+		}
+		fmt.Printf("%s: Size=%s, Crust=%s, Toppings=%v\n", name, menuPizza.Size, menuPizza.Crust, menuPizza.Toppings())
+	}
+
 	fmt.Println("\n=== Validation Examples ===")
 
-	// Example 3: Demonstrate validation - missing size
-	invalidBuilder1 := &ConcretePizzaBuilder{}
+	// Example 7: Demonstrate validation - missing size
+	invalidBuilder1 := &pizza.ConcretePizzaBuilder{}
 	_, err = invalidBuilder1.SetCrust("Thin").AddCheese().Build()
 	if err != nil {
 		fmt.Printf("Validation error (missing size): %v\n", err)
 	}
 
-	// Example 4: Demonstrate validation - missing crust
-	invalidBuilder2 := &ConcretePizzaBuilder{}
+	// Example 8: Demonstrate validation - missing crust
+	invalidBuilder2 := &pizza.ConcretePizzaBuilder{}
 	_, err = invalidBuilder2.SetSize("Large").AddCheese().Build()
 	if err != nil {
 		fmt.Printf("Validation error (missing crust): %v\n", err)
 	}
+
+	fmt.Println("\n=== Allergen Reporting ===")
+
+	// Example 9: Derive allergens and check them against a customer's declared allergies
+	cheesy := &pizza.ConcretePizzaBuilder{}
+	cheesyPizza, err := cheesy.DeclareAllergy("Dairy").SetSize("Large").SetCrust("Stuffed").AddCheese().AddMushrooms().Build()
+	if err != nil {
+		fmt.Printf("Error creating Cheesy pizza: %v\n", err)
+	} else {
+		fmt.Printf("Cheesy Pizza: Allergens=%v, Warnings=%v\n", cheesyPizza.Allergens(), cheesy.Warnings())
+	}
+
+	_, err = (&pizza.ConcretePizzaBuilder{}).DeclareAllergy("Gluten").StrictAllergies().SetSize("Small").SetCrust("Thin").Build()
+	if err != nil {
+		fmt.Printf("Validation error (strict allergy): %v\n", err)
+	}
+
+	safe, err := (&pizza.ConcretePizzaBuilder{}).DeclareAllergy("Gluten").StrictAllergies().SetSize("Small").SetCrust("GlutenFree").AddMushrooms().Build()
+	if err != nil {
+		fmt.Printf("Error creating Safe pizza: %v\n", err)
+	} else {
+		fmt.Printf("Safe Pizza: Crust=%s, Allergens=%v\n", safe.Crust, safe.Allergens())
+	}
+
+	fmt.Println("\n=== Size Defaults ===")
+
+	// Example 10: Seed size-dependent toppings; explicit calls in the chain win over the defaults
+	for _, size := range []string{"Small", "Medium", "Large"} {
+		sized, err := pizza.NewSizedPizza(size).SetCrust("Thin").Build()
+		if err != nil {
+			fmt.Printf("Error creating %s pizza: %v\n", size, err)
+			continue
+		}
+		fmt.Printf("%s default: Toppings=%v\n", size, sized.Toppings())
+	}
+
+	fmt.Println("\n=== Structured Logging ===")
+
+	// Example 11: Log every build as a structured record, here as text on stdout without timestamps
+	previous := pizza.Log
+	pizza.Log = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	(&pizza.ConcretePizzaBuilder{}).SetSize("Medium").SetCrust("Thin").AddCheese().AddMushrooms().Build()
+	(&pizza.ConcretePizzaBuilder{}).SetSize("Large").AddPepperoni().Build()
+	pizza.Log = previous
 }
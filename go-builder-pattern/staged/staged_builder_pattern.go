@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// ============================================================================
+// STAGED BUILDER PATTERN IMPLEMENTATION
+// ============================================================================
+// The Staged Builder pattern is a type-safe variation of the Builder pattern that
+// enforces a specific sequence of operations through different interfaces at each stage.
+// This provides compile-time guarantees that mandatory fields are set in the correct
+// order and prevents the creation of invalid intermediate states.
+// ============================================================================
+
+func main() {
+	demonstrateStagedBuilder()
+}
+
+// Log receives a structured record for every Build, with the make, color and features of the car
+// It discards them by default; set it to slog.Default() or any other logger to observe the builder
+var Log = slog.New(discardHandler{})
+
+// discardHandler drops every record; as it enables no level, records are not even built
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// Car represents the complex product being built using the staged builder pattern
+// This struct contains both mandatory fields (Make, Color) and optional features (HasGPS, IsElectric)
+// The staged builder ensures mandatory fields are set before optional ones, and that
+// BatteryKWh is set whenever the car is electric
+type Car struct {
+	Make       string  // Mandatory: Car manufacturer (e.g., "Toyota", "Tesla", "Ferrari")
+	Color      string  // Mandatory: Car color (e.g., "Red", "Blue", "Yellow")
+	HasGPS     bool    // Optional: Whether the car has GPS navigation system
+	IsElectric bool    // Optional: Whether the car is electric powered
+	BatteryKWh float64 // Conditionally required: Battery capacity, must be set for electric cars
+}
+
+// MakeStage Stage 1: First mandatory step to set the car make
+// This interface only allows setting the make and moving to the next stage
+type MakeStage interface {
+	SetMake(make string) ColorStage // Must set make first, returns next stage
+}
+
+// ColorStage Stage 2: Second mandatory step to set the car color
+// This interface only allows setting the color and moving to the next stage
+type ColorStage interface {
+	SetColor(color string) OptionalStage // Must set color second, returns next stage
+}
+
+// OptionalStage Stage 3: Final stage for optional features and building
+// This interface allows setting optional features and building the final car
+type OptionalStage interface {
+	WithGPS() OptionalStage     // Optional: Add GPS feature
+	MakeElectric() BatteryStage // Optional: Make the car electric, requires the battery capacity next
+	Build() Car                 // Build and return the final car object
+}
+
+// BatteryStage Conditional stage entered only by electric cars
+// Choosing MakeElectric() makes the battery capacity mandatory: the only way back to
+// OptionalStage is through SetBatteryKWh, so an electric car without a battery cannot be built
+type BatteryStage interface {
+	SetBatteryKWh(kwh float64) OptionalStage // Must set battery capacity, returns to the optional stage
+}
+
+// CarBuilder implements all stages of the staged builder pattern
+// It maintains the car state and implements different interfaces for each stage
+type CarBuilder struct {
+	car   Car    // The car object being constructed through stages
+	stage string // Name of the stage interface the builder was last returned as, used by String
+}
+
+// NewCarBuilder creates a new car builder and returns the first stage (MakeStage)
+// This is the entry point for the staged builder pattern
+func NewCarBuilder() MakeStage {
+	return &CarBuilder{
+		car:   Car{},       // Initialize with empty car
+		stage: "MakeStage", // Start at the first stage
+	}
+}
+
+// SetMake : Stage 1 Implementation
+// Sets the car make (mandatory field) and progresses to ColorStage
+func (cb *CarBuilder) SetMake(make string) ColorStage {
+	cb.car.Make = make
+	cb.stage = "ColorStage"
+	return cb // Return self but typed as ColorStage interface
+}
+
+// SetColor : Stage 2 Implementation
+// Sets the car color (mandatory field) and progresses to OptionalStage
+func (cb *CarBuilder) SetColor(color string) OptionalStage {
+	cb.car.Color = color
+	cb.stage = "OptionalStage"
+	return cb // Return self but typed as OptionalStage interface
+}
+
+// WithGPS : Stage 3 Implementation
+// Adds GPS feature (optional) and remains in OptionalStage for method chaining
+func (cb *CarBuilder) WithGPS() OptionalStage {
+	cb.car.HasGPS = true
+	cb.stage = "OptionalStage"
+	return cb // Return self to allow method chaining of optional features
+}
+
+// MakeElectric : Stage 3 Implementation
+// Makes the car electric (optional) and progresses to BatteryStage
+func (cb *CarBuilder) MakeElectric() BatteryStage {
+	cb.car.IsElectric = true
+	cb.stage = "BatteryStage"
+	return cb // Return self but typed as BatteryStage interface
+}
+
+// SetBatteryKWh : Battery Stage Implementation
+// Sets the battery capacity (required for electric cars) and returns to OptionalStage
+func (cb *CarBuilder) SetBatteryKWh(kwh float64) OptionalStage {
+	cb.car.BatteryKWh = kwh
+	cb.stage = "OptionalStage"
+	return cb // Return self but typed as OptionalStage interface
+}
+
+// Build : Stage 3 Implementation
+// Finalizes construction and returns the completed car
+// No validation needed here since mandatory fields are enforced by the staged interfaces
+// The built car is logged to Log
+func (cb *CarBuilder) Build() Car {
+	Log.Info("car built", "make", cb.car.Make, "color", cb.car.Color, "gps", cb.car.HasGPS,
+		"electric", cb.car.IsElectric, "battery_kwh", cb.car.BatteryKWh)
+	return cb.car
+}
+
+// String : Debug representation
+// Summarizes the current stage and the fields set so far, e.g. "stage=ColorStage make=Tesla"
+// Every stage is backed by the same *CarBuilder, so any stage value can be printed by
+// asserting it to fmt.Stringer, which is handy when logging a partially-built car
+func (cb *CarBuilder) String() string {
+	parts := []string{"stage=" + cb.stage}
+	if cb.car.Make != "" {
+		parts = append(parts, "make="+cb.car.Make)
+	}
+	if cb.car.Color != "" {
+		parts = append(parts, "color="+cb.car.Color)
+	}
+	if cb.car.HasGPS {
+		parts = append(parts, "gps=true")
+	}
+	if cb.car.IsElectric {
+		parts = append(parts, "electric=true")
+	}
+	if cb.car.BatteryKWh != 0 {
+		parts = append(parts, fmt.Sprintf("battery=%gkWh", cb.car.BatteryKWh))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Usage Examples:
+//
+// Basic car (mandatory fields only):
+//   basicCar := NewCarBuilder().SetMake("Toyota").SetColor("Blue").Build()
+//
+// Luxury car (with all features):
+//   luxuryCar := NewCarBuilder().SetMake("Tesla").SetColor("Red").WithGPS().MakeElectric().SetBatteryKWh(100).Build()
+//
+// Custom car (flexible optional features):
+//   customCar := NewCarBuilder().SetMake("Ferrari").SetColor("Yellow").MakeElectric().SetBatteryKWh(75).Build()
+//
+// Compile-time safety examples (these would cause compile errors):
+//   NewCarBuilder().SetColor("Red")                                         // Error: SetColor not available on MakeStage
+//   NewCarBuilder().SetMake("Toyota").Build()                               // Error: Build not available on ColorStage
+//   NewCarBuilder().WithGPS()                                               // Error: WithGPS not available on MakeStage
+//   NewCarBuilder().SetMake("Tesla").SetColor("Red").MakeElectric().Build() // Error: Build not available on BatteryStage
+
+// demonstrateStagedBuilder demonstrates the staged builder pattern with comprehensive examples
+func demonstrateStagedBuilder() {
+	fmt.Println("=== STAGED BUILDER PATTERN DEMONSTRATION ===")
+	fmt.Println()
+
+	// Example 1: Basic car with only mandatory fields
+	// The staged builder enforces the order: Make → Color → Build
+	fmt.Println("=== Basic Car (Mandatory fields only) ===")
+	basicCar := NewCarBuilder().
+		SetMake("Toyota"). // Stage 1: Must set make first
+		SetColor("Blue").  // Stage 2: Must set color second
+		Build()            // Stage 3: Build the car
+
+	fmt.Printf("Basic Car: Make=%s, Color=%s, GPS=%t, Electric=%t\n",
+		basicCar.Make, basicCar.Color, basicCar.HasGPS, basicCar.IsElectric)
+
+	// Example 2: Luxury car with all optional features
+	// Demonstrates method chaining in the optional stage
+	fmt.Println("\n=== Luxury Car (With optional features) ===")
+	luxuryCar := NewCarBuilder().
+		SetMake("Tesla").   // Stage 1: Set make
+		SetColor("Red").    // Stage 2: Set color
+		WithGPS().          // Stage 3: Add optional GPS
+		MakeElectric().     // Stage 3: Add optional electric feature
+		SetBatteryKWh(100). // Battery stage: Required because the car is electric
+		Build()             // Stage 3: Build the final car
+
+	fmt.Printf("Luxury Car: Make=%s, Color=%s, GPS=%t, Electric=%t, Battery=%.0fkWh\n",
+		luxuryCar.Make, luxuryCar.Color, luxuryCar.HasGPS, luxuryCar.IsElectric, luxuryCar.BatteryKWh)
+
+	// Example 3: Different order of optional features
+	// Shows flexibility in the optional stage while maintaining mandatory order
+	fmt.Println("\n=== Sports Car (Different optional order) ===")
+	sportsCar := NewCarBuilder().
+		SetMake("Ferrari"). // Stage 1: Set make
+		SetColor("Yellow"). // Stage 2: Set color
+		MakeElectric().     // Stage 3: Make electric first
+		SetBatteryKWh(75).  // Battery stage: Required because the car is electric
+		Build()             // Stage 3: Build without GPS
+
+	fmt.Printf("Sports Car: Make=%s, Color=%s, GPS=%t, Electric=%t, Battery=%.0fkWh\n",
+		sportsCar.Make, sportsCar.Color, sportsCar.HasGPS, sportsCar.IsElectric, sportsCar.BatteryKWh)
+
+	// Example 4: Economy car with only GPS
+	fmt.Println("\n=== Economy Car (Single optional feature) ===")
+	economyCar := NewCarBuilder().
+		SetMake("Honda").  // Stage 1: Set make
+		SetColor("White"). // Stage 2: Set color
+		WithGPS().         // Stage 3: Add only GPS
+		Build()            // Stage 3: Build the car
+
+	fmt.Printf("Economy Car: Make=%s, Color=%s, GPS=%t, Electric=%t\n",
+		economyCar.Make, economyCar.Color, economyCar.HasGPS, economyCar.IsElectric)
+
+	// Example 5: Log every build as a structured record, here as text on stdout without timestamps
+	fmt.Println("\n=== Structured Logging ===")
+	previous := Log
+	Log = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	NewCarBuilder().SetMake("Toyota").SetColor("Blue").Build()
+	NewCarBuilder().SetMake("Tesla").SetColor("Red").MakeElectric().SetBatteryKWh(100).Build()
+	Log = previous
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestCarBuilderString prints the builder at each stage of a chain and checks the summary of
+// the stage and the fields set so far
+func TestCarBuilderString(t *testing.T) {
+	tests := []struct {
+		name  string
+		stage func() any // Builder as returned by the stage under test
+		want  string
+	}{
+		{"MakeStage", func() any { return NewCarBuilder() }, "stage=MakeStage"},
+		{"ColorStage", func() any { return NewCarBuilder().SetMake("Tesla") }, "stage=ColorStage make=Tesla"},
+		{"OptionalStage", func() any { return NewCarBuilder().SetMake("Tesla").SetColor("Red") }, "stage=OptionalStage make=Tesla color=Red"},
+		{"OptionalStage with GPS", func() any { return NewCarBuilder().SetMake("Tesla").SetColor("Red").WithGPS() },
+			"stage=OptionalStage make=Tesla color=Red gps=true"},
+		{"BatteryStage", func() any { return NewCarBuilder().SetMake("Tesla").SetColor("Red").MakeElectric() },
+			"stage=BatteryStage make=Tesla color=Red electric=true"},
+		{"OptionalStage after the battery", func() any {
+			return NewCarBuilder().SetMake("Tesla").SetColor("Red").WithGPS().MakeElectric().SetBatteryKWh(100)
+		}, "stage=OptionalStage make=Tesla color=Red gps=true electric=true battery=100kWh"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stringer, ok := tt.stage().(fmt.Stringer)
+			if !ok {
+				t.Fatalf("%T does not implement fmt.Stringer", tt.stage())
+			}
+			if got := stringer.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/*
+Broadcaster: fan-out of published values to every subscriber.
+A regular subscriber receives every value through a buffered channel; when its buffer is
+full the publisher waits for it (backpressure). A throttled subscriber receives at most one
+value per interval: values published in between are coalesced and only the latest is kept,
+which suits UI-style updates where intermediate values don't matter.
+*/
+
+// Subscription is a subscriber's view of a Broadcaster
+type Subscription[T any] struct {
+	C <-chan T // Delivers the published values, closed on Unsubscribe or Close
+
+	out      chan T
+	quit     chan struct{} // Closed to stop delivering to this subscriber
+	quitOnce sync.Once
+	throttle time.Duration // Minimum time between deliveries, 0 for a regular subscriber
+
+	mu     sync.Mutex
+	latest T             // Latest value not yet delivered (throttled subscribers only)
+	has    bool          // Whether latest holds a pending value
+	notify chan struct{} // Signals the throttled delivery goroutine
+}
+
+// Broadcaster publishes values to all current subscribers
+type Broadcaster[T any] struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription[T]]struct{}
+	closed bool
+
+	closing   chan struct{} // Closed by Close before it takes the write lock, releases blocked publishers
+	closeOnce sync.Once
+}
+
+// NewBroadcaster creates a broadcaster without subscribers
+func NewBroadcaster[T any]() *Broadcaster[T] {
+	return &Broadcaster[T]{subs: make(map[*Subscription[T]]struct{}), closing: make(chan struct{})}
+}
+
+// Subscribe registers a subscriber that receives every published value,
+// buffering up to buffer values before the publisher has to wait for it
+func (b *Broadcaster[T]) Subscribe(buffer int) *Subscription[T] {
+	sub := &Subscription[T]{out: make(chan T, buffer), quit: make(chan struct{})}
+	sub.C = sub.out
+	b.add(sub)
+	return sub
+}
+
+// SubscribeThrottled registers a subscriber that receives at most one value per interval.
+// Values published faster than that are coalesced, keeping only the latest one.
+// Publishing never waits for a throttled subscriber.
+func (b *Broadcaster[T]) SubscribeThrottled(interval time.Duration) *Subscription[T] {
+	sub := &Subscription[T]{
+		out:      make(chan T),
+		quit:     make(chan struct{}),
+		throttle: interval,
+		notify:   make(chan struct{}, 1),
+	}
+	sub.C = sub.out
+	go sub.runThrottled()
+	b.add(sub)
+	return sub
+}
+
+// add registers a subscription, or closes it right away if the broadcaster is closed
+func (b *Broadcaster[T]) add(sub *Subscription[T]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.stop()
+		return
+	}
+	b.subs[sub] = struct{}{}
+}
+
+// Publish delivers v to every subscriber, waiting for regular subscribers whose buffer is full
+func (b *Broadcaster[T]) Publish(v T) {
+	_ = b.PublishCtx(context.Background(), v)
+}
+
+// PublishCtx delivers v to every subscriber like Publish, but stops waiting for slow
+// subscribers once ctx is cancelled and returns ctx.Err(). Subscribers not reached by then
+// miss the value.
+func (b *Broadcaster[T]) PublishCtx(ctx context.Context, v T) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		if err := sub.deliver(ctx, v, b.closing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unsubscribe removes a subscriber and closes its channel
+func (b *Broadcaster[T]) Unsubscribe(sub *Subscription[T]) {
+	// stop first so a publisher blocked on this subscriber lets go of the read lock
+	sub.quitOnce.Do(func() { close(sub.quit) })
+
+	b.mu.Lock()
+	_, ok := b.subs[sub]
+	delete(b.subs, sub)
+	b.mu.Unlock()
+	if ok {
+		sub.stop()
+	}
+}
+
+// Close removes every subscriber and closes their channels
+func (b *Broadcaster[T]) Close() {
+	// stop delivering first so a publisher blocked on a full subscriber lets go of the read lock
+	b.closeOnce.Do(func() { close(b.closing) })
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		sub.stop()
+		delete(b.subs, sub)
+	}
+}
+
+// deliver hands a value to the subscriber according to its mode, giving up when ctx is cancelled
+// or the broadcaster is closing
+func (s *Subscription[T]) deliver(ctx context.Context, v T, closing <-chan struct{}) error {
+	if s.throttle > 0 {
+		s.mu.Lock()
+		s.latest, s.has = v, true
+		s.mu.Unlock()
+		select {
+		case s.notify <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+	select {
+	case s.out <- v:
+		return nil
+	case <-s.quit:
+		return nil
+	case <-closing:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stop ends delivery; regular subscriptions are closed here, throttled ones by their goroutine.
+// Caller must hold the broadcaster's write lock so no publisher is sending.
+func (s *Subscription[T]) stop() {
+	s.quitOnce.Do(func() { close(s.quit) })
+	if s.throttle == 0 {
+		close(s.out)
+	}
+}
+
+// runThrottled delivers the latest pending value at most once per interval
+func (s *Subscription[T]) runThrottled() {
+	defer close(s.out)
+
+	var last time.Time
+	for {
+		select {
+		case <-s.notify:
+		case <-s.quit:
+			return
+		}
+
+		// wait out the rest of the interval, values published meanwhile replace latest
+		if wait := s.throttle - time.Since(last); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-s.quit:
+				return
+			}
+		}
+
+		s.mu.Lock()
+		v, has := s.latest, s.has
+		s.has = false
+		s.mu.Unlock()
+		if !has {
+			continue
+		}
+
+		select {
+		case s.out <- v:
+			last = time.Now()
+		case <-s.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestBroadcasterCloseReleasesBlockedPublisher closes a broadcaster while a publisher waits on a
+// subscriber whose buffer is full and nobody reads. Close must not deadlock on the publisher, and
+// neither goroutine may outlive the test.
+func TestBroadcasterCloseReleasesBlockedPublisher(t *testing.T) {
+	testutil.LeakCheck(t)
+	b := NewBroadcaster[int]()
+	sub := b.Subscribe(1)
+	b.Publish(1) // fills the buffer
+
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		b.Publish(2) // blocks on the full subscriber
+	}()
+	select {
+	case <-published:
+		t.Fatal("Publish did not wait for the full subscriber")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		b.Close()
+	}()
+	for _, done := range []chan struct{}{closed, published} {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Close did not release the blocked publisher")
+		}
+	}
+
+	if v, ok := <-sub.C; !ok || v != 1 {
+		t.Fatalf("first receive = %d, %t, want the buffered 1", v, ok)
+	}
+	if _, ok := <-sub.C; ok {
+		t.Fatal("subscription still open after Close")
+	}
+}
+
+// TestThrottledSubscriberCoalescesBurst publishes a burst of 100 values to subscribers with
+// different throttle intervals and checks that each throttled one receives far fewer values,
+// at most one per interval, ending with the latest, while a regular subscriber receives them all
+func TestThrottledSubscriberCoalescesBurst(t *testing.T) {
+	const burst = 100
+	tests := []struct {
+		name     string
+		interval time.Duration // Zero for a regular subscriber
+	}{
+		{"regular", 0},
+		{"throttled every 10ms", 10 * time.Millisecond},
+		{"throttled every 50ms", 50 * time.Millisecond},
+	}
+	testutil.LeakCheck(t)
+	b := NewBroadcaster[int]()
+	received := make([][]int, len(tests))
+	var wg sync.WaitGroup
+	for i, tt := range tests {
+		sub := b.Subscribe(burst)
+		if tt.interval > 0 {
+			sub = b.SubscribeThrottled(tt.interval)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range sub.C {
+				received[i] = append(received[i], v)
+			}
+		}()
+	}
+
+	start := time.Now()
+	for i := 1; i <= burst; i++ {
+		b.Publish(i)
+		time.Sleep(time.Millisecond)
+	}
+	elapsed := time.Since(start)
+	time.Sleep(100 * time.Millisecond) // longer than every interval, the latest value is delivered
+	b.Close()
+	wg.Wait()
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := received[i]
+			if len(got) == 0 || got[len(got)-1] != burst || !slices.IsSorted(got) {
+				t.Fatalf("received %v, want increasing values ending with %d", got, burst)
+			}
+			if tt.interval == 0 {
+				if len(got) != burst {
+					t.Errorf("regular subscriber received %d values, want %d", len(got), burst)
+				}
+				return
+			}
+			if limit := int(elapsed/tt.interval) + 2; len(got) > limit {
+				t.Errorf("received %d values over %v, want at most %d", len(got), elapsed, limit)
+			}
+		})
+	}
+}
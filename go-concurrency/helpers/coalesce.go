@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+/*
+Coalesce: forward only the latest value of each time window.
+High-frequency streams (sensor readings, progress updates, UI state) often change faster than a
+consumer such as a worker pool can act on them, and only the newest value matters. The first
+value of a burst opens a window; when it ends the latest value received in it is forwarded and
+the ones before it are dropped. Unlike Debounce, a steady stream still gets one value per window
+instead of waiting for a pause, and the last value is sent at the end of its window even if the
+input then goes quiet.
+*/
+
+// Coalesce forwards at most one value per window from in, the most recent one, dropping the
+// values it replaced. The output closes once in is closed, after forwarding the pending value.
+func Coalesce[T any](in <-chan T, window time.Duration) <-chan T {
+	return CoalesceCtx(context.Background(), in, window)
+}
+
+// CoalesceCtx coalesces like Coalesce and stops (closing the output) once ctx is cancelled
+func CoalesceCtx[T any](ctx context.Context, in <-chan T, window time.Duration) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		var latest T
+		var pending bool               // Whether latest has not been forwarded yet
+		var windowEnd <-chan time.Time // Set while a window is open
+		var ready chan<- T             // out once a window ended with a pending value, nil otherwise
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					if pending {
+						send(ctx, out, latest)
+					}
+					return
+				}
+				latest, pending = v, true
+				if windowEnd == nil && ready == nil {
+					windowEnd = time.After(window)
+				}
+			case <-windowEnd:
+				windowEnd, ready = nil, out
+			case ready <- latest:
+				// a slow consumer gets the value that is latest by the time it reads
+				pending, ready = false, nil
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestCoalesceBurstThenSilence sends bursts of values well within one window, each followed by
+// silence with the input still open, and checks that only the last value of every burst is
+// forwarded, no earlier than a window after the burst began, and that closing the input closes the
+// output (flushing a value still pending)
+func TestCoalesceBurstThenSilence(t *testing.T) {
+	const window = 100 * time.Millisecond
+	tests := []struct {
+		name      string
+		bursts    []int // Number of values in each burst
+		closeLast bool  // Close the input right after the last burst instead of after its silence
+	}{
+		{"single value", []int{1}, false},
+		{"burst of 100", []int{100}, false},
+		{"bursts separated by silence", []int{20, 1, 50}, false},
+		{"closed right after the burst", []int{10, 30}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			in := make(chan int)
+			out := Coalesce(in, window)
+			receive := func() (int, bool) {
+				select {
+				case v, ok := <-out:
+					return v, ok
+				case <-time.After(5 * window):
+					t.Fatalf("nothing forwarded within %v", 5*window)
+					return 0, false
+				}
+			}
+
+			last := 0
+			for i, n := range tt.bursts {
+				start := time.Now()
+				for range n {
+					last++
+					in <- last
+				}
+				if tt.closeLast && i == len(tt.bursts)-1 {
+					close(in)
+					if v, ok := receive(); !ok || v != last {
+						t.Fatalf("got %d (open %v) after closing the input, want the pending %d", v, ok, last)
+					}
+					break
+				}
+
+				v, ok := receive()
+				if !ok || v != last {
+					t.Fatalf("burst %d forwarded %d (open %v), want its last value %d", i, v, ok, last)
+				}
+				if elapsed := time.Since(start); elapsed < window {
+					t.Errorf("burst %d forwarded after %v, want no earlier than the window of %v", i, elapsed, window)
+				}
+				select {
+				case v := <-out:
+					t.Fatalf("burst %d forwarded %d during the silence after its last value", i, v)
+				case <-time.After(2 * window):
+				}
+			}
+			if !tt.closeLast {
+				close(in)
+			}
+			if v, ok := receive(); ok {
+				t.Errorf("got %d after the input was closed, want the output closed", v)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import "context"
+
+/*
+Context convention shared by every helper in this package.
+A helper that can block takes a context.Context (the pipeline stages) or offers a Ctx variant
+next to its plain form (MergeCtx, PublishCtx, WaitCtx) that returns promptly once the context is
+cancelled. Every blocking operation selects on ctx.Done() as well, through send or wait below, so no
+helper waits on a channel without an exit.
+*/
+
+// send writes v to out unless ctx is cancelled first, reporting whether the value was sent
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// wait blocks until done is closed, returning ctx.Err() if ctx is cancelled first
+func wait(ctx context.Context, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestCtxVariantsReturnOnCancel blocks every context-aware helper on inputs that never deliver or
+// close, then cancels the context and checks that each one returns promptly without leaking
+func TestCtxVariantsReturnOnCancel(t *testing.T) {
+	never := func() <-chan int { return make(chan int) }
+	drain := func(ch <-chan int) error {
+		for range ch {
+		}
+		return nil
+	}
+	tests := []struct {
+		name    string
+		run     func(ctx context.Context, t *testing.T) error // Blocks until ctx is cancelled
+		wantErr bool                                          // Whether it returns context.Canceled
+	}{
+		{"send", func(ctx context.Context, t *testing.T) error {
+			if send(ctx, make(chan int), 1) {
+				return errors.New("sent without a receiver")
+			}
+			return nil
+		}, false},
+		{"wait", func(ctx context.Context, t *testing.T) error { return wait(ctx, make(chan struct{})) }, true},
+		{"MergeCtx", func(ctx context.Context, t *testing.T) error { return drain(MergeCtx(ctx, never(), never())) }, false},
+		{"OrderedMerge", func(ctx context.Context, t *testing.T) error {
+			return drain(OrderedMerge(ctx, func(a, b int) bool { return a < b }, never(), never()))
+		}, false},
+		{"RoundRobinSelect", func(ctx context.Context, t *testing.T) error {
+			return drain(RoundRobinSelect(ctx, []<-chan int{never(), never()}))
+		}, false},
+		{"CoalesceCtx", func(ctx context.Context, t *testing.T) error {
+			return drain(CoalesceCtx(ctx, never(), time.Millisecond))
+		}, false},
+		{"DrainCtx", func(ctx context.Context, t *testing.T) error { _, err := DrainCtx(ctx, never()); return err }, true},
+		{"Stage", func(ctx context.Context, t *testing.T) error {
+			return drain(Stage(ctx, never(), func(v int) int { return v }))
+		}, false},
+		{"Filter", func(ctx context.Context, t *testing.T) error {
+			return drain(Filter(ctx, never(), func(int) bool { return true }))
+		}, false},
+		{"Dedup", func(ctx context.Context, t *testing.T) error { return drain(Dedup(ctx, never())) }, false},
+		{"DedupWindow", func(ctx context.Context, t *testing.T) error { return drain(DedupWindow(ctx, never(), 4)) }, false},
+		{"FlatMap", func(ctx context.Context, t *testing.T) error {
+			return drain(FlatMap(ctx, never(), func(v int) []int { return []int{v} }))
+		}, false},
+		{"Batch", func(ctx context.Context, t *testing.T) error {
+			for range Batch(ctx, never(), 4, time.Millisecond) {
+			}
+			return nil
+		}, false},
+		{"Timeout", func(ctx context.Context, t *testing.T) error {
+			onTime, timedOut := Timeout(ctx, never(), time.Millisecond)
+			go drain(timedOut)
+			return drain(onTime)
+		}, false},
+		{"Tee", func(ctx context.Context, t *testing.T) error {
+			a, b := Tee(ctx, never(), 0)
+			go drain(b)
+			return drain(a)
+		}, false},
+		{"Pipeline", func(ctx context.Context, t *testing.T) error {
+			return drain(Pipeline(ctx, never(), func(v int) int { return v }))
+		}, false},
+		{"Broadcaster.PublishCtx", func(ctx context.Context, t *testing.T) error {
+			b := NewBroadcaster[int]()
+			t.Cleanup(b.Close)
+			b.Subscribe(0) // never read
+			return b.PublishCtx(ctx, 1)
+		}, true},
+		{"TypedBus.PublishCtx", func(ctx context.Context, t *testing.T) error {
+			b := NewTypedBus[int]()
+			t.Cleanup(b.Close)
+			b.Subscribe("tick", 0) // never read
+			return b.PublishCtx(ctx, "tick", 1)
+		}, true},
+		{"WaitCtx", func(ctx context.Context, t *testing.T) error {
+			var wg sync.WaitGroup
+			wg.Add(1)
+			t.Cleanup(wg.Done) // releases the goroutine WaitCtx leaves waiting on the group
+			return WaitCtx(ctx, &wg)
+		}, true},
+		{"WeightedWaitGroup.WaitCtx", func(ctx context.Context, t *testing.T) error {
+			var wg WeightedWaitGroup
+			wg.Add(3)
+			t.Cleanup(func() { wg.Done(3) })
+			return wg.WaitCtx(ctx)
+		}, true},
+		{"WithContext", func(ctx context.Context, t *testing.T) error {
+			g, gctx := WithContext(ctx)
+			g.Go(func() error {
+				<-gctx.Done()
+				return gctx.Err()
+			})
+			return g.Wait()
+		}, true},
+		{"IterConcurrent", func(ctx context.Context, t *testing.T) error {
+			blocked := func(ctx context.Context, v int) (int, error) {
+				<-ctx.Done()
+				return 0, ctx.Err()
+			}
+			for _, err := range IterConcurrent(ctx, []int{1, 2, 3}, 2, blocked) {
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			errc := make(chan error, 1)
+			go func() { errc <- tt.run(ctx, t) }()
+
+			select {
+			case err := <-errc:
+				t.Fatalf("returned %v before the cancellation", err)
+			case <-time.After(10 * time.Millisecond):
+			}
+			cancel()
+			select {
+			case err := <-errc:
+				if tt.wantErr != errors.Is(err, context.Canceled) || (!tt.wantErr && err != nil) {
+					t.Errorf("returned %v, want context.Canceled: %t", err, tt.wantErr)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("still blocked a second after the cancellation")
+			}
+		})
+	}
+}
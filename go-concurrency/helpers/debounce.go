@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+Debounce: collapse a burst of calls into a single invocation.
+Every call restarts a timer; fn only runs once the calls have stopped for the quiet period d.
+Typical uses are saving a document after the user stopped typing or reloading a config file
+after a burst of change events.
+*/
+
+// Debouncer delays fn until calls to Call have stopped for the quiet period.
+// It is safe for concurrent use: calls from many goroutines collapse into one invocation.
+type Debouncer struct {
+	fn func()
+	d  time.Duration
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	gen     int  // Incremented by every Call, so a timer that fired just before a newer Call is ignored
+	pending bool // Whether a call is waiting for fn to run
+}
+
+// NewDebouncer creates a debouncer running fn once calls have stopped for d
+func NewDebouncer(fn func(), d time.Duration) *Debouncer {
+	return &Debouncer{fn: fn, d: d}
+}
+
+// Call schedules fn, postponing it by d from now if it was already scheduled
+func (db *Debouncer) Call() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.timer != nil {
+		db.timer.Stop()
+	}
+	db.gen++
+	db.pending = true
+	gen := db.gen
+	db.timer = time.AfterFunc(db.d, func() { db.fire(gen) })
+}
+
+// fire runs fn for the call of the given generation, unless a newer call or Flush/Stop superseded it
+func (db *Debouncer) fire(gen int) {
+	db.mu.Lock()
+	if gen != db.gen || !db.pending {
+		db.mu.Unlock()
+		return
+	}
+	db.pending = false
+	db.mu.Unlock()
+
+	// run outside the lock so fn may call Call again
+	db.fn()
+}
+
+// Flush runs a pending fn immediately instead of waiting for the quiet period, e.g. on shutdown
+func (db *Debouncer) Flush() {
+	db.mu.Lock()
+	if db.timer != nil {
+		db.timer.Stop()
+	}
+	pending := db.pending
+	db.pending = false
+	db.mu.Unlock()
+
+	if pending {
+		db.fn()
+	}
+}
+
+// Stop cancels a pending fn without running it
+func (db *Debouncer) Stop() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.timer != nil {
+		db.timer.Stop()
+	}
+	db.pending = false
+}
+
+// Debounce returns a function that, when called repeatedly, invokes fn only once the calls
+// have stopped for d. The returned function is safe to call from multiple goroutines.
+func Debounce(fn func(), d time.Duration) func() {
+	return NewDebouncer(fn, d).Call
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestDebounceCollapsesRapidCalls calls a debounced function in bursts from several goroutines
+// and checks that each burst collapses into a single invocation
+func TestDebounceCollapsesRapidCalls(t *testing.T) {
+	const quiet = 20 * time.Millisecond
+	tests := []struct {
+		name       string
+		goroutines int
+		calls      int // Calls per goroutine and burst
+		bursts     int // Bursts separated by more than the quiet period
+	}{
+		{"single call", 1, 1, 1},
+		{"rapid calls from one goroutine", 1, 50, 1},
+		{"rapid calls from many goroutines", 5, 10, 1},
+		{"two bursts", 5, 10, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var invocations atomic.Int32
+			call := Debounce(func() { invocations.Add(1) }, quiet)
+
+			for range tt.bursts {
+				var wg sync.WaitGroup
+				for range tt.goroutines {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for range tt.calls {
+							call()
+							time.Sleep(time.Millisecond)
+						}
+					}()
+				}
+				wg.Wait()
+				time.Sleep(3 * quiet)
+			}
+			if got := invocations.Load(); got != int32(tt.bursts) {
+				t.Errorf("%d invocations for %d bursts of %d calls, want %d",
+					got, tt.bursts, tt.goroutines*tt.calls, tt.bursts)
+			}
+		})
+	}
+}
+
+// TestDebouncerFlushAndStop checks that Flush runs a pending call right away and only once,
+// and that Stop drops it
+func TestDebouncerFlushAndStop(t *testing.T) {
+	const quiet = 20 * time.Millisecond
+	tests := []struct {
+		name      string
+		ops       func(db *Debouncer)
+		wantNow   int32 // Invocations right after ops
+		wantLater int32 // Invocations once the quiet period has passed
+	}{
+		{"call", func(db *Debouncer) { db.Call() }, 0, 1},
+		{"call, then flush", func(db *Debouncer) { db.Call(); db.Flush() }, 1, 1},
+		{"flush without a call", func(db *Debouncer) { db.Flush() }, 0, 0},
+		{"call, then stop", func(db *Debouncer) { db.Call(); db.Stop() }, 0, 0},
+		{"stop, then call", func(db *Debouncer) { db.Stop(); db.Call() }, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var invocations atomic.Int32
+			db := NewDebouncer(func() { invocations.Add(1) }, quiet)
+			tt.ops(db)
+			if got := invocations.Load(); got != tt.wantNow {
+				t.Errorf("%d invocations right away, want %d", got, tt.wantNow)
+			}
+			time.Sleep(3 * quiet)
+			if got := invocations.Load(); got != tt.wantLater {
+				t.Errorf("%d invocations after the quiet period, want %d", got, tt.wantLater)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+/*
+Draining leftover channels.
+On shutdown a producer may still have values buffered or in flight; reading its channel until
+it is closed lets the producer finish instead of blocking forever on a send. Drain collects the
+values, DrainDiscard throws them away, and DrainCtx and DrainWithTimeout give up on
+cancellation or after a deadline for producers that might never close their channel.
+*/
+
+// Drain reads ch until it is closed and returns every value read, in order.
+// It blocks until ch is closed, so it never returns for a channel nobody closes; use
+// DrainCtx or DrainWithTimeout when that is possible. A nil channel blocks forever.
+func Drain[T any](ch <-chan T) []T {
+	var values []T
+	for v := range ch {
+		values = append(values, v)
+	}
+	return values
+}
+
+// DrainDiscard reads ch until it is closed, discarding the values. Like Drain it blocks until
+// ch is closed.
+func DrainDiscard[T any](ch <-chan T) {
+	for range ch {
+	}
+}
+
+// DrainCtx reads ch until it is closed or ctx is cancelled. It returns the values read and nil
+// once ch is closed, or the values read so far and ctx.Err() on cancellation.
+func DrainCtx[T any](ctx context.Context, ch <-chan T) ([]T, error) {
+	var values []T
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return values, nil
+			}
+			values = append(values, v)
+		case <-ctx.Done():
+			Log.LogAttrs(ctx, slog.LevelWarn, "drain gave up", slog.Int("values", len(values)), slog.Any("error", ctx.Err()))
+			return values, ctx.Err()
+		}
+	}
+}
+
+// DrainWithTimeout reads ch until it is closed or d has passed, whichever comes first. It
+// returns the values read and whether ch was closed; on false the producer may still be
+// blocked on a send and should be stopped some other way (e.g. by cancelling its context).
+func DrainWithTimeout[T any](ch <-chan T, d time.Duration) ([]T, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	values, err := DrainCtx(ctx, ch)
+	return values, err == nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestDrain drains pre-closed buffered channels, channels a producer is still sending on and
+// channels nobody closes, and checks the values read, that the producer is let finish and that
+// DrainWithTimeout reports whether the channel was closed
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		buffered []int // Values in the buffer before draining starts
+		produced []int // Values a producer sends while the channel is drained
+		closed   bool  // Whether the channel is closed, before draining or after the producer is done
+		want     []int
+	}{
+		{"pre-closed buffered channel", 3, []int{1, 2, 3}, nil, true, []int{1, 2, 3}},
+		{"pre-closed empty channel", 3, nil, nil, true, nil},
+		{"buffered values, then a producer", 2, []int{1, 2}, []int{3, 4, 5}, true, []int{1, 2, 3, 4, 5}},
+		{"producer on an unbuffered channel", 0, nil, []int{1, 2, 3}, true, []int{1, 2, 3}},
+		{"channel nobody closes", 2, []int{7}, []int{8}, false, []int{7, 8}},
+	}
+	drains := []struct {
+		name  string
+		drain func(ch <-chan int) (values []int, closed bool)
+	}{
+		{"Drain", func(ch <-chan int) ([]int, bool) { return Drain(ch), true }},
+		{"DrainDiscard", func(ch <-chan int) ([]int, bool) { DrainDiscard(ch); return nil, true }},
+		{"DrainWithTimeout", func(ch <-chan int) ([]int, bool) { return DrainWithTimeout(ch, 50*time.Millisecond) }},
+	}
+	for _, tt := range tests {
+		for _, d := range drains {
+			if !tt.closed && d.name != "DrainWithTimeout" {
+				continue // Drain and DrainDiscard block until the channel is closed
+			}
+			t.Run(tt.name+"/"+d.name, func(t *testing.T) {
+				testutil.LeakCheck(t)
+				ch := make(chan int, tt.capacity)
+				for _, v := range tt.buffered {
+					ch <- v
+				}
+				finished := make(chan struct{})
+				go func() {
+					defer close(finished)
+					for _, v := range tt.produced {
+						ch <- v
+					}
+					if tt.closed {
+						close(ch)
+					}
+				}()
+				if tt.produced == nil {
+					<-finished // closed before draining starts
+				}
+
+				got, closed := d.drain(ch)
+				select {
+				case <-finished:
+				case <-time.After(time.Second):
+					t.Fatal("producer still blocked after the drain")
+				}
+				if closed != tt.closed {
+					t.Errorf("%s reported closed %v, want %v", d.name, closed, tt.closed)
+				}
+				if d.name == "DrainDiscard" {
+					if n := len(ch); n != 0 {
+						t.Errorf("%d values left in the channel", n)
+					}
+					return
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("%s returned %v, want %v", d.name, got, tt.want)
+				}
+			})
+		}
+	}
+}
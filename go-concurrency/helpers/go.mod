@@ -0,0 +1,3 @@
+module go_concurrency_helpers
+
+go 1.23
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+/*
+Group: goroutines sharing one cancellation lifecycle, in the style of errgroup.
+WithContext derives a context from a parent; it is cancelled as soon as one goroutine started
+with Go returns an error, or when the parent is cancelled, so siblings that respect it stop early.
+Wait blocks for every goroutine and returns the first error.
+*/
+
+// Group runs goroutines tied to a shared context. Create it with WithContext.
+type Group struct {
+	wg     sync.WaitGroup
+	ctx    context.Context // Group context, used for logging
+	cancel context.CancelCauseFunc
+	once   sync.Once
+	err    error // First error returned by a Go func
+}
+
+// WithContext returns a Group and a context derived from ctx. The context is cancelled when the
+// first Go func returns a non-nil error, when Wait returns, or when ctx is cancelled.
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{ctx: ctx, cancel: cancel}, ctx
+}
+
+// Go runs fn in a new goroutine. The first error cancels the group's context.
+func (g *Group) Go(fn func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := fn(); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel(err)
+				Log.LogAttrs(g.ctx, slog.LevelWarn, "group cancelled", slog.Any("error", err))
+			})
+		}
+	}()
+}
+
+// Wait blocks until every Go func has returned and returns the first error, if any.
+// Cancellation of the parent context is not an error by itself: funcs report it if they care.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel(nil)
+	return g.err
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestGroupCancelsSiblings starts goroutines in a Group, some failing and some blocked on the
+// group's context, and checks that the first error or a cancelled parent stops the blocked ones
+// with the right cause, and that Wait returns the first error
+func TestGroupCancelsSiblings(t *testing.T) {
+	errA, errB := errors.New("replica us: connection refused"), errors.New("replica eu: reset")
+	tests := []struct {
+		name         string
+		fail         error // Error of the goroutine failing right away, if any
+		failLate     error // Error of a sibling that fails once the context is cancelled, if any
+		cancelParent bool
+		siblings     int // Goroutines blocked on the context until it is cancelled
+		wantErr      error
+		wantCause    error
+	}{
+		{"child error cancels siblings", errA, nil, false, 3, errA, errA},
+		{"first error wins", errA, errB, false, 2, errA, errA},
+		{"parent cancelled", nil, nil, true, 3, nil, context.Canceled},
+		{"no blocked siblings", errA, nil, false, 0, errA, errA},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			parent, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			g, ctx := WithContext(parent)
+
+			var mu sync.Mutex
+			var causes []error
+			for range tt.siblings {
+				g.Go(func() error {
+					<-ctx.Done()
+					mu.Lock()
+					causes = append(causes, context.Cause(ctx))
+					mu.Unlock()
+					return nil
+				})
+			}
+			if tt.failLate != nil {
+				g.Go(func() error {
+					<-ctx.Done()
+					return tt.failLate
+				})
+			}
+			if tt.fail != nil {
+				g.Go(func() error { return tt.fail })
+			}
+			if tt.cancelParent {
+				cancel()
+			}
+
+			errc := make(chan error, 1)
+			go func() { errc <- g.Wait() }()
+			var err error
+			select {
+			case err = <-errc:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Wait still blocked: siblings were not cancelled")
+			}
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Wait() = %v, want %v", err, tt.wantErr)
+			}
+			if len(causes) != tt.siblings {
+				t.Fatalf("%d siblings stopped, want %d", len(causes), tt.siblings)
+			}
+			for _, cause := range causes {
+				if !errors.Is(cause, tt.wantCause) {
+					t.Errorf("sibling stopped with cause %v, want %v", cause, tt.wantCause)
+				}
+			}
+		})
+	}
+}
+
+// TestGroupContextCancelledAfterWait checks that the group's context is cancelled once Wait
+// returns, even when every goroutine succeeded, while the parent stays alive
+func TestGroupContextCancelledAfterWait(t *testing.T) {
+	tests := []struct {
+		name       string
+		goroutines int
+	}{
+		{"no goroutines", 0},
+		{"successful goroutines", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := context.Background()
+			g, ctx := WithContext(parent)
+			for range tt.goroutines {
+				g.Go(func() error {
+					if ctx.Err() != nil {
+						return errors.New("context cancelled before Wait")
+					}
+					return nil
+				})
+			}
+			if err := g.Wait(); err != nil {
+				t.Fatalf("Wait() = %v, want nil", err)
+			}
+			if ctx.Err() == nil {
+				t.Error("group context still alive after Wait")
+			}
+			if parent.Err() != nil {
+				t.Errorf("parent cancelled: %v", parent.Err())
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"iter"
+	"sync"
+)
+
+/*
+IterConcurrent: a bounded worker pool as a range-over-func iterator.
+The collection examples start goroutines, gather results on a channel and range over it. With
+Go 1.23 iterators the same pattern becomes a plain for loop: the workers run while the loop
+consumes results lazily, in completion order, and leaving the loop early (break or return)
+cancels the workers and waits for them, so nothing leaks.
+*/
+
+// IterConcurrent runs fn on every item with at most workers calls at once and yields each
+// result with its error as soon as it completes, in completion order:
+//
+//	for r, err := range IterConcurrent(ctx, urls, 4, fetch) { ... }
+//
+// Breaking out of the loop cancels the context passed to fn and returns once every worker has
+// stopped. If ctx is cancelled before every item was processed, the last pair yielded carries
+// ctx.Err(). workers below 1 is treated as 1.
+func IterConcurrent[T, R any](ctx context.Context, items []T, workers int, fn func(context.Context, T) (R, error)) iter.Seq2[R, error] {
+	type outcome struct {
+		value R
+		err   error
+	}
+	return func(yield func(R, error) bool) {
+		runCtx, cancel := context.WithCancel(ctx)
+		jobs := make(chan T)
+		results := make(chan outcome)
+
+		go func() {
+			defer close(jobs)
+			for _, item := range items {
+				if !send(runCtx, jobs, item) {
+					return
+				}
+			}
+		}()
+
+		var wg sync.WaitGroup
+		for range max(workers, 1) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for item := range jobs {
+					if runCtx.Err() != nil {
+						return // the loop was left or ctx cancelled, start no more calls
+					}
+					value, err := fn(runCtx, item)
+					if !send(runCtx, results, outcome{value, err}) {
+						return
+					}
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		// on an early exit stop the workers and wait until they are gone
+		defer func() {
+			cancel()
+			for range results {
+			}
+		}()
+
+		yielded := 0
+		for o := range results {
+			if !yield(o.value, o.err) {
+				return
+			}
+			yielded++
+		}
+		if yielded < len(items) && ctx.Err() != nil {
+			var zero R
+			yield(zero, ctx.Err())
+		}
+	}
+}
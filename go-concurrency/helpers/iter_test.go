@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestIterConcurrentEarlyTermination ranges over IterConcurrent and leaves the loop early, by a
+// break or by cancelling ctx, and checks how many results were yielded, that no call to fn is
+// still running once the loop is left, and that at most one call per worker started beyond the
+// results consumed
+func TestIterConcurrentEarlyTermination(t *testing.T) {
+	const items = 20
+	tests := []struct {
+		name        string
+		workers     int
+		breakAfter  int // Results consumed before breaking out of the loop, 0 for all
+		cancelAfter int // Results consumed before cancelling ctx and ranging on, 0 for never
+		wantResults int // Results without error yielded
+	}{
+		{"consume everything", 3, 0, 0, items},
+		{"break after five", 3, 5, 0, 5},
+		{"break after the first on one worker", 1, 1, 0, 1},
+		{"break after the first on many workers", 8, 1, 0, 1},
+		{"cancel ctx after three", 3, 0, 3, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			ids := make([]int, items)
+			for i := range ids {
+				ids[i] = i + 1
+			}
+			var started, active, peak atomic.Int32
+			lookup := func(ctx context.Context, id int) (int, error) {
+				started.Add(1)
+				n := active.Add(1)
+				defer active.Add(-1)
+				for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+				}
+				return id, nil
+			}
+
+			results := 0
+			var last error
+			for _, err := range IterConcurrent(ctx, ids, tt.workers, lookup) {
+				if err != nil {
+					last = err
+					continue
+				}
+				results++
+				if results == tt.breakAfter {
+					break
+				}
+				if results == tt.cancelAfter {
+					cancel()
+				}
+			}
+
+			if n := active.Load(); n != 0 {
+				t.Errorf("%d calls still running after the loop", n)
+			}
+			if p := peak.Load(); p > int32(tt.workers) {
+				t.Errorf("%d calls ran at once, want at most %d", p, tt.workers)
+			}
+			if tt.cancelAfter > 0 {
+				// the workers finish at most their current call after the cancellation
+				if results < tt.cancelAfter || results > tt.cancelAfter+tt.workers || !errors.Is(last, context.Canceled) {
+					t.Errorf("%d results and last error %v after cancelling at %d, want at most %d more and context.Canceled",
+						results, last, tt.cancelAfter, tt.workers)
+				}
+				return
+			}
+			if results != tt.wantResults || last != nil {
+				t.Errorf("%d results and error %v, want %d and none", results, last, tt.wantResults)
+			}
+			if n := started.Load(); n > int32(results+tt.workers) {
+				t.Errorf("%d calls started for %d results consumed, want at most %d", n, results, results+tt.workers)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+)
+
+/*
+Structured logging of the helpers.
+Helpers log notable events (a timed function finished, a Group was cancelled by an error, a
+wait or drain gave up on its context) to Log as slog records with attributes such as name,
+duration and error. Where a helper has a context the record is logged with it, so a handler can
+add values it carries. Log discards everything by default.
+*/
+
+// Log receives the structured log records of the helpers, e.g. slog.Default(). Set it before
+// using the helpers from several goroutines, it is not guarded against concurrent changes.
+var Log = slog.New(discardHandler{})
+
+// discardHandler drops every record; as it enables no level, records are not even built
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
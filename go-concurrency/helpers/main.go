@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+func main() {
+	// comment out any of the following function calls to run a single example
+	PipelineExample()
+	PipelineCancellationExample()
+	FilterFlatMapExample()
+	MergeExample()
+	BroadcasterExample()
+	DebounceExample()
+	ContextCancellationExample()
+	WeightedWaitGroupExample()
+	GroupExample()
+	BatchExample()
+	ThrottleExample()
+	TimedExample()
+	WaitCtxExample()
+	TimeoutStageExample()
+	StructuredLogExample()
+}
+
+func PipelineExample() {
+	ctx := context.Background()
+
+	//square every number, then add one
+	square := func(n int) int { return n * n }
+	addOne := func(n int) int { return n + 1 }
+
+	for v := range Pipeline(ctx, Generate(ctx, 1, 2, 3, 4, 5), square, addOne) {
+		fmt.Println("Pipeline output:", v)
+	}
+}
+
+func PipelineCancellationExample() {
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	numbers := make([]int, 100)
+	for i := range numbers {
+		numbers[i] = i
+	}
+	double := func(n int) int { return n * 2 }
+	out := Pipeline(ctx, Generate(ctx, numbers...), double, double, double)
+
+	//read only a few values, then abandon the pipeline
+	for i := 0; i < 3; i++ {
+		fmt.Println("Read before cancel:", <-out)
+	}
+	cancel()
+
+	//give the stages a moment to observe the cancellation and exit
+	time.Sleep(100 * time.Millisecond)
+	fmt.Printf("Goroutines before: %d, after cancel: %d\n", before, runtime.NumGoroutine())
+}
+
+func FilterFlatMapExample() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	//split lines into words and keep only the long ones
+	lines := Generate(ctx, "the quick brown fox", "jumps over", "the lazy dog")
+	words := FlatMap(ctx, lines, strings.Fields)
+	for word := range Filter(ctx, words, func(w string) bool { return len(w) > 3 }) {
+		fmt.Println("Long word:", word)
+	}
+
+	//abandon a FlatMap halfway through a slice: cancelling releases every stage
+	before := runtime.NumGoroutine()
+	repeated := FlatMap(ctx, Generate(ctx, 1000), func(n int) []int { return make([]int, n) })
+	evens := Filter(ctx, repeated, func(n int) bool { return n%2 == 0 })
+	fmt.Println("First value:", <-evens)
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+	fmt.Printf("Goroutines before: %d, after cancel: %d\n", before, runtime.NumGoroutine())
+}
+
+func MergeExample() {
+	done := make(chan struct{})
+
+	//three producers, e.g. the result channels of three worker pools
+	producer := func(name string, n int) <-chan string {
+		out := make(chan string)
+		go func() {
+			defer close(out)
+			for i := 1; i <= n; i++ {
+				select {
+				case out <- fmt.Sprintf("%s-%d", name, i):
+				case <-done:
+					return
+				}
+			}
+		}()
+		return out
+	}
+
+	for v := range Merge(done, producer("A", 3), producer("B", 3), producer("C", 3)) {
+		fmt.Println("Merged:", v)
+	}
+
+	//stop early: closing done releases every forwarder even though values are left unread
+	before := runtime.NumGoroutine()
+	merged := Merge(done, producer("X", 100), producer("Y", 100))
+	fmt.Println("Read before done:", <-merged)
+	close(done)
+	time.Sleep(100 * time.Millisecond)
+	fmt.Printf("Goroutines before: %d, after done: %d\n", before, runtime.NumGoroutine())
+}
+
+func BroadcasterExample() {
+	b := NewBroadcaster[int]()
+	first, second := b.Subscribe(10), b.Subscribe(10)
+
+	//every subscriber receives every published value
+	for i := 1; i <= 3; i++ {
+		b.Publish(i)
+	}
+	b.Close()
+	for v := range first.C {
+		fmt.Printf("First subscriber received %d, second received %d\n", v, <-second.C)
+	}
+}
+
+func DebounceExample() {
+	save := NewDebouncer(func() { fmt.Println("Debounced: saving document") }, time.Hour)
+
+	//three rapid edits schedule a single save, Flush runs it right away on shutdown
+	for range 3 {
+		save.Call()
+	}
+	save.Flush()
+}
+
+func ContextCancellationExample() {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	//MergeCtx: producers that never close, the merged channel closes at the deadline
+	forever := func() <-chan int {
+		out := make(chan int)
+		go func() {
+			for i := 0; ; i++ {
+				if !send(ctx, out, i) {
+					return
+				}
+			}
+		}()
+		return out
+	}
+	received := 0
+	for range MergeCtx(ctx, forever(), forever()) {
+		received++
+	}
+	fmt.Printf("MergeCtx closed after %d values: %v\n", received, ctx.Err())
+}
+
+func WeightedWaitGroupExample() {
+	var wg WeightedWaitGroup
+
+	//uploads of different sizes, each reporting progress in 1MB chunks
+	sizes := map[string]int64{"small.zip": 2, "medium.zip": 5, "large.zip": 12}
+	for name, size := range sizes {
+		wg.Add(size)
+		go func() {
+			for chunk := int64(0); chunk < size; chunk++ {
+				time.Sleep(5 * time.Millisecond)
+				wg.Done(1)
+			}
+			fmt.Printf("Uploaded %s (%dMB)\n", name, size)
+		}()
+	}
+
+	wg.Wait()
+	fmt.Printf("All uploads done, MB pending: %d\n", wg.Pending())
+}
+
+func GroupExample() {
+	g, ctx := WithContext(context.Background())
+
+	//three replicas are queried, one fails fast and the others give up on the shared context
+	for _, replica := range []string{"eu", "us", "asia"} {
+		g.Go(func() error {
+			if replica == "us" {
+				time.Sleep(10 * time.Millisecond)
+				return errors.New("replica us: connection refused")
+			}
+			select {
+			case <-time.After(time.Second):
+				fmt.Printf("Replica %s answered\n", replica)
+				return nil
+			case <-ctx.Done():
+				fmt.Printf("Replica %s stopped: %v\n", replica, context.Cause(ctx))
+				return ctx.Err()
+			}
+		})
+	}
+
+	fmt.Printf("Group finished, err: %v\n", g.Wait())
+}
+
+func BatchExample() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	//rows arrive in a burst of 7, a pause, then 2 more: bulk insert up to 3 rows at a time
+	rows := make(chan int)
+	go func() {
+		defer close(rows)
+		for i := 1; i <= 9; i++ {
+			if i == 8 {
+				time.Sleep(100 * time.Millisecond)
+			}
+			rows <- i
+		}
+	}()
+
+	//the 7th row is flushed alone after 20ms instead of waiting for the next burst
+	for batch := range Batch(ctx, rows, 3, 20*time.Millisecond) {
+		fmt.Println("Bulk insert:", batch)
+	}
+}
+
+func ThrottleExample() {
+
+	//an expensive lookup that the downstream can serve at most 3 at a time
+	lookup := Throttle(func(id int) string {
+		time.Sleep(10 * time.Millisecond)
+		return fmt.Sprintf("user-%d", id)
+	}, 3)
+
+	//20 goroutines call it at once, the extra ones wait for a free slot
+	names := make([]string, 20)
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			names[i] = lookup(i + 1)
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("Looked up %d users through the throttle, last: %s\n", len(names), names[len(names)-1])
+}
+
+func TimedExample() {
+	//quiet by default: only the returned duration is used
+	elapsed := Timed("warm cache", func() { time.Sleep(20 * time.Millisecond) })
+	fmt.Printf("Warm cache took at least 20ms: %t\n", elapsed >= 20*time.Millisecond)
+
+	//with a handler on Log every measurement is reported
+	Log = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	defer func() { Log = slog.New(discardHandler{}) }()
+
+	var wg sync.WaitGroup
+	Timed("fan-out of 5 workers", func() {
+		for i := 1; i <= 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				time.Sleep(time.Duration(i) * 10 * time.Millisecond)
+			}()
+		}
+		wg.Wait()
+	})
+	_, err := TimedErr("flaky upload", func() error {
+		time.Sleep(10 * time.Millisecond)
+		return errors.New("connection reset")
+	})
+	fmt.Println("Upload error passed through:", err)
+}
+
+func WaitCtxExample() {
+	var wg sync.WaitGroup
+	hang := make(chan struct{})
+
+	//two workers finish, the third hangs on a stalled connection
+	for i := 1; i <= 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i == 3 {
+				<-hang
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	fmt.Println("WaitCtx:", WaitCtx(ctx, &wg))
+
+	//the helper goroutine WaitCtx left in wg.Wait exits once the hung worker does
+	close(hang)
+}
+
+func TimeoutStageExample() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	//the fast path indexes events but stalls on every third one
+	events := Generate(ctx, "e1", "e2", "e3", "e4", "e5", "e6")
+	onTime, timedOut := Timeout(ctx, events, 20*time.Millisecond)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		n := 0
+		for e := range onTime {
+			n++
+			fmt.Println("Indexed", e)
+			if n%2 == 0 {
+				time.Sleep(50 * time.Millisecond)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for e := range timedOut {
+			fmt.Println("Deferred to the slow path:", e)
+		}
+	}()
+	wg.Wait()
+}
+
+func StructuredLogExample() {
+
+	//send the helpers' records to a text handler, dropping the attributes that vary per run
+	var buf bytes.Buffer
+	Log = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	defer func() { Log = slog.New(discardHandler{}) }()
+
+	Timed("load config", func() {})
+
+	g, _ := WithContext(context.Background())
+	g.Go(func() error { return errors.New("replica eu: timeout") })
+	g.Wait()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var wg sync.WaitGroup
+	WaitCtx(ctx, &wg)
+
+	fmt.Print(buf.String())
+}
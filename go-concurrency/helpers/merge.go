@@ -0,0 +1,130 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+/*
+Fan-in: merge several producer channels into one.
+One forwarding goroutine per input channel copies values to the shared output.
+Every send is a select on done, so closing done makes all forwarders return even if
+nobody reads the output anymore, and the output is closed once they have all exited.
+OrderedMerge instead keeps the order: it merges individually sorted inputs into one sorted
+output (a k-way merge), e.g. the sorted results of several worker pools.
+*/
+
+// Merge fans values from all input channels into the returned channel.
+// The output is closed when every input is closed or done is closed, whichever comes first.
+func Merge[T any](done <-chan struct{}, chans ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+
+	forward := func(in <-chan T) {
+		defer wg.Done()
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case out <- v:
+				case <-done:
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}
+
+	wg.Add(len(chans))
+	for _, in := range chans {
+		go forward(in)
+	}
+
+	// close the output only after every forwarder has stopped sending
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// MergeCtx is Merge following the package's context convention: the output is closed when
+// every input is closed or ctx is cancelled
+func MergeCtx[T any](ctx context.Context, chans ...<-chan T) <-chan T {
+	return Merge(ctx.Done(), chans...)
+}
+
+// OrderedMerge merges channels that each deliver values in ascending order (according to less)
+// into one output in ascending order. It holds the next value of every open input and always
+// emits the smallest, so it has to wait for every open input to deliver before emitting;
+// inputs may have different lengths and leave the merge once closed. Equal values keep the
+// order of chans. The output is closed when every input is closed or ctx is cancelled.
+func OrderedMerge[T any](ctx context.Context, less func(a, b T) bool, chans ...<-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		h := &mergeHeap[T]{less: less}
+
+		// next reads the following value of input i onto the heap, reporting false if ctx was cancelled
+		next := func(i int) bool {
+			select {
+			case v, ok := <-chans[i]:
+				if ok {
+					heap.Push(h, mergeHead[T]{value: v, input: i})
+				}
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for i := range chans {
+			if !next(i) {
+				return
+			}
+		}
+		for h.Len() > 0 {
+			head := heap.Pop(h).(mergeHead[T])
+			if !send(ctx, out, head.value) || !next(head.input) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// mergeHead is the next value of one OrderedMerge input
+type mergeHead[T any] struct {
+	value T
+	input int // Index of the input channel the value came from
+}
+
+// mergeHeap orders the heads of the OrderedMerge inputs, smallest value first
+type mergeHeap[T any] struct {
+	heads []mergeHead[T]
+	less  func(a, b T) bool
+}
+
+func (h *mergeHeap[T]) Len() int { return len(h.heads) }
+func (h *mergeHeap[T]) Less(i, j int) bool {
+	a, b := h.heads[i], h.heads[j]
+	if h.less(a.value, b.value) {
+		return true
+	}
+	if h.less(b.value, a.value) {
+		return false
+	}
+	return a.input < b.input
+}
+func (h *mergeHeap[T]) Swap(i, j int) { h.heads[i], h.heads[j] = h.heads[j], h.heads[i] }
+func (h *mergeHeap[T]) Push(x any)    { h.heads = append(h.heads, x.(mergeHead[T])) }
+func (h *mergeHeap[T]) Pop() any {
+	last := h.heads[len(h.heads)-1]
+	h.heads = h.heads[:len(h.heads)-1]
+	return last
+}
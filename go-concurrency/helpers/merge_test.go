@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestOrderedMerge merges several pre-sorted inputs and checks the output is globally sorted,
+// with inputs of different lengths, empty inputs, and equal values kept in the order of the inputs.
+// Values are ordered by their first character; the rest tells the inputs apart.
+func TestOrderedMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs [][]string
+		want   []string
+	}{
+		{"unequal lengths", [][]string{{"1", "4", "9"}, {"2", "3"}, {"5", "6", "7", "8"}}, []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}},
+		{"one input outlasting the others", [][]string{{"1"}, {"2", "3", "4", "5"}, {"0"}}, []string{"0", "1", "2", "3", "4", "5"}},
+		{"equal values keep the input order", [][]string{{"1a", "3a"}, {"1b", "2b", "3b"}, {"3c"}}, []string{"1a", "1b", "2b", "3a", "3b", "3c"}},
+		{"empty inputs", [][]string{{}, {"2", "5"}, {}}, []string{"2", "5"}},
+		{"all inputs empty", [][]string{{}, {}}, nil},
+		{"single input", [][]string{{"1", "2", "3"}}, []string{"1", "2", "3"}},
+		{"no inputs", nil, nil},
+	}
+	less := func(a, b string) bool { return a[0] < b[0] }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			chans := make([]<-chan string, len(tt.inputs))
+			for i, in := range tt.inputs {
+				chans[i] = Generate(ctx, in...)
+			}
+			var got []string
+			for v := range OrderedMerge(ctx, less, chans...) {
+				got = append(got, v)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+/*
+Reusable pipeline stages built on channels.
+Every stage runs in its own goroutine, reads from an input channel and writes to an output
+channel it owns and closes. All stages share a context: cancelling it makes every stage
+stop, close its output and return, so no goroutine is left blocked on a send nobody reads.
+*/
+
+// Generate emits the given values on the returned channel, stopping early if ctx is cancelled
+func Generate[T any](ctx context.Context, values ...T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for _, v := range values {
+			if !send(ctx, out, v) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Stage applies fn to every value read from in and emits the results on the returned channel.
+// The output is closed when in is closed or ctx is cancelled.
+func Stage[T, R any](ctx context.Context, in <-chan T, fn func(T) R) <-chan R {
+	out := make(chan R)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if !send(ctx, out, fn(v)) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Filter emits only the values read from in for which pred returns true.
+// The output is closed when in is closed or ctx is cancelled.
+func Filter[T any](ctx context.Context, in <-chan T, pred func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if pred(v) && !send(ctx, out, v) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Dedup emits only the values read from in that were not seen before, e.g. to drop duplicate
+// deliveries of an event stream. Every distinct value is remembered until the stage exits, so
+// memory grows with the number of distinct values; use DedupWindow for unbounded streams.
+// The output is closed when in is closed or ctx is cancelled.
+func Dedup[T comparable](ctx context.Context, in <-chan T) <-chan T {
+	seen := make(map[T]struct{})
+	return Filter(ctx, in, func(v T) bool {
+		if _, ok := seen[v]; ok {
+			return false
+		}
+		seen[v] = struct{}{}
+		return true
+	})
+}
+
+// DedupWindow is Dedup with bounded memory: it only remembers the last size distinct values,
+// forgetting the oldest one when a new value arrives, so a duplicate that arrives after more
+// than size other distinct values is emitted again. A size below 1 is treated as 1.
+func DedupWindow[T comparable](ctx context.Context, in <-chan T, size int) <-chan T {
+	size = max(size, 1)
+	seen := make(map[T]struct{}, size)
+	window := make([]T, 0, size) // ring of the remembered values, oldest at next once full
+	next := 0
+	return Filter(ctx, in, func(v T) bool {
+		if _, ok := seen[v]; ok {
+			return false
+		}
+		if len(window) < size {
+			window = append(window, v)
+		} else {
+			delete(seen, window[next])
+			window[next] = v
+			next = (next + 1) % size
+		}
+		seen[v] = struct{}{}
+		return true
+	})
+}
+
+// FlatMap applies fn to every value read from in and emits each element of the returned slice,
+// so one input may produce zero, one or many outputs. The output is closed when in is closed
+// or ctx is cancelled, also in the middle of emitting a slice.
+func FlatMap[T, R any](ctx context.Context, in <-chan T, fn func(T) []R) <-chan R {
+	out := make(chan R)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				for _, r := range fn(v) {
+					if !send(ctx, out, r) {
+						return
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Batch groups the values read from in into slices of up to size values, for bulk operations
+// downstream. A batch is emitted once it is full or maxWait after its first value arrived,
+// whichever comes first; maxWait <= 0 only flushes full batches. The final partial batch is
+// emitted when in is closed. The output is closed when in is closed or ctx is cancelled, in
+// which case a pending partial batch is dropped.
+func Batch[T any](ctx context.Context, in <-chan T, size int, maxWait time.Duration) <-chan []T {
+	size = max(size, 1)
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+		var batch []T
+		var flush <-chan time.Time // Fires maxWait after the first value of the batch, nil while empty
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		defer timer.Stop()
+
+		emit := func() bool {
+			timer.Stop()
+			flush = nil
+			full := batch
+			batch = nil
+			return send(ctx, out, full)
+		}
+
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					if len(batch) > 0 {
+						emit()
+					}
+					return
+				}
+				if batch == nil {
+					batch = make([]T, 0, size)
+					if maxWait > 0 {
+						timer.Reset(maxWait)
+						flush = timer.C
+					}
+				}
+				batch = append(batch, v)
+				if len(batch) == size && !emit() {
+					return
+				}
+			case <-flush:
+				if !emit() {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Timeout splits in into a fast and a slow path: each value is offered to the on-time output for
+// up to perItem and goes to the timed-out output if nobody took it in time, so a slow consumer
+// does not stall the stream and slow items can be handled separately. Both outputs are closed
+// when in is closed or ctx is cancelled. Both must be drained (or ctx cancelled), as a value
+// waiting on the timed-out output blocks the stage.
+func Timeout[T any](ctx context.Context, in <-chan T, perItem time.Duration) (onTime, timedOut <-chan T) {
+	fast := make(chan T)
+	slow := make(chan T)
+	go func() {
+		defer close(fast)
+		defer close(slow)
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		defer timer.Stop()
+
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				timer.Reset(perItem)
+				select {
+				case fast <- v:
+					timer.Stop()
+				case <-timer.C:
+					if !send(ctx, slow, v) {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return fast, slow
+}
+
+// Tee duplicates every value read from in onto both returned channels, e.g. to process results
+// and log them. No value is ever dropped: each one is delivered to both outputs before the next
+// is read, so the slower consumer sets the pace. buffer is the capacity of each output and lets
+// the faster consumer run ahead by up to that many values. Both outputs are closed when in is
+// closed or ctx is cancelled, so both must be drained (or ctx cancelled).
+func Tee[T any](ctx context.Context, in <-chan T, buffer int) (<-chan T, <-chan T) {
+	out1 := make(chan T, buffer)
+	out2 := make(chan T, buffer)
+	go func() {
+		defer close(out1)
+		defer close(out2)
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				// deliver to whichever output is ready first, then to the other one
+				o1, o2 := out1, out2
+				for range 2 {
+					select {
+					case o1 <- v:
+						o1 = nil
+					case o2 <- v:
+						o2 = nil
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out1, out2
+}
+
+// Pipeline chains stages that keep the value type, feeding the output of each into the next
+func Pipeline[T any](ctx context.Context, in <-chan T, fns ...func(T) T) <-chan T {
+	out := in
+	for _, fn := range fns {
+		out = Stage(ctx, out, fn)
+	}
+	return out
+}
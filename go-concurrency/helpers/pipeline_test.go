@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestPipelineCancelStopsStages cancels a pipeline whose consumer stopped reading midway. Every
+// stage must close its output and return instead of blocking on a send nobody reads.
+func TestPipelineCancelStopsStages(t *testing.T) {
+	testutil.LeakCheck(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	values := make([]int, 1000)
+	for i := range values {
+		values[i] = i
+	}
+	inc := func(v int) int { return v + 1 }
+	out := Pipeline(ctx, Generate(ctx, values...), inc, inc, inc)
+
+	if v := <-out; v != 3 {
+		t.Fatalf("first value = %d, want 3", v)
+	}
+	cancel()
+
+	// a stage may still hand over a value it was sending when ctx was cancelled, then it closes
+	deadline := time.After(time.Second)
+	for received := 1; ; received++ {
+		select {
+		case _, ok := <-out:
+			if !ok {
+				if received == len(values) {
+					t.Fatal("the pipeline ran to completion despite the cancellation")
+				}
+				return
+			}
+		case <-deadline:
+			t.Fatal("the output was not closed after cancel")
+		}
+	}
+}
+
+// TestStagesCancelWithoutReader cancels stages of every kind while their outputs are never read,
+// so each one is blocked on a send when ctx is cancelled
+func TestStagesCancelWithoutReader(t *testing.T) {
+	testutil.LeakCheck(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	Stage(ctx, Generate(ctx, 1, 2, 3), func(v int) int { return v * v })
+	Filter(ctx, Generate(ctx, 1, 2, 3), func(int) bool { return true })
+	FlatMap(ctx, Generate(ctx, 1, 2, 3), func(v int) []int { return []int{v, v} })
+	Batch(ctx, Generate(ctx, 1, 2, 3), 1, 0)
+	Timeout(ctx, Generate(ctx, 1, 2, 3), time.Millisecond)
+	Tee(ctx, Generate(ctx, 1, 2, 3), 0)
+	time.Sleep(20 * time.Millisecond) // let every stage block on its first send
+}
+
+// TestDedup runs streams with duplicates through Dedup and DedupWindow and checks which values
+// pass: the unbounded stage drops every repeat, the window forgets its oldest value once full
+func TestDedup(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     []string
+		window int // DedupWindow size, 0 for Dedup
+		want   []string
+	}{
+		{"unbounded drops every repeat", []string{"a", "b", "a", "c", "d", "b", "a"}, 0, []string{"a", "b", "c", "d"}},
+		{"unbounded without duplicates", []string{"a", "b", "c"}, 0, []string{"a", "b", "c"}},
+		{"unbounded all duplicates", []string{"a", "a", "a"}, 0, []string{"a"}},
+		{"unbounded empty", nil, 0, nil},
+		{"window evicts the oldest", []string{"a", "b", "a", "c", "d", "b", "a"}, 2, []string{"a", "b", "c", "d", "b", "a"}},
+		{"repeat inside the window", []string{"a", "b", "c", "c", "b", "a"}, 3, []string{"a", "b", "c"}},
+		{"repeat just outside the window", []string{"a", "b", "c", "d", "a"}, 3, []string{"a", "b", "c", "d", "a"}},
+		{"dropped repeats do not refresh", []string{"a", "b", "a", "c", "a"}, 2, []string{"a", "b", "c", "a"}},
+		{"window of one", []string{"a", "a", "b", "a", "a"}, 1, []string{"a", "b", "a"}},
+		{"window below one", []string{"a", "a", "b", "b"}, -5, []string{"a", "b"}},
+		{"window larger than the stream", []string{"a", "b", "a", "c", "b"}, 10, []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var out <-chan string
+			if tt.window == 0 {
+				out = Dedup(ctx, Generate(ctx, tt.in...))
+			} else {
+				out = DedupWindow(ctx, Generate(ctx, tt.in...), tt.window)
+			}
+			var got []string
+			for v := range out {
+				got = append(got, v)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTeeFastSlow reads the two outputs of Tee with one side stalled, or neither, and checks
+// that the fast side runs ahead by at most the buffer plus the value in hand, and that once the
+// slow side reads too both outputs receive every value in order and close
+func TestTeeFastSlow(t *testing.T) {
+	values := []int{1, 2, 3, 4, 5, 6}
+	tests := []struct {
+		name      string
+		slow      int // Output that stalls until the fast one is blocked: 1, 2 or 0 for neither
+		buffer    int
+		wantAhead int // Values the fast side gets while the slow one stalls
+	}{
+		{"both fast", 0, 0, 0},
+		{"slow second output", 2, 0, 1},
+		{"slow first output", 1, 0, 1},
+		{"slow second output, buffered", 2, 3, 4},
+		{"slow first output, buffered", 1, 3, 4},
+		{"buffer holding the whole stream", 2, 10, len(values)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			fast, slow := Tee(ctx, Generate(ctx, values...), tt.buffer)
+			if tt.slow == 1 {
+				fast, slow = slow, fast
+			}
+
+			var gotFast, gotSlow []int
+			if tt.slow != 0 {
+				// the fast side reads until Tee blocks on the stalled one
+			ahead:
+				for {
+					select {
+					case v, ok := <-fast:
+						if !ok {
+							break ahead
+						}
+						gotFast = append(gotFast, v)
+					case <-time.After(50 * time.Millisecond):
+						break ahead
+					}
+				}
+				if len(gotFast) != tt.wantAhead {
+					t.Errorf("fast side got %v while the slow side stalled, want %d values", gotFast, tt.wantAhead)
+				}
+			}
+			slowDone := make(chan struct{})
+			go func() {
+				defer close(slowDone)
+				for v := range slow {
+					gotSlow = append(gotSlow, v)
+				}
+			}()
+			for v := range fast {
+				gotFast = append(gotFast, v)
+			}
+			<-slowDone
+
+			if !slices.Equal(gotFast, values) || !slices.Equal(gotSlow, values) {
+				t.Errorf("outputs got %v and %v, want %v on both", gotFast, gotSlow, values)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"slices"
+)
+
+/*
+Fair fan-in: receive from several channels in a fixed rotation.
+A select with several ready cases picks one at random, which is fair only on average: over a
+short window one busy channel may be served many times in a row. RoundRobinSelect instead
+polls the channels in turn, starting after the one served last, so under load every channel
+gets an equal share. Only when none is ready does it block until any of them is.
+*/
+
+// RoundRobinSelect fans the values of chans into the returned channel, taking them in a fair
+// rotation: after a value from chans[i] the next one is taken from the first ready channel
+// after i. Closed inputs leave the rotation. The output is closed when every input is closed
+// or ctx is cancelled.
+func RoundRobinSelect[T any](ctx context.Context, chans []<-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		open := slices.Clone(chans)
+		next := 0
+		for len(open) > 0 {
+			i, v, ok := receiveFrom(ctx, open, next)
+			switch {
+			case i < 0:
+				return
+			case !ok:
+				// the channel after the closed one moves into its place
+				open = slices.Delete(open, i, i+1)
+				next = i
+			default:
+				if !send(ctx, out, v) {
+					return
+				}
+				next = i + 1
+			}
+			if next >= len(open) {
+				next = 0
+			}
+		}
+	}()
+	return out
+}
+
+// receiveFrom receives one value from chans, polling them in order starting at next and
+// blocking on all of them if none is ready. It returns the index of the channel received from
+// and the received value, or index -1 once ctx is cancelled.
+func receiveFrom[T any](ctx context.Context, chans []<-chan T, next int) (int, T, bool) {
+	for k := range chans {
+		i := (next + k) % len(chans)
+		select {
+		case v, ok := <-chans[i]:
+			return i, v, ok
+		default:
+		}
+	}
+
+	// nothing ready: wait for any channel, the rotation resumes with the next call
+	cases := make([]reflect.SelectCase, 0, len(chans)+1)
+	for _, ch := range chans {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+	}
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+	i, v, ok := reflect.Select(cases)
+	if i == len(chans) {
+		var zero T
+		return -1, zero, false
+	}
+	if !ok {
+		var zero T
+		return i, zero, false
+	}
+	// a nil value of an interface type T converts to nil, not a panic
+	x, _ := v.Interface().(T)
+	return i, x, true
+}
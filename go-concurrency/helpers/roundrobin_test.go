@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestRoundRobinSelectFairRotation feeds RoundRobinSelect from channels that always have a value
+// ready and checks the exact rotation: one value per channel in turn, with drained channels
+// leaving the rotation
+func TestRoundRobinSelectFairRotation(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs [][]string // Values buffered in each input channel, which is then closed
+		want   []string
+	}{
+		{"equal saturated inputs", [][]string{{"a1", "a2", "a3"}, {"b1", "b2", "b3"}, {"c1", "c2", "c3"}},
+			[]string{"a1", "b1", "c1", "a2", "b2", "c2", "a3", "b3", "c3"}},
+		{"uneven inputs", [][]string{{"a1"}, {"b1", "b2", "b3"}, {"c1", "c2"}},
+			[]string{"a1", "b1", "c1", "b2", "c2", "b3"}},
+		{"empty input", [][]string{{"a1", "a2"}, {}, {"c1", "c2"}},
+			[]string{"a1", "c1", "a2", "c2"}},
+		{"single input", [][]string{{"a1", "a2", "a3"}}, []string{"a1", "a2", "a3"}},
+		{"no inputs", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var chans []<-chan string
+			for _, values := range tt.inputs {
+				ch := make(chan string, len(values))
+				for _, v := range values {
+					ch <- v
+				}
+				close(ch)
+				chans = append(chans, ch)
+			}
+			var got []string
+			for v := range RoundRobinSelect(ctx, chans) {
+				got = append(got, v)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRoundRobinSelectSaturated runs producers that keep their channels full and checks that
+// every one of them gets exactly an even share of the receives, where a plain select would only
+// be fair on average
+func TestRoundRobinSelectSaturated(t *testing.T) {
+	tests := []struct {
+		name      string
+		producers int
+		receives  int
+	}{
+		{"two producers", 2, 200},
+		{"five producers", 5, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			chans := make([]<-chan int, tt.producers)
+			for p := range chans {
+				// the buffer covers the producer's share, so it is never empty while we receive
+				ch := make(chan int, tt.receives/tt.producers)
+				for range cap(ch) {
+					ch <- p
+				}
+				go func() {
+					defer close(ch)
+					for send(ctx, ch, p) {
+					}
+				}()
+				chans[p] = ch
+			}
+
+			counts := make([]int, tt.producers)
+			fair := RoundRobinSelect(ctx, chans)
+			for range tt.receives {
+				counts[<-fair]++
+			}
+			for p, n := range counts {
+				if share := tt.receives / tt.producers; n != share {
+					t.Errorf("producer %d got %d of %d receives, want %d; counts %v", p, n, tt.receives, share, counts)
+				}
+			}
+			cancel()
+			for range fair {
+			}
+		})
+	}
+}
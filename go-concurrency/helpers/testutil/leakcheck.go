@@ -0,0 +1,45 @@
+// Package testutil holds test helpers shared by the modules of this repository. It imports
+// testing, so only import it from _test.go files.
+package testutil
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+/*
+Goroutine-leak detector for tests of pipeline and pool code, importable by any module of the
+repository as go_concurrency_helpers/testutil.
+LeakCheck snapshots the goroutine count when the test starts and compares it once the test
+and its other cleanups are done. Goroutines that are winding down need a moment to exit, so
+the count is polled for a short settle delay before the test is failed.
+*/
+
+// leakSettle is how long LeakCheck waits for goroutines to exit before reporting a leak
+const leakSettle = 500 * time.Millisecond
+
+// LeakCheck fails t if more goroutines are running after the test than when it was called.
+// Call it first in the test, e.g. `testutil.LeakCheck(t)`; the check runs as a t.Cleanup, after cleanups
+// registered later in the test (which may stop the goroutines) have run.
+func LeakCheck(t testing.TB) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		t.Helper()
+		deadline := time.Now().Add(leakSettle)
+		for {
+			after := runtime.NumGoroutine()
+			if after <= before {
+				return
+			}
+			if time.Now().After(deadline) {
+				buf := make([]byte, 1<<16)
+				buf = buf[:runtime.Stack(buf, true)]
+				t.Errorf("goroutine leak: %d goroutines before the test, %d after\n%s", before, after, buf)
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
@@ -0,0 +1,22 @@
+package main
+
+/*
+Throttle: a decorator bounding how many calls of a function run at once.
+It is the semaphore at the heart of the worker pool (MaxInFlight) as a standalone wrapper:
+a buffered channel holds one token per allowed call, and callers beyond the limit block
+until a running call returns its token.
+*/
+
+// Throttle returns a wrapper around fn that allows at most max concurrent invocations; extra
+// callers block until a running call returns. max below 1 is treated as 1.
+func Throttle[T, R any](fn func(T) R, max int) func(T) R {
+	if max < 1 {
+		max = 1
+	}
+	tokens := make(chan struct{}, max)
+	return func(arg T) R {
+		tokens <- struct{}{}
+		defer func() { <-tokens }()
+		return fn(arg)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestThrottleCapsConcurrency starts more callers than the limit with calls that block until
+// released, and checks that exactly the limit runs at once, the others wait, and every caller
+// gets its own result
+func TestThrottleCapsConcurrency(t *testing.T) {
+	tests := []struct {
+		name     string
+		max      int
+		callers  int
+		wantPeak int
+	}{
+		{"more callers than the limit", 3, 20, 3},
+		{"fewer callers than the limit", 8, 5, 5},
+		{"limit of one", 1, 10, 1},
+		{"limit below one", 0, 4, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			var mu sync.Mutex
+			running, peak := 0, 0
+			release := make(chan struct{})
+			double := Throttle(func(n int) int {
+				mu.Lock()
+				running++
+				peak = max(peak, running)
+				mu.Unlock()
+				<-release
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return 2 * n
+			}, tt.max)
+
+			results := make([]int, tt.callers)
+			var wg sync.WaitGroup
+			for i := range tt.callers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					results[i] = double(i)
+				}()
+			}
+			current := func() int {
+				mu.Lock()
+				defer mu.Unlock()
+				return running
+			}
+			for wait := time.Now().Add(5 * time.Second); current() < tt.wantPeak; time.Sleep(time.Millisecond) {
+				if time.Now().After(wait) {
+					t.Fatalf("%d calls running, want %d", current(), tt.wantPeak)
+				}
+			}
+			time.Sleep(20 * time.Millisecond) // give blocked callers a chance to slip past the limit
+			close(release)
+			wg.Wait()
+
+			if peak != tt.wantPeak {
+				t.Errorf("peak of %d concurrent calls, want %d", peak, tt.wantPeak)
+			}
+			for i, r := range results {
+				if r != 2*i {
+					t.Errorf("caller %d got %d, want %d", i, r, 2*i)
+				}
+			}
+		})
+	}
+}
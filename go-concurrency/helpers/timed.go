@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+/*
+Stopwatch helpers for measuring how long a piece of work took.
+Timed and TimedErr run a function and return its duration, and report it to Log, so examples and
+benchmarks measure work instead of narrating sleeps. Log discards everything by default, which
+keeps tests quiet.
+*/
+
+// Timed runs fn and returns how long it took, logging it under name to Log
+func Timed(name string, fn func()) time.Duration {
+	start := time.Now()
+	fn()
+	elapsed := time.Since(start)
+	logTimed(name, elapsed, nil)
+	return elapsed
+}
+
+// TimedErr runs fn and returns how long it took together with its error, logging both under
+// name to Log
+func TimedErr(name string, fn func() error) (time.Duration, error) {
+	start := time.Now()
+	err := fn()
+	elapsed := time.Since(start)
+	logTimed(name, elapsed, err)
+	return elapsed, err
+}
+
+// logTimed records a measurement in Log, at warn level if the function failed
+func logTimed(name string, elapsed time.Duration, err error) {
+	if err != nil {
+		Log.Warn("timed", slog.String("name", name), slog.Duration("duration", elapsed), slog.Any("error", err))
+		return
+	}
+	Log.Info("timed", slog.String("name", name), slog.Duration("duration", elapsed))
+}
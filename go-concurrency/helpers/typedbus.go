@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+TypedBus: an event bus routing events by type name.
+Subscribers register for one event type (e.g. "email.sent") and only receive events published
+under that name. The bus keeps one Broadcaster per type name, so each type has its own
+subscriber list and delivery (buffering, backpressure, unsubscribing) works exactly like the
+Broadcaster's. A typical producer is a worker pool publishing its lifecycle events, with UI code
+subscribing to the few types it displays.
+*/
+
+// Event is a value published on a TypedBus under a type name
+type Event[T any] struct {
+	Type    string // Type name the event was published under, e.g. "email.sent"
+	Payload T
+}
+
+// TypedBus delivers published events to the subscribers of their type name. It is safe for
+// concurrent use by publishers and subscribers.
+type TypedBus[T any] struct {
+	mu     sync.Mutex
+	topics map[string]*Broadcaster[Event[T]]  // Subscriber list per type name
+	subs   map[*Subscription[Event[T]]]string // Type name of every subscription, for Unsubscribe
+	closed bool
+}
+
+// NewTypedBus creates a bus without subscribers
+func NewTypedBus[T any]() *TypedBus[T] {
+	return &TypedBus[T]{
+		topics: make(map[string]*Broadcaster[Event[T]]),
+		subs:   make(map[*Subscription[Event[T]]]string),
+	}
+}
+
+// Subscribe registers a subscriber that receives every event published under eventType,
+// buffering up to buffer events before publishers of that type have to wait for it.
+// Subscribing to a closed bus returns a subscription whose channel is already closed.
+func (b *TypedBus[T]) Subscribe(eventType string, buffer int) *Subscription[Event[T]] {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	topic, ok := b.topics[eventType]
+	if !ok {
+		topic = NewBroadcaster[Event[T]]()
+		if b.closed {
+			topic.Close()
+			return topic.Subscribe(buffer)
+		}
+		b.topics[eventType] = topic
+	}
+	sub := topic.Subscribe(buffer)
+	b.subs[sub] = eventType
+	return sub
+}
+
+// Publish delivers the payload to every subscriber of eventType, waiting for subscribers whose
+// buffer is full. Events of a type without subscribers are dropped.
+func (b *TypedBus[T]) Publish(eventType string, payload T) {
+	_ = b.PublishCtx(context.Background(), eventType, payload)
+}
+
+// PublishCtx delivers the payload like Publish, but stops waiting for slow subscribers once ctx
+// is cancelled and returns ctx.Err()
+func (b *TypedBus[T]) PublishCtx(ctx context.Context, eventType string, payload T) error {
+	b.mu.Lock()
+	topic := b.topics[eventType]
+	b.mu.Unlock()
+	if topic == nil {
+		return nil
+	}
+	// the topic does its own locking, so publishers of other types and subscribers are not held up
+	return topic.PublishCtx(ctx, Event[T]{Type: eventType, Payload: payload})
+}
+
+// Unsubscribe removes a subscriber and closes its channel. Unknown subscriptions are ignored.
+func (b *TypedBus[T]) Unsubscribe(sub *Subscription[Event[T]]) {
+	b.mu.Lock()
+	eventType, ok := b.subs[sub]
+	delete(b.subs, sub)
+	topic := b.topics[eventType]
+	b.mu.Unlock()
+	if ok {
+		topic.Unsubscribe(sub)
+	}
+}
+
+// Close removes every subscriber and closes their channels
+func (b *TypedBus[T]) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for eventType, topic := range b.topics {
+		topic.Close()
+		delete(b.topics, eventType)
+	}
+	clear(b.subs)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestTypedBusRouting publishes events of several types and checks which ones every subscriber
+// received: only those of its own type, none after it unsubscribed
+func TestTypedBusRouting(t *testing.T) {
+	type publish struct{ eventType, payload string }
+	tests := []struct {
+		name        string
+		subscribers []string  // Type name each subscriber registers for
+		publishes   []publish // Published in order
+		unsubscribe int       // Index of a subscriber removed after the first publish, -1 for none
+		want        [][]string
+	}{
+		{
+			name:        "email subscriber does not see images",
+			subscribers: []string{"email.sent", "image.processed"},
+			publishes:   []publish{{"email.sent", "welcome"}, {"image.processed", "cat.png"}, {"email.sent", "receipt"}},
+			unsubscribe: -1,
+			want:        [][]string{{"welcome", "receipt"}, {"cat.png"}},
+		},
+		{
+			name:        "two subscribers of one type",
+			subscribers: []string{"email.sent", "email.sent"},
+			publishes:   []publish{{"email.sent", "welcome"}, {"image.processed", "cat.png"}},
+			unsubscribe: -1,
+			want:        [][]string{{"welcome"}, {"welcome"}},
+		},
+		{
+			name:        "events without subscribers are dropped",
+			subscribers: []string{"email.sent"},
+			publishes:   []publish{{"pdf.rendered", "invoice.pdf"}, {"email.sent", "welcome"}},
+			unsubscribe: -1,
+			want:        [][]string{{"welcome"}},
+		},
+		{
+			name:        "unsubscribed subscriber misses later events",
+			subscribers: []string{"image.processed", "image.processed"},
+			publishes:   []publish{{"image.processed", "cat.png"}, {"image.processed", "dog.png"}},
+			unsubscribe: 1,
+			want:        [][]string{{"cat.png", "dog.png"}, {"cat.png"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := NewTypedBus[string]()
+			subs := make([]*Subscription[Event[string]], len(tt.subscribers))
+			for i, eventType := range tt.subscribers {
+				subs[i] = bus.Subscribe(eventType, len(tt.publishes))
+			}
+			for i, p := range tt.publishes {
+				bus.Publish(p.eventType, p.payload)
+				if i == 0 && tt.unsubscribe >= 0 {
+					bus.Unsubscribe(subs[tt.unsubscribe])
+				}
+			}
+			bus.Close()
+
+			for i, sub := range subs {
+				var got []string
+				for event := range sub.C {
+					if event.Type != tt.subscribers[i] {
+						t.Errorf("subscriber %d of %s received a %s event", i, tt.subscribers[i], event.Type)
+					}
+					got = append(got, event.Payload)
+				}
+				if !slices.Equal(got, tt.want[i]) {
+					t.Errorf("subscriber %d received %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestTypedBusConcurrent publishes several types from many goroutines while subscribers come
+// and go, and checks that every subscriber only ever receives its own type. Run it with -race.
+func TestTypedBusConcurrent(t *testing.T) {
+	tests := []struct {
+		name                 string
+		types                []string
+		publishers, churners int
+	}{
+		{"two types", []string{"email.sent", "image.processed"}, 4, 4},
+		{"many types", []string{"a", "b", "c", "d", "e", "f"}, 8, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			bus := NewTypedBus[int]()
+			var wg sync.WaitGroup
+			for p := range tt.publishers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range 200 {
+						bus.Publish(tt.types[(p+i)%len(tt.types)], i)
+					}
+				}()
+			}
+			// churners subscribe, read a few events and unsubscribe, over and over
+			for c := range tt.churners {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range 20 {
+						eventType := tt.types[(c+i)%len(tt.types)]
+						sub := bus.Subscribe(eventType, 1)
+						done := make(chan struct{})
+						go func() {
+							defer close(done)
+							for event := range sub.C {
+								if event.Type != eventType {
+									t.Errorf("subscriber of %s received a %s event", eventType, event.Type)
+								}
+							}
+						}()
+						bus.Unsubscribe(sub)
+						<-done
+					}
+				}()
+			}
+			wg.Wait()
+			bus.Close()
+			if len(bus.topics) != 0 || len(bus.subs) != 0 {
+				t.Errorf("%d topics and %d subscriptions left after Close", len(bus.topics), len(bus.subs))
+			}
+		})
+	}
+}
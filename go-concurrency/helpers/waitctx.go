@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+/*
+Context-aware waiting on a plain sync.WaitGroup.
+sync.WaitGroup has no way to give up on Wait, so a stalled goroutine blocks the caller forever.
+WaitCtx moves the Wait to a helper goroutine that closes a channel when it returns, and the
+caller selects on that channel and the context.
+*/
+
+// WaitCtx waits for wg like wg.Wait, but returns ctx.Err() as soon as ctx is cancelled.
+//
+// Caveat: Wait cannot be interrupted, so when ctx wins the helper goroutine keeps waiting on wg
+// and only exits once the group is done. If a goroutine of the group never finishes, the helper
+// leaks with it. WeightedWaitGroup.WaitCtx has no such helper goroutine.
+func WaitCtx(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	err := wait(ctx, done)
+	if err != nil {
+		Log.LogAttrs(ctx, slog.LevelWarn, "wait gave up", slog.Any("error", err))
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestWaitCtx waits on groups that finish or hang against contexts that are cancelled or time
+// out, and checks whichever comes first decides the outcome
+func TestWaitCtx(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		hung    int // Workers that only finish after WaitCtx returned
+		ctx     func() (context.Context, context.CancelFunc)
+		wantErr error
+	}{
+		{"group done", 3, 0, func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) }, nil},
+		{"empty group", 0, 0, func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) }, nil},
+		{"hung worker, timeout", 3, 1, func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 20*time.Millisecond)
+		}, context.DeadlineExceeded},
+		{"hung workers, cancelled", 3, 3, func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(20*time.Millisecond, cancel)
+			return ctx, cancel
+		}, context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			var wg sync.WaitGroup
+			hang := make(chan struct{})
+			defer close(hang)
+			for i := range tt.workers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if i < tt.hung {
+						<-hang
+					}
+				}()
+			}
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			if err := WaitCtx(ctx, &wg); !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("WaitCtx() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestWaitCtxCaveat checks the documented caveat: once the context won, the helper goroutine
+// stays blocked in wg.Wait next to the hung worker, and only exits when the group is done
+func TestWaitCtxCaveat(t *testing.T) {
+	testutil.LeakCheck(t)
+	settled := func(want int) int {
+		n := runtime.NumGoroutine()
+		for deadline := time.Now().Add(time.Second); n != want && time.Now().Before(deadline); n = runtime.NumGoroutine() {
+			time.Sleep(time.Millisecond)
+		}
+		return n
+	}
+	before := runtime.NumGoroutine()
+
+	var wg sync.WaitGroup
+	hang := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-hang
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := WaitCtx(ctx, &wg); !errors.Is(err, context.Canceled) {
+		t.Fatalf("WaitCtx() = %v, want %v", err, context.Canceled)
+	}
+
+	if n := settled(before + 2); n != before+2 {
+		t.Errorf("%d goroutines left behind, want 2: the hung worker and the waiting helper", n-before)
+	}
+	close(hang)
+	if n := settled(before); n != before {
+		t.Errorf("%d goroutines left once the group is done, want 0", n-before)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+WeightedWaitGroup: a WaitGroup counting work units instead of goroutines.
+Tasks of different sizes add their weight (e.g. bytes to upload or images to resize) and mark
+it done, possibly in several steps, and Wait blocks until the weighted counter is back to zero.
+The counter closes a channel when it reaches zero, which also makes the wait cancellable.
+*/
+
+// WeightedWaitGroup waits for a weighted amount of work to complete. The zero value is ready to use.
+type WeightedWaitGroup struct {
+	mu    sync.Mutex
+	count int64
+	zero  chan struct{} // Closed when count drops back to zero, nil before the first Add
+}
+
+// Add adds n work units to the counter. n must not be negative.
+func (wg *WeightedWaitGroup) Add(n int64) {
+	if n < 0 {
+		panic("weighted wait group: negative Add")
+	}
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+	if wg.count == 0 && n > 0 {
+		wg.zero = make(chan struct{})
+	}
+	wg.count += n
+}
+
+// Done marks n work units as completed, releasing the waiters once the counter reaches zero.
+// Completing more units than were added panics, like sync.WaitGroup.
+func (wg *WeightedWaitGroup) Done(n int64) {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+	if n > wg.count || n < 0 {
+		panic("weighted wait group: counter would become negative")
+	}
+	wg.count -= n
+	if wg.count == 0 && n > 0 {
+		close(wg.zero)
+	}
+}
+
+// Wait blocks until the weighted counter is zero
+func (wg *WeightedWaitGroup) Wait() {
+	_ = wg.WaitCtx(context.Background())
+}
+
+// WaitCtx blocks until the weighted counter is zero or ctx is cancelled, returning ctx.Err() then
+func (wg *WeightedWaitGroup) WaitCtx(ctx context.Context) error {
+	wg.mu.Lock()
+	zero := wg.zero
+	wg.mu.Unlock()
+	if zero == nil {
+		return nil
+	}
+	return wait(ctx, zero)
+}
+
+// Pending returns the number of work units not yet done
+func (wg *WeightedWaitGroup) Pending() int64 {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+	return wg.count
+}
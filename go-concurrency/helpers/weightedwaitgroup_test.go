@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestWeightedWaitGroupMixedWeights adds jobs of different weights, completes them in steps of
+// different sizes and checks that Wait only returns once the last unit is done
+func TestWeightedWaitGroupMixedWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []int64
+		steps   []int64 // Units marked done, in order, summing to the total weight
+	}{
+		{"one unit at a time", []int64{2, 5, 12}, []int64{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}},
+		{"whole jobs", []int64{2, 5, 12}, []int64{12, 2, 5}},
+		{"uneven chunks", []int64{10, 1, 100}, []int64{3, 50, 1, 7, 49, 1}},
+		{"zero weights", []int64{0, 4, 0}, []int64{0, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var wg WeightedWaitGroup
+			var total int64
+			for _, w := range tt.weights {
+				wg.Add(w)
+				total += w
+			}
+			waited := make(chan struct{})
+			go func() {
+				wg.Wait()
+				close(waited)
+			}()
+
+			for i, n := range tt.steps {
+				wg.Done(n)
+				total -= n
+				if got := wg.Pending(); got != total {
+					t.Fatalf("Pending() = %d after step %d, want %d", got, i, total)
+				}
+				if total == 0 {
+					break
+				}
+				select {
+				case <-waited:
+					t.Fatalf("Wait returned with %d units pending", total)
+				default:
+				}
+			}
+			select {
+			case <-waited:
+			case <-time.After(time.Second):
+				t.Fatal("Wait still blocked after every unit was done")
+			}
+		})
+	}
+}
+
+// TestWeightedWaitGroupMisuse checks that a negative Add and completing more units than were
+// added panic, like with sync.WaitGroup
+func TestWeightedWaitGroupMisuse(t *testing.T) {
+	tests := []struct {
+		name string
+		use  func(wg *WeightedWaitGroup)
+	}{
+		{"negative Add", func(wg *WeightedWaitGroup) { wg.Add(-1) }},
+		{"Done without Add", func(wg *WeightedWaitGroup) { wg.Done(1) }},
+		{"Done beyond the counter", func(wg *WeightedWaitGroup) { wg.Add(2); wg.Done(3) }},
+		{"negative Done", func(wg *WeightedWaitGroup) { wg.Add(2); wg.Done(-1) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("no panic")
+				}
+			}()
+			var wg WeightedWaitGroup
+			tt.use(&wg)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+Adaptive concurrency for the WorkerPool (AIMD, like TCP congestion control).
+MaxConcurrency workers are started, but only `limit` of them may process a task at once.
+Every AdaptInterval the controller looks at the tasks completed in that interval:
+if too many failed, or their average latency more than doubled compared to the best interval
+seen so far (the downstream is queueing), the limit is halved (multiplicative decrease);
+otherwise, if there was work to do, it grows by one (additive increase).
+The limit always stays within MinConcurrency and MaxConcurrency.
+*/
+
+const (
+	defaultAdaptInterval = 100 * time.Millisecond // Used when AdaptInterval is zero
+	adaptiveMaxErrorRate = 0.1                    // Error rate above which the limit is halved
+	adaptiveLatencyRatio = 2.0                    // Latency increase over the best interval that halves the limit
+)
+
+// adaptiveLimiter is a semaphore whose size is tuned by the adaptive controller
+type adaptiveLimiter struct {
+	min, max int
+
+	mu      sync.Mutex
+	limit   int           // Tasks allowed to be processed at once
+	active  int           // Tasks being processed
+	changed chan struct{} // Closed and replaced whenever a slot may have become available
+
+	// observations of the current interval, swapped to zero by the controller
+	completed    atomic.Int64
+	failed       atomic.Int64
+	latencyNanos atomic.Int64
+	waiting      atomic.Int64 // Workers waiting for a slot, i.e. work that could use more concurrency
+
+	bestLatency time.Duration // Lowest average latency of an interval so far, controller only
+}
+
+// newAdaptiveLimiter creates a limiter starting at initial, clamped to [lo, hi]
+func newAdaptiveLimiter(lo, hi, initial int) *adaptiveLimiter {
+	lo = clamp(lo, 1, hi)
+	return &adaptiveLimiter{
+		min:     lo,
+		max:     hi,
+		limit:   clamp(initial, lo, hi),
+		changed: make(chan struct{}),
+	}
+}
+
+// clamp bounds v to [lo, hi]
+func clamp(v, lo, hi int) int {
+	return min(max(v, lo), hi)
+}
+
+// acquire waits for a processing slot, returning false if done is closed first
+func (l *adaptiveLimiter) acquire(done <-chan struct{}) bool {
+	l.waiting.Add(1)
+	defer l.waiting.Add(-1)
+	for {
+		l.mu.Lock()
+		if l.active < l.limit {
+			l.active++
+			l.mu.Unlock()
+			return true
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-done:
+			return false
+		}
+	}
+}
+
+// release frees a processing slot and records the outcome of the task that held it
+func (l *adaptiveLimiter) release(elapsed time.Duration, err error) {
+	l.completed.Add(1)
+	l.latencyNanos.Add(int64(elapsed))
+	if err != nil {
+		l.failed.Add(1)
+	}
+
+	l.mu.Lock()
+	l.active--
+	l.notify()
+	l.mu.Unlock()
+}
+
+// notify wakes the workers waiting for a slot. Caller must hold mu.
+func (l *adaptiveLimiter) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// current returns the current limit
+func (l *adaptiveLimiter) current() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// adjust applies one AIMD step based on the observations of the last interval
+func (l *adaptiveLimiter) adjust() {
+	completed := l.completed.Swap(0)
+	failed := l.failed.Swap(0)
+	latency := time.Duration(0)
+	if completed > 0 {
+		latency = time.Duration(l.latencyNanos.Swap(0) / completed)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch {
+	case completed == 0:
+		// nothing finished, no signal to act on
+		return
+	case float64(failed)/float64(completed) > adaptiveMaxErrorRate,
+		l.bestLatency > 0 && float64(latency) > adaptiveLatencyRatio*float64(l.bestLatency):
+		l.limit = max(l.limit/2, l.min)
+	case l.waiting.Load() > 0:
+		l.limit = min(l.limit+1, l.max)
+		l.notify()
+	}
+	if l.bestLatency == 0 || latency < l.bestLatency {
+		l.bestLatency = latency
+	}
+}
+
+// adapt runs the controller until the pool shuts down
+func (wp *WorkerPool) adapt(l *adaptiveLimiter) {
+	interval := wp.AdaptInterval
+	if interval <= 0 {
+		interval = defaultAdaptInterval
+	}
+	ticker := wp.clock().NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			l.adjust()
+		case <-wp.done:
+			return
+		}
+	}
+}
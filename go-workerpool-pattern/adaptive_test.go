@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// interval is what the adaptive controller observes between two adjustments
+type interval struct {
+	completed int
+	failed    int
+	latency   time.Duration // Processing time of every completed task
+	backlog   bool          // Whether workers are waiting for a slot
+}
+
+// TestAdaptiveLimiterAIMD feeds the controller intervals of simulated latency and errors and
+// checks the limit after each adjustment: +1 while work waits, halved on errors or when latency
+// doubles over the best interval, always within MinConcurrency and MaxConcurrency
+func TestAdaptiveLimiterAIMD(t *testing.T) {
+	fast, slow := 20*time.Millisecond, 60*time.Millisecond
+	ok := interval{completed: 10, latency: fast, backlog: true}
+	tests := []struct {
+		name            string
+		lo, hi, initial int
+		intervals       []interval
+		wantLimits      []int // Limit after each interval
+	}{
+		{"additive increase under backlog", 1, 16, 2, []interval{ok, ok, ok}, []int{3, 4, 5}},
+		{"no increase without backlog", 1, 16, 2, []interval{{completed: 10, latency: fast}, {completed: 10, latency: fast}}, []int{2, 2}},
+		{"no signal without completions", 1, 16, 4, []interval{{backlog: true}, {backlog: true}}, []int{4, 4}},
+		{"capped at MaxConcurrency", 1, 4, 3, []interval{ok, ok, ok}, []int{4, 4, 4}},
+		{"halved on errors down to MinConcurrency", 2, 16, 12,
+			[]interval{{completed: 10, failed: 5, latency: fast, backlog: true}, {completed: 10, failed: 2, latency: fast}, {completed: 4, failed: 4, latency: fast}},
+			[]int{6, 3, 2}},
+		{"error rate at the threshold is tolerated", 1, 16, 4, []interval{{completed: 10, failed: 1, latency: fast, backlog: true}}, []int{5}},
+		{"halved when latency doubles, then recovers", 1, 16, 8,
+			[]interval{ok, {completed: 10, latency: slow, backlog: true}, ok, ok},
+			[]int{9, 4, 5, 6}},
+		{"latency below double is tolerated", 1, 16, 8,
+			[]interval{ok, {completed: 10, latency: 2 * fast, backlog: true}},
+			[]int{9, 10}},
+		{"initial limit clamped", 3, 6, 10, []interval{{completed: 10, failed: 10, latency: fast}}, []int{3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newAdaptiveLimiter(tt.lo, tt.hi, tt.initial)
+			var limits []int
+			for _, iv := range tt.intervals {
+				for i := range iv.completed {
+					if !l.acquire(nil) {
+						t.Fatal("no slot for a task")
+					}
+					var err error
+					if i < iv.failed {
+						err = errors.New("downstream overloaded")
+					}
+					l.release(iv.latency, err)
+				}
+				if iv.backlog {
+					l.waiting.Add(1)
+				}
+				l.adjust()
+				if iv.backlog {
+					l.waiting.Add(-1)
+				}
+				limits = append(limits, l.current())
+			}
+			if !slices.Equal(limits, tt.wantLimits) {
+				t.Errorf("limits %v, want %v", limits, tt.wantLimits)
+			}
+		})
+	}
+}
+
+// TestStatsReportsAdaptiveConcurrency checks that Stats reports the controller's limit, which
+// starts at Concurrency clamped to MinConcurrency and MaxConcurrency, or the worker count
+// without the controller
+func TestStatsReportsAdaptiveConcurrency(t *testing.T) {
+	tests := []struct {
+		name                string
+		concurrency, lo, hi int
+		want                int
+	}{
+		{"within the bounds", 2, 1, 16, 2},
+		{"above MaxConcurrency", 20, 1, 16, 16},
+		{"below MinConcurrency", 1, 4, 16, 4},
+		{"without the controller", 5, 0, 0, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wp := &WorkerPool{Concurrency: tt.concurrency, MinConcurrency: tt.lo, MaxConcurrency: tt.hi, Clock: NewFakeClock(time.Unix(0, 0))}
+			if err := wp.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer wp.Close()
+			if got := wp.Stats().Concurrency; got != tt.want {
+				t.Errorf("Stats().Concurrency = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
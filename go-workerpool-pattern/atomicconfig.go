@@ -0,0 +1,107 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+/*
+Hot-reloadable configuration.
+AtomicConfig holds a value that one goroutine can replace while many others keep reading it,
+without locks: every Store publishes a new immutable snapshot through an atomic pointer. A
+running WorkerPool reads its live PoolSettings from one on every loop iteration, so limits can
+be tuned (e.g. from a reloaded config file) without restarting the pool.
+*/
+
+// AtomicConfig is a value safe for concurrent Load and Store. Store the value as a whole and do
+// not modify it afterwards, as readers may still hold it. The zero value holds the zero T.
+type AtomicConfig[T any] struct {
+	current atomic.Pointer[configSnapshot[T]]
+}
+
+// configSnapshot is one stored value with the channel closed when it is replaced
+type configSnapshot[T any] struct {
+	value   T
+	changed chan struct{}
+}
+
+// NewAtomicConfig creates an AtomicConfig holding initial
+func NewAtomicConfig[T any](initial T) *AtomicConfig[T] {
+	c := &AtomicConfig[T]{}
+	c.Store(initial)
+	return c
+}
+
+// Load returns the current value
+func (c *AtomicConfig[T]) Load() T {
+	value, _ := c.Watch()
+	return value
+}
+
+// Store replaces the current value and wakes everyone waiting on Watch
+func (c *AtomicConfig[T]) Store(value T) {
+	old := c.current.Swap(&configSnapshot[T]{value: value, changed: make(chan struct{})})
+	if old != nil {
+		close(old.changed)
+	}
+}
+
+// Watch returns the current value and a channel closed once it is replaced, so a goroutine
+// waiting for a new value does not have to poll
+func (c *AtomicConfig[T]) Watch() (T, <-chan struct{}) {
+	s := c.current.Load()
+	if s == nil {
+		// first use of a zero AtomicConfig, publish the zero value unless a Store won the race
+		c.current.CompareAndSwap(nil, &configSnapshot[T]{changed: make(chan struct{})})
+		s = c.current.Load()
+	}
+	return s.value, s.changed
+}
+
+// PoolSettings are the settings of a running WorkerPool that can be swapped through its Settings
+type PoolSettings struct {
+	ActiveWorkers int           // Number of workers taking tasks, the others idle until it is raised; zero means all
+	TaskInterval  time.Duration // Minimum pause of each worker between two tasks, a per-worker rate limit; zero means none
+}
+
+// awaitActive blocks worker id while the live settings leave it inactive. It returns once the
+// settings activate it, or the pool is cancelled or shutting down so the worker can exit.
+func (wp *WorkerPool) awaitActive(id int) {
+	if wp.Settings == nil {
+		return
+	}
+	var stop <-chan struct{} // nil blocks forever, without ShutdownOrder workers exit on done
+	if wp.stops != nil {
+		stop = wp.stops.stop[id]
+	}
+	for {
+		settings, changed := wp.Settings.Watch()
+		if settings.ActiveWorkers <= 0 || id < settings.ActiveWorkers {
+			return
+		}
+		select {
+		case <-changed:
+		case <-wp.ctx.Done():
+			return
+		case <-wp.done:
+			return
+		case <-stop:
+			return
+		}
+	}
+}
+
+// pace makes a worker wait TaskInterval of the live settings after a task
+func (wp *WorkerPool) pace() {
+	if wp.Settings == nil {
+		return
+	}
+	if interval := wp.Settings.Load().TaskInterval; interval > 0 {
+		timer := wp.clock().NewTimer(interval)
+		defer timer.Stop()
+		select {
+		case <-timer.C():
+		case <-wp.ctx.Done():
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestAtomicConfigConcurrent has writers Store while readers Load and Watch, and checks that
+// readers only ever see whole values, that every Store closes the channel of the value it
+// replaced, and that the last value stored wins. Run it with -race.
+func TestAtomicConfigConcurrent(t *testing.T) {
+	// pair is consistent when B == 2*A, a torn read would break that
+	type pair struct{ A, B int }
+	tests := []struct {
+		name             string
+		config           func() *AtomicConfig[pair]
+		writers, readers int
+	}{
+		{"zero value", func() *AtomicConfig[pair] { return &AtomicConfig[pair]{} }, 1, 8},
+		{"NewAtomicConfig", func() *AtomicConfig[pair] { return NewAtomicConfig(pair{1, 2}) }, 1, 8},
+		{"many writers", func() *AtomicConfig[pair] { return NewAtomicConfig(pair{1, 2}) }, 4, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			c := tt.config()
+			const stores = 1000
+			var writers, readers sync.WaitGroup
+			stop := make(chan struct{})
+			for w := range tt.writers {
+				writers.Add(1)
+				go func() {
+					defer writers.Done()
+					for i := range stores {
+						_, changed := c.Watch()
+						n := w*stores + i
+						c.Store(pair{n, 2 * n})
+						select {
+						case <-changed:
+						default:
+							t.Error("Store left the channel of the replaced value open")
+						}
+					}
+				}()
+			}
+			for range tt.readers {
+				readers.Add(1)
+				go func() {
+					defer readers.Done()
+					for {
+						if v := c.Load(); v.B != 2*v.A {
+							t.Errorf("Load returned the torn value %+v", v)
+						}
+						select {
+						case <-stop:
+							return
+						default:
+						}
+					}
+				}()
+			}
+			writers.Wait()
+			close(stop)
+			readers.Wait()
+
+			if tt.writers == 1 {
+				if v := c.Load(); v != (pair{stores - 1, 2 * (stores - 1)}) {
+					t.Errorf("Load after the writer is done = %+v, want the last value stored", v)
+				}
+			}
+		})
+	}
+}
+
+// TestLiveSettingsActiveWorkers starts a pool of four workers with blocking tasks queued, changes
+// ActiveWorkers while they run and checks how many tasks run before and after the change: raised
+// limits wake the idle workers, lowered ones let the running tasks finish
+func TestLiveSettingsActiveWorkers(t *testing.T) {
+	tests := []struct {
+		name                  string
+		before, after         int // ActiveWorkers at the start and once the first tasks run
+		wantBefore, wantAfter int
+	}{
+		{"raised from one to all", 1, 0, 1, 4},
+		{"raised from two to three", 2, 3, 2, 3},
+		{"lowered while busy", 3, 1, 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			settings := NewAtomicConfig(PoolSettings{ActiveWorkers: tt.before})
+			wp := &WorkerPool{Concurrency: 4, Settings: settings}
+			if err := wp.Start(); err != nil {
+				t.Fatal(err)
+			}
+			started, release := make(chan struct{}, 4), make(chan struct{})
+			for i := range 4 {
+				if err := wp.Submit(Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+					started <- struct{}{}
+					<-release
+					return nil, nil
+				}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			running := 0
+			expect := func(want int, when string) {
+				t.Helper()
+				for ; running < want; running++ {
+					select {
+					case <-started:
+					case <-time.After(time.Second):
+						t.Fatalf("%d tasks running %s, want %d", running, when, want)
+					}
+				}
+				select {
+				case <-started:
+					t.Fatalf("%d tasks running %s, want %d", running+1, when, want)
+				case <-time.After(20 * time.Millisecond):
+				}
+			}
+
+			expect(tt.wantBefore, "at the start")
+			settings.Store(PoolSettings{ActiveWorkers: tt.after})
+			expect(tt.wantAfter, "after the change")
+			close(release)
+			wp.Close()
+		})
+	}
+}
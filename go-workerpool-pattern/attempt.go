@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+/*
+A single processing attempt of a WorkerPool task.
+Every call to Process goes through attempt, which runs the tracing hooks around it and
+bounds it with TaskTimeout. The hooks let callers create tracing spans without the pool
+depending on any tracing library, and AfterProcess fires on every path: success, error,
+timeout, cancellation and panic. The PanicPolicy decides what happens to a panic after the
+hook ran: it is re-raised (crashing the program, the default) or recovered into an
+ErrTaskPanicked error that either fails the task right away or is retried like any other error.
+*/
+
+// PanicPolicy is how the pool handles a task whose Process panics
+type PanicPolicy int
+
+const (
+	PanicCrash        PanicPolicy = iota // Re-raise the panic on the worker goroutine, crashing the program
+	PanicRecoverFail                     // Recover and fail the task with ErrTaskPanicked, without retrying
+	PanicRecoverRetry                    // Recover and retry the attempt like any other error, up to MaxRetries
+)
+
+// attempt runs one Process call wrapped in the BeforeProcess / AfterProcess hooks.
+// ctx is the pool context, or the context of a hedge race. A panic is re-raised or, depending
+// on the PanicPolicy, returned as an error wrapping ErrTaskPanicked.
+func (wp *WorkerPool) attempt(ctx context.Context, task Task) (value any, err error) {
+	var handle any
+	if wp.BeforeProcess != nil {
+		handle = wp.BeforeProcess(task)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			panicErr := fmt.Errorf("%w: %v", ErrTaskPanicked, r)
+			if wp.AfterProcess != nil {
+				wp.AfterProcess(task, handle, panicErr)
+			}
+			if wp.PanicPolicy == PanicCrash {
+				panic(r)
+			}
+			value, err = nil, panicErr
+			return
+		}
+		if wp.AfterProcess != nil {
+			wp.AfterProcess(task, handle, err)
+		}
+	}()
+
+	return wp.processWithTimeout(ctx, task)
+}
+
+// processWithTimeout calls Process, giving up after TaskTimeout (a fresh deadline per attempt)
+// or once parent is done. The task's done channel is closed at that point; a task that ignores
+// it keeps running in the background but its outcome is discarded and ErrTaskTimeout (or
+// ErrTaskCancelled when parent is done) is reported instead.
+func (wp *WorkerPool) processWithTimeout(parent context.Context, task Task) (any, error) {
+	if wp.TaskTimeout <= 0 && wp.CumulativeTimeout <= 0 {
+		return task.Process(parent.Done())
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if wp.TaskTimeout > 0 {
+		ctx, cancel = withTimeout(parent, wp.clock(), wp.TaskTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+	defer cancel()
+
+	finished := runAsync(func() (any, error) { return task.Process(ctx.Done()) })
+	select {
+	case o := <-finished:
+		return o.result()
+	case <-ctx.Done():
+		if parent.Err() != nil {
+			return nil, ErrTaskCancelled
+		}
+		return nil, ErrTaskTimeout
+	}
+}
+
+// attemptOutcome is the outcome of an attempt run on another goroutine
+type attemptOutcome struct {
+	value    any
+	err      error
+	panicked any // Value the attempt panicked with, if any
+}
+
+// result returns the outcome, re-raising a forwarded panic on the calling goroutine so it
+// behaves like a panic of an attempt run in place
+func (o attemptOutcome) result() (any, error) {
+	if o.panicked != nil {
+		panic(o.panicked)
+	}
+	return o.value, o.err
+}
+
+// runAsync runs fn on a new goroutine and delivers its outcome, including a panic, on the
+// returned channel. The channel is buffered so the goroutine never blocks on an abandoned result.
+func runAsync(fn func() (any, error)) <-chan attemptOutcome {
+	finished := make(chan attemptOutcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				finished <- attemptOutcome{panicked: r}
+			}
+		}()
+		value, err := fn()
+		finished <- attemptOutcome{value: value, err: err}
+	}()
+	return finished
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestTimeoutModes runs a task that hangs on a FakeClock with MaxRetries 2 and checks the number
+// of attempts and when the task fails: TaskTimeout gives every attempt a fresh deadline, while
+// CumulativeTimeout bounds all of them together
+func TestTimeoutModes(t *testing.T) {
+	tests := []struct {
+		name         string
+		hang         time.Duration // Fake time every attempt takes unless cancelled
+		taskTimeout  time.Duration
+		cumulative   time.Duration
+		wantAttempts int32
+		wantElapsed  time.Duration
+		wantErr      error
+	}{
+		{"per attempt", 1500 * time.Millisecond, time.Second, 0, 3, 3 * time.Second, ErrTaskTimeout},
+		{"cumulative", 1500 * time.Millisecond, 0, time.Second, 1, time.Second, ErrTaskTimeout},
+		{"cumulative cuts the last attempt", 1500 * time.Millisecond, time.Second, 2500 * time.Millisecond, 3, 2500 * time.Millisecond, ErrTaskTimeout},
+		{"cumulative after every attempt", 1500 * time.Millisecond, time.Second, 10 * time.Second, 3, 3 * time.Second, ErrTaskTimeout},
+		{"within both limits", 500 * time.Millisecond, time.Second, time.Second, 1, 500 * time.Millisecond, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			clock := NewFakeClock(time.Unix(0, 0))
+			start := clock.Now()
+			var attempts atomic.Int32
+			wp := &WorkerPool{Concurrency: 1, MaxRetries: 2, Clock: clock, TaskTimeout: tt.taskTimeout, CumulativeTimeout: tt.cumulative,
+				Tasks: []Task{{Id: 1, Work: func(done <-chan struct{}) (any, error) {
+					attempts.Add(1)
+					timer := clock.NewTimer(tt.hang)
+					defer timer.Stop()
+					select {
+					case <-timer.C():
+						return nil, nil
+					case <-done:
+						return nil, errors.New("call aborted")
+					}
+				}}}}
+			errc := make(chan error, 1)
+			go func() { errc <- wp.RunMap()[1] }()
+
+			// an attempt is on the clock with its hang and every configured timeout
+			want := 1
+			for _, d := range []time.Duration{tt.taskTimeout, tt.cumulative} {
+				if d > 0 {
+					want++
+				}
+			}
+			var err error
+			for finished := false; !finished; {
+				for wait := time.Now().Add(5 * time.Second); !finished && clock.Waiters() != want; {
+					select {
+					case err = <-errc:
+						finished = true
+					case <-time.After(time.Millisecond):
+					}
+					if time.Now().After(wait) {
+						t.Fatalf("%d timers on the clock at %v, want %d", clock.Waiters(), clock.Now().Sub(start), want)
+					}
+				}
+				if !finished {
+					clock.Advance(100 * time.Millisecond)
+				}
+			}
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("task failed with %v, want %v", err, tt.wantErr)
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("%d attempts, want %d", got, tt.wantAttempts)
+			}
+			if elapsed := clock.Now().Sub(start); elapsed != tt.wantElapsed {
+				t.Errorf("task finished after %v, want %v", elapsed, tt.wantElapsed)
+			}
+		})
+	}
+}
+
+// TestPanicPolicy runs a task panicking on its first attempts under each PanicPolicy and checks
+// the outcome: PanicCrash re-raises the panic from the attempt (it would crash the worker, so the
+// attempt is called directly), PanicRecoverFail fails the task without retrying it and
+// PanicRecoverRetry retries it like an error
+func TestPanicPolicy(t *testing.T) {
+	const panicValue = "assignment to entry in nil map"
+	tests := []struct {
+		name        string
+		policy      PanicPolicy
+		timeout     time.Duration // TaskTimeout, runs the attempt on another goroutine
+		panics      int32         // Attempts that panic before one succeeds
+		wantCrash   bool
+		wantErr     error
+		wantAttempt int32
+	}{
+		{"crash", PanicCrash, 0, 1, true, nil, 1},
+		{"crash with TaskTimeout", PanicCrash, time.Minute, 1, true, nil, 1},
+		{"recover and fail", PanicRecoverFail, 0, 1, false, ErrTaskPanicked, 1},
+		{"recover and fail with TaskTimeout", PanicRecoverFail, time.Minute, 1, false, ErrTaskPanicked, 1},
+		{"recover and retry", PanicRecoverRetry, 0, 1, false, nil, 2},
+		{"recover and retry until MaxRetries", PanicRecoverRetry, 0, 5, false, ErrTaskPanicked, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			var attempts atomic.Int32
+			task := Task{Id: 1, Work: func(done <-chan struct{}) (any, error) {
+				if attempts.Add(1) <= tt.panics {
+					panic(panicValue)
+				}
+				return "ok", nil
+			}}
+			var hookErr error
+			wp := &WorkerPool{Concurrency: 1, MaxRetries: 2, PanicPolicy: tt.policy, TaskTimeout: tt.timeout,
+				AfterProcess: func(task Task, handle any, err error) { hookErr = err }}
+
+			if tt.wantCrash {
+				func() {
+					defer func() {
+						if r := recover(); r != panicValue {
+							t.Errorf("attempt panicked with %v, want the task's panic re-raised", r)
+						}
+					}()
+					wp.attempt(context.Background(), task)
+				}()
+				if !errors.Is(hookErr, ErrTaskPanicked) {
+					t.Errorf("AfterProcess saw %v before the crash, want ErrTaskPanicked", hookErr)
+				}
+				return
+			}
+
+			var err error
+			wp.Tasks = []Task{task}
+			wp.OnResult = func(task Task, result Result) { err = result.Err }
+			if runErr := wp.Run(); runErr != nil {
+				t.Fatal(runErr)
+			}
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("task finished with %v, want %v", err, tt.wantErr)
+			}
+			if n := attempts.Load(); n != tt.wantAttempt {
+				t.Errorf("%d attempts, want %d", n, tt.wantAttempt)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"slices"
+	"sync"
+)
+
+/*
+Bookkeeping of the submitted tasks: which are still queued and which finished processing.
+When a batch is aborted (e.g. because its Deadline passed) the pool can tell the caller
+which tasks completed and which never ran or were cancelled midway, so the rest can be
+resubmitted later.
+Queued tasks can be cancelled individually. Tasks already sitting in a channel cannot be
+removed from it, so cancelling leaves a tombstone instead: the worker that dequeues the task
+finds the tombstone and skips it, which behaves as if the task had been removed from the queue.
+Tasks submitted by a Scheduler are not tracked: a scheduler can run for the lifetime of the
+process, and keeping an entry per tick would grow without bound.
+*/
+
+// batchTracker records the submitted tasks, the queued ones and the ones that finished processing
+type batchTracker struct {
+	mu         sync.Mutex
+	submitted  []int        // Ids in submission order
+	queued     map[int]int  // Number of tasks per Id submitted but not yet picked up by a worker
+	tombstones map[int]int  // Number of queued tasks per Id cancelled with CancelTask
+	completed  map[int]bool // Ids of tasks that finished processing, successfully or not
+	failed     map[int]bool // Ids of completed tasks whose latest result was an error
+
+	keep    bool     // Whether complete keeps the results, only set by RunWithContext
+	results []Result // Results of the completed tasks in completion order, kept only with keep
+}
+
+// submit records a task accepted by the pool
+func (b *batchTracker) submit(task Task) {
+	if task.untracked {
+		return
+	}
+	id := task.Id
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.queued == nil {
+		b.queued = make(map[int]int)
+		b.tombstones = make(map[int]int)
+	}
+	b.submitted = append(b.submitted, id)
+	b.queued[id]++
+}
+
+// start records that a worker picked up a task, reporting false if the task was cancelled
+// while queued and must be skipped
+func (b *batchTracker) start(task Task) bool {
+	if task.untracked {
+		return true
+	}
+	id := task.Id
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tombstones[id] > 0 {
+		b.tombstones[id]--
+		return false
+	}
+	b.queued[id]--
+	return true
+}
+
+// withdraw removes the most recent submission of a task that never reached the queue
+func (b *batchTracker) withdraw(task Task) {
+	if task.untracked {
+		return
+	}
+	id := task.Id
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := len(b.submitted) - 1; i >= 0; i-- {
+		if b.submitted[i] == id {
+			b.submitted = slices.Delete(b.submitted, i, i+1)
+			break
+		}
+	}
+	// a CancelTask in the meantime turned the queued entry into a tombstone
+	if b.queued[id] > 0 {
+		b.queued[id]--
+	} else if b.tombstones[id] > 0 {
+		b.tombstones[id]--
+	}
+}
+
+// cancel marks a queued task with the given Id as cancelled, reporting false if none is queued
+func (b *batchTracker) cancel(id int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.queued[id] == 0 {
+		return false
+	}
+	b.queued[id]--
+	b.tombstones[id]++
+	return true
+}
+
+// complete records the result of a task that finished processing
+func (b *batchTracker) complete(task Task, result Result) {
+	if task.untracked {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.completed == nil {
+		b.completed = make(map[int]bool)
+		b.failed = make(map[int]bool)
+	}
+	b.completed[result.TaskId] = true
+	if result.Err != nil {
+		b.failed[result.TaskId] = true
+	} else {
+		delete(b.failed, result.TaskId)
+	}
+	if b.keep {
+		b.results = append(b.results, result)
+	}
+}
+
+// keepResults makes complete keep the results for completedResults. Only RunWithContext returns
+// them; streaming pools and the other batch runs (Reduce, RunMap) keep none, so a long-running
+// pool does not buffer every result it ever produced.
+func (b *batchTracker) keepResults() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.keep = true
+}
+
+// completedResults returns the results of the completed tasks, in completion order
+func (b *batchTracker) completedResults() []Result {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.results)
+}
+
+// split returns the submitted Ids that completed and those that did not, in submission order
+func (b *batchTracker) split() (completed, unfinished []int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, id := range b.submitted {
+		if b.completed[id] {
+			completed = append(completed, id)
+		} else {
+			unfinished = append(unfinished, id)
+		}
+	}
+	return slices.Clip(completed), slices.Clip(unfinished)
+}
+
+// succeeded returns the submitted Ids that completed successfully and the others, which did not
+// finish or failed, in submission order
+func (b *batchTracker) succeeded() (succeeded, rest []int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, id := range b.submitted {
+		if b.completed[id] && !b.failed[id] {
+			succeeded = append(succeeded, id)
+		} else {
+			rest = append(rest, id)
+		}
+	}
+	return slices.Clip(succeeded), slices.Clip(rest)
+}
+
+// Completed returns the Ids of the submitted tasks that finished processing (successfully or with
+// an error), in submission order
+func (wp *WorkerPool) Completed() []int {
+	completed, _ := wp.batch.split()
+	return completed
+}
+
+// Unfinished returns the Ids of the submitted tasks that were skipped or cancelled midway,
+// e.g. because the Deadline passed, in submission order
+func (wp *WorkerPool) Unfinished() []int {
+	_, unfinished := wp.batch.split()
+	return unfinished
+}
+
+// CancelTask removes a task that is still queued (including a delayed one not yet due), so it
+// is never processed: it reports ErrTaskCancelled and counts as unfinished. It returns false if
+// no task with that Id is queued, because it already started, finished or was never submitted.
+// With duplicate Ids one queued task is cancelled per call. In-flight tasks are not affected;
+// use Cancel to cancel the whole pool.
+func (wp *WorkerPool) CancelTask(id int) bool {
+	return wp.batch.cancel(id)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestRunWithContextPartialResults checks that RunWithContext returns the results of the tasks
+// that completed, also when the batch is cancelled midway, and that the other tasks are reported
+// as unfinished
+func TestRunWithContextPartialResults(t *testing.T) {
+	const total = 20
+	tests := []struct {
+		name        string
+		cancelAfter int32 // Results after which the batch is cancelled, 0 to let it complete
+		wantErr     error
+	}{
+		{name: "completed batch", wantErr: nil},
+		{name: "cancelled midway", cancelAfter: 6, wantErr: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			tasks := make([]Task, total)
+			for i := range tasks {
+				id := i + 1
+				tasks[i] = Task{Id: id, Work: func(done <-chan struct{}) (any, error) { return id * id, nil }}
+			}
+			var received atomic.Int32
+			wp := WorkerPool{Tasks: tasks, Concurrency: 2, OnResult: func(Task, Result) {
+				if received.Add(1) == tt.cancelAfter {
+					cancel()
+				}
+			}}
+
+			results, err := wp.RunWithContext(ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RunWithContext error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.cancelAfter > 0 && (len(results) < int(tt.cancelAfter) || len(results) == total) {
+				t.Fatalf("got %d results, want at least %d and fewer than %d", len(results), tt.cancelAfter, total)
+			}
+			if tt.cancelAfter == 0 && len(results) != total {
+				t.Fatalf("got %d results, want %d", len(results), total)
+			}
+			for _, r := range results {
+				if r.Err != nil || r.Value != r.TaskId*r.TaskId {
+					t.Errorf("result of task %d = %v, %v, want %d", r.TaskId, r.Value, r.Err, r.TaskId*r.TaskId)
+				}
+			}
+			if got := len(results) + len(wp.Unfinished()); got != total {
+				t.Errorf("%d results and %d unfinished tasks, want %d in total", len(results), len(wp.Unfinished()), total)
+			}
+		})
+	}
+}
+
+// TestBatchKeepsNoResults checks that only RunWithContext keeps results: the other runs hand
+// them to a hook and a streaming pool never buffers them
+func TestBatchKeepsNoResults(t *testing.T) {
+	newTasks := func() []Task {
+		tasks := make([]Task, 50)
+		for i := range tasks {
+			tasks[i] = Task{Id: i, Work: func(done <-chan struct{}) (any, error) { return make([]byte, 1024), nil }}
+		}
+		return tasks
+	}
+	tests := []struct {
+		name string
+		run  func(wp *WorkerPool)
+	}{
+		{"Run", func(wp *WorkerPool) { wp.Run() }},
+		{"Reduce", func(wp *WorkerPool) {
+			Reduce(wp, 0, func(n int, _ Result) int { return n + 1 })
+		}},
+		{"RunMap", func(wp *WorkerPool) { wp.RunMap() }},
+		{"streaming", func(wp *WorkerPool) {
+			tasks := wp.Tasks
+			wp.Tasks = nil
+			wp.Start()
+			for _, task := range tasks {
+				if err := wp.Submit(task); err != nil {
+					t.Fatal(err)
+				}
+			}
+			wp.Close()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			wp := &WorkerPool{Tasks: newTasks(), Concurrency: 4}
+			tt.run(wp)
+			if n := len(wp.batch.completedResults()); n != 0 {
+				t.Errorf("the pool kept %d results, want none", n)
+			}
+			if n := len(wp.Completed()); n != 50 {
+				t.Errorf("Completed() has %d Ids, want 50", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+/*
+Circuit breaker for the multi-type worker pool.
+After FailureThreshold consecutive failures of one task type the breaker trips open and
+tasks of that type are fast-failed for Cooldown. Once the cooldown has passed the breaker
+half-opens and lets a single probe task through: success closes it again, failure re-opens it.
+*/
+
+// ErrCircuitOpen is reported for tasks rejected because the breaker of their type is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of the circuit for one task type
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // Tasks flow normally
+	BreakerOpen                         // Tasks are fast-failed until the cooldown passes
+	BreakerHalfOpen                     // A single probe task is allowed through
+)
+
+// String returns a readable name of the breaker state
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuit tracks the breaker state of a single task type
+type circuit struct {
+	state    BreakerState
+	failures int       // Consecutive failures while closed
+	openedAt time.Time // When the breaker last tripped open
+	probing  bool      // Whether the half-open probe is in flight
+}
+
+// CircuitBreaker tracks failures per task type and decides whether tasks may run
+type CircuitBreaker struct {
+	FailureThreshold int           // Consecutive failures that trip the breaker open
+	Cooldown         time.Duration // How long the breaker stays open before probing recovery
+	Clock            Clock         // Optional source of time for the cooldown, nil uses the real clock
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// circuitFor returns the circuit of a type, creating it on first use. Caller must hold mu.
+func (cb *CircuitBreaker) circuitFor(typeName string) *circuit {
+	if cb.circuits == nil {
+		cb.circuits = make(map[string]*circuit)
+	}
+	c, ok := cb.circuits[typeName]
+	if !ok {
+		c = &circuit{}
+		cb.circuits[typeName] = c
+	}
+	return c
+}
+
+// Allow reports whether a task of the given type may be processed now
+func (cb *CircuitBreaker) Allow(typeName string) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c := cb.circuitFor(typeName)
+	switch c.state {
+	case BreakerOpen:
+		if clockOrReal(cb.Clock).Now().Sub(c.openedAt) < cb.Cooldown {
+			return false
+		}
+		// cooldown passed, let one probe through
+		c.state = BreakerHalfOpen
+		c.probing = true
+		return true
+	case BreakerHalfOpen:
+		if c.probing {
+			return false
+		}
+		c.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Record updates the circuit of a type with the outcome of a processed task
+func (cb *CircuitBreaker) Record(typeName string, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c := cb.circuitFor(typeName)
+	if err == nil {
+		c.state = BreakerClosed
+		c.failures = 0
+		c.probing = false
+		return
+	}
+
+	c.failures++
+	if c.state == BreakerHalfOpen || c.failures >= cb.FailureThreshold {
+		c.state = BreakerOpen
+		c.openedAt = clockOrReal(cb.Clock).Now()
+		c.probing = false
+	}
+}
+
+// States returns a snapshot of the breaker state of every type seen so far
+func (cb *CircuitBreaker) States() map[string]BreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	states := make(map[string]BreakerState, len(cb.circuits))
+	for name, c := range cb.circuits {
+		states[name] = c.state
+	}
+	return states
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+/*
+Clock abstraction for the time-dependent features of the WorkerPool.
+Timeouts, the Deadline, backoff, hedging, delayed tasks, idle shutdown, ramp-up, the adaptive
+controller and the circuit breaker cooldown read time through a Clock. The default is the real
+clock; a test can inject a FakeClock and advance it explicitly instead of sleeping, which makes
+timing features fast and deterministic to check.
+A wait that can be abandoned, e.g. a timeout raced against a result in a select, uses NewTimer
+and stops the timer once it is done waiting. A FakeClock would otherwise keep the abandoned
+timer until the fake time reaches it, and Waiters would count it as somebody blocked on the clock.
+*/
+
+// Clock is the source of time used by the pool
+type Clock interface {
+	Now() time.Time                         // Current time, see time.Now
+	After(d time.Duration) <-chan time.Time // Delivers the time once d has elapsed, see time.After
+	NewTimer(d time.Duration) Timer         // Like After, but can be stopped, see time.NewTimer
+	NewTicker(d time.Duration) Ticker       // Delivers the time every d, see time.NewTicker
+}
+
+// Timer delivers the time once on its channel, unless it is stopped first
+type Timer interface {
+	C() <-chan time.Time // Channel the time is delivered on
+	Stop()               // Stops the timer, releasing it if it has not fired yet
+}
+
+// Ticker delivers the ticks of a Clock
+type Ticker interface {
+	C() <-chan time.Time // Channel the ticks are delivered on
+	Stop()               // Stops the ticker, no more ticks are delivered
+}
+
+// realClock is the Clock backed by the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+// realTicker adapts a time.Ticker to the Ticker interface
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time { return t.ticker.C }
+func (t realTicker) Stop()               { t.ticker.Stop() }
+
+// realTimer adapts a time.Timer to the Timer interface
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time { return t.timer.C }
+func (t realTimer) Stop()               { t.timer.Stop() }
+
+// clockOrReal returns c, or the real clock if c is nil
+func clockOrReal(c Clock) Clock {
+	if c == nil {
+		return realClock{}
+	}
+	return c
+}
+
+// clock returns the pool's Clock, the real clock by default
+func (wp *WorkerPool) clock() Clock {
+	return clockOrReal(wp.Clock)
+}
+
+// withDeadline is context.WithDeadline driven by clock. With the real clock it is exactly
+// context.WithDeadline; any other clock cancels the context with context.DeadlineExceeded as
+// its cause once the clock reaches the deadline (see ctxErr).
+func withDeadline(parent context.Context, clock Clock, at time.Time) (context.Context, context.CancelFunc) {
+	if _, ok := clock.(realClock); ok {
+		return context.WithDeadline(parent, at)
+	}
+
+	ctx, cancel := context.WithCancelCause(parent)
+	timer := clock.NewTimer(at.Sub(clock.Now()))
+	go func() {
+		defer timer.Stop()
+		select {
+		case <-timer.C():
+			cancel(context.DeadlineExceeded)
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() { cancel(nil) }
+}
+
+// withTimeout is context.WithTimeout driven by clock, see withDeadline
+func withTimeout(parent context.Context, clock Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	return withDeadline(parent, clock, clock.Now().Add(d))
+}
+
+// ctxErr returns ctx.Err(), reporting context.DeadlineExceeded for a context whose deadline
+// passed on a custom clock as well
+func ctxErr(ctx context.Context) error {
+	if errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+		return context.DeadlineExceeded
+	}
+	return ctx.Err()
+}
+
+// FakeClock is a Clock whose time only moves when Advance is called. Timers and tickers fire
+// during Advance, in due-time order, once the fake time reaches them. A tick is dropped if the
+// previous one was not received yet, like with time.Ticker. A timer leaves the clock once it
+// fires or is stopped; a channel of After cannot be stopped, so it stays until it fires. The
+// zero value is not usable, use NewFakeClock.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeTimer
+}
+
+// fakeTimer is a pending After channel or ticker of a FakeClock
+type fakeTimer struct {
+	clock  *FakeClock
+	at     time.Time      // Fake time at which it fires next
+	period time.Duration  // Interval of a ticker, zero for a one-shot After
+	ch     chan time.Time // Buffered channel the time is delivered on
+}
+
+// NewFakeClock creates a fake clock starting at the given time
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now returns the current fake time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the fake time once Advance moved it d past now
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer returns a timer that fires once Advance moved the fake time d past now. Stopping it
+// removes it from the clock.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, at: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.ch <- c.now
+		return t
+	}
+	c.waiters = append(c.waiters, t)
+	return t
+}
+
+// NewTicker returns a ticker that ticks every d of fake time. It panics if d is not positive.
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for FakeClock.NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, at: c.now.Add(d), period: d, ch: make(chan time.Time, 1)}
+	c.waiters = append(c.waiters, t)
+	return t
+}
+
+// Waiters returns the number of pending timers and tickers, so a test can wait until the code
+// under test is blocked on the clock before advancing it. Fired and stopped timers are not
+// counted.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// Advance moves the fake time forward by d and fires every timer and ticker that became due
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	end := c.now.Add(d)
+	for {
+		next := -1
+		for i, t := range c.waiters {
+			if !t.at.After(end) && (next < 0 || t.at.Before(c.waiters[next].at)) {
+				next = i
+			}
+		}
+		if next < 0 {
+			break
+		}
+
+		t := c.waiters[next]
+		c.now = t.at
+		select {
+		case t.ch <- t.at:
+		default:
+		}
+		if t.period > 0 {
+			t.at = t.at.Add(t.period)
+		} else {
+			c.waiters = append(c.waiters[:next], c.waiters[next+1:]...)
+		}
+	}
+	c.now = end
+}
+
+// C returns the channel the time is delivered on
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+// Stop removes the timer or ticker from its clock, nothing more is delivered
+func (t *fakeTimer) Stop() {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, w := range c.waiters {
+		if w == t {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
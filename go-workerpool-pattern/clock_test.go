@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// advanceUntil advances clock by step, leaving the pool a moment of real time to react after
+// each step, until cond holds. It fails the test if that takes more than five seconds of real time.
+func advanceUntil(t *testing.T, clock *FakeClock, step time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met after advancing the clock to %s", clock.Now().Format(time.TimeOnly))
+		}
+		clock.Advance(step)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestFakeClockWaiters checks that fired and stopped timers leave the clock, so Waiters only
+// counts somebody still blocked on it
+func TestFakeClockWaiters(t *testing.T) {
+	tests := []struct {
+		name    string
+		arm     func(c *FakeClock) // Creates timers and tickers, and stops some of them
+		advance time.Duration
+		want    int
+	}{
+		{"pending timer", func(c *FakeClock) { c.NewTimer(time.Minute) }, 0, 1},
+		{"stopped timer", func(c *FakeClock) { c.NewTimer(time.Minute).Stop() }, 0, 0},
+		{"fired timer", func(c *FakeClock) { c.NewTimer(time.Minute) }, time.Minute, 0},
+		{"timer stopped after it fired", func(c *FakeClock) { c.NewTimer(time.Second).Stop() }, time.Minute, 0},
+		{"pending After", func(c *FakeClock) { c.After(time.Minute) }, 0, 1},
+		{"fired After", func(c *FakeClock) { c.After(time.Minute) }, time.Minute, 0},
+		{"ticker", func(c *FakeClock) { c.NewTicker(time.Second) }, time.Minute, 1},
+		{"stopped ticker", func(c *FakeClock) { c.NewTicker(time.Second).Stop() }, time.Minute, 0},
+		{"one of two timers stopped", func(c *FakeClock) { c.NewTimer(time.Hour); c.NewTimer(time.Hour).Stop() }, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewFakeClock(time.Unix(0, 0))
+			tt.arm(clock)
+			clock.Advance(tt.advance)
+			if got := clock.Waiters(); got != tt.want {
+				t.Errorf("Waiters() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPoolReleasesAbandonedTimers runs tasks through pool features that race a timeout against
+// the outcome and checks that the timeouts that lost the race do not stay on the FakeClock
+func TestPoolReleasesAbandonedTimers(t *testing.T) {
+	const tasks = 20
+	work := func(done <-chan struct{}) (any, error) { return nil, nil }
+	tests := []struct {
+		name      string
+		configure func(wp *WorkerPool)
+		submit    func(wp *WorkerPool, task Task) error
+	}{
+		{"hedge won by the primary", func(wp *WorkerPool) { wp.HedgeAfter = time.Hour }, (*WorkerPool).Submit},
+		{"result delivered before ResultTimeout", func(wp *WorkerPool) { wp.ResultTimeout = time.Hour }, (*WorkerPool).Submit},
+		{"TrySubmit queued in time", func(wp *WorkerPool) {}, func(wp *WorkerPool, task Task) error { return wp.TrySubmit(task, time.Hour) }},
+		{"Deadline not reached", func(wp *WorkerPool) { wp.Deadline = time.Unix(0, 0).Add(time.Hour) }, (*WorkerPool).Submit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			clock := NewFakeClock(time.Unix(0, 0))
+			wp := &WorkerPool{Concurrency: 2, Clock: clock}
+			tt.configure(wp)
+			if err := wp.Start(); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			results := wp.ResultsCtx(ctx)
+			go func() {
+				for range results {
+				}
+			}()
+
+			for i := range tasks {
+				if err := tt.submit(wp, Task{Id: i + 1, Work: work}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := wp.WaitIdle(ctx); err != nil {
+				t.Fatal(err)
+			}
+			// timers are stopped right after the race they lost, give the workers a moment
+			settled := func(want int) int {
+				for wait := time.Now().Add(time.Second); clock.Waiters() != want && time.Now().Before(wait); {
+					time.Sleep(time.Millisecond)
+				}
+				return clock.Waiters()
+			}
+			want := 0
+			if !wp.Deadline.IsZero() {
+				want = 1 // the deadline itself is pending until the pool shuts down
+			}
+			if got := settled(want); got != want {
+				t.Errorf("Waiters() = %d after %d tasks, want %d", got, tasks, want)
+			}
+			wp.Close()
+			if got := settled(0); got != 0 {
+				t.Errorf("Waiters() = %d after Close, want 0", got)
+			}
+		})
+	}
+}
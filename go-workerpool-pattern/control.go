@@ -0,0 +1,109 @@
+package main
+
+import "sync"
+
+/*
+Control signals for the WorkerPool workers.
+Besides the task channels every worker selects on a control channel, so it reacts to a
+signal even while it is idle waiting for work. The channel is a broadcast: it is closed and
+replaced whenever the control state changes, which wakes all workers at once without the
+sender having to reach each of them, and the workers then read the new state.
+Pause, Resume and Restart are built on it; further signals only need a new state field.
+*/
+
+// workerControl is the control state shared by all workers. The zero value is running.
+type workerControl struct {
+	mu      sync.Mutex
+	paused  bool
+	changed chan struct{} // Closed and replaced when the state changes
+
+	generation int // Incremented by Restart, workers of an older generation are replaced
+}
+
+// state returns whether the workers are paused and a channel closed on the next change
+func (c *workerControl) state() (paused bool, changed <-chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.changed == nil {
+		c.changed = make(chan struct{})
+	}
+	return c.paused, c.changed
+}
+
+// setPaused updates the paused state and signals the workers if it changed
+func (c *workerControl) setPaused(paused bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused == paused {
+		return
+	}
+	c.paused = paused
+	c.signal()
+}
+
+// restart starts a new worker generation and signals the workers
+func (c *workerControl) restart() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.signal()
+}
+
+// current returns the current worker generation
+func (c *workerControl) current() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// signal wakes everyone waiting on the changed channel. Caller must hold mu.
+func (c *workerControl) signal() {
+	if c.changed != nil {
+		close(c.changed)
+	}
+	c.changed = make(chan struct{})
+}
+
+// awaitResume blocks while the workers are paused, or until done is closed
+func (c *workerControl) awaitResume(done <-chan struct{}) {
+	for {
+		paused, changed := c.state()
+		if !paused {
+			return
+		}
+		select {
+		case <-changed:
+		case <-done:
+			return
+		}
+	}
+}
+
+// Pause stops the workers from taking new tasks. Tasks being processed finish normally and
+// queued tasks wait, so Run and Close do not return until Resume is called. In Stack, Prioritize
+// and Queue mode a worker that was already waiting for a task may take one more and holds it
+// until Resume. Cancelling the pool overrides the pause, so queued tasks are skipped as usual.
+func (wp *WorkerPool) Pause() {
+	wp.control.setPaused(true)
+}
+
+// Resume lets paused workers take tasks again
+func (wp *WorkerPool) Resume() {
+	wp.control.setPaused(false)
+}
+
+// Restart replaces every worker with a fresh one, e.g. after a config change that needs clean
+// per-worker state. Each worker finishes the task it is processing and then exits, and a new
+// worker takes over its slot and affinity channel; idle workers are replaced right away. Queued
+// tasks stay queued and are processed by the new workers, so nothing is lost. Restart does not
+// wait for the replacement. In Stack, Prioritize and Queue mode a worker waiting for a task is
+// replaced after the next task it takes. It has no effect on custom Workers (NewWorker).
+func (wp *WorkerPool) Restart() {
+	wp.control.restart()
+}
+
+// Paused reports whether the pool is paused
+func (wp *WorkerPool) Paused() bool {
+	paused, _ := wp.control.state()
+	return paused
+}
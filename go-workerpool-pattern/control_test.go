@@ -0,0 +1,316 @@
+package main
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestWorkerControlSignals applies control operations and checks that the broadcast channel a
+// worker selects on is closed exactly when the state changes
+func TestWorkerControlSignals(t *testing.T) {
+	tests := []struct {
+		name           string
+		op             func(c *workerControl)
+		wantSignal     bool
+		wantPaused     bool
+		wantGeneration int
+	}{
+		{"pause", func(c *workerControl) { c.setPaused(true) }, true, true, 0},
+		{"resume while running", func(c *workerControl) { c.setPaused(false) }, false, false, 0},
+		{"pause twice", func(c *workerControl) { c.setPaused(true); c.setPaused(true) }, true, true, 0},
+		{"pause and resume", func(c *workerControl) { c.setPaused(true); c.setPaused(false) }, true, false, 0},
+		{"restart", func(c *workerControl) { c.restart() }, true, false, 1},
+		{"restart while paused", func(c *workerControl) { c.setPaused(true); c.restart() }, true, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c workerControl
+			_, changed := c.state()
+			tt.op(&c)
+			select {
+			case <-changed:
+				if !tt.wantSignal {
+					t.Error("workers signalled without a state change")
+				}
+			default:
+				if tt.wantSignal {
+					t.Error("workers not signalled")
+				}
+			}
+			if paused, _ := c.state(); paused != tt.wantPaused || c.current() != tt.wantGeneration {
+				t.Errorf("paused %v in generation %d, want %v in %d", paused, c.current(), tt.wantPaused, tt.wantGeneration)
+			}
+		})
+	}
+}
+
+// TestPauseResume pauses a pool with tasks in flight and checks that they finish while the
+// queued ones do not start until Resume, in every dispatch mode
+func TestPauseResume(t *testing.T) {
+	tests := []struct {
+		name     string
+		pool     func() *WorkerPool
+		inFlight int
+	}{
+		{"shared channel, idle", func() *WorkerPool { return &WorkerPool{Concurrency: 2} }, 0},
+		{"shared channel, busy", func() *WorkerPool { return &WorkerPool{Concurrency: 2} }, 2},
+		{"stack", func() *WorkerPool { return &WorkerPool{Concurrency: 2, Stack: true} }, 1},
+		{"prioritize", func() *WorkerPool { return &WorkerPool{Concurrency: 2, Prioritize: true} }, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			wp := tt.pool()
+			if err := wp.Start(); err != nil {
+				t.Fatal(err)
+			}
+			var started, finished atomic.Int32
+			release := make(chan struct{})
+			for i := range tt.inFlight {
+				if err := wp.Submit(Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+					started.Add(1)
+					<-release
+					finished.Add(1)
+					return nil, nil
+				}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for wait := time.Now().Add(5 * time.Second); started.Load() < int32(tt.inFlight); time.Sleep(time.Millisecond) {
+				if time.Now().After(wait) {
+					t.Fatalf("%d tasks in flight, want %d", started.Load(), tt.inFlight)
+				}
+			}
+
+			// the queue holds one task per worker, Submit blocks beyond that while paused
+			wp.Pause()
+			var queuedStarted atomic.Int32
+			for i := range 2 {
+				if err := wp.Submit(Task{Id: 100 + i, Work: func(done <-chan struct{}) (any, error) {
+					queuedStarted.Add(1)
+					return nil, nil
+				}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			close(release)
+			time.Sleep(20 * time.Millisecond) // in-flight tasks finish, queued ones must not start
+			if !wp.Paused() || finished.Load() != int32(tt.inFlight) || queuedStarted.Load() != 0 {
+				t.Fatalf("paused %v: %d of %d in-flight tasks finished and %d queued tasks started, want all finished and none started",
+					wp.Paused(), finished.Load(), tt.inFlight, queuedStarted.Load())
+			}
+
+			wp.Resume()
+			wp.Close()
+			if wp.Paused() || queuedStarted.Load() != 2 {
+				t.Errorf("paused %v with %d queued tasks run after Resume, want 2", wp.Paused(), queuedStarted.Load())
+			}
+		})
+	}
+}
+
+// TestRestartReplacesWorkers restarts a pool with some workers busy and checks from the records
+// that idle workers are replaced right away, busy ones once their task finished, and that tasks
+// queued across the restart are all processed
+func TestRestartReplacesWorkers(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		busy        int
+	}{
+		{"idle workers", 3, 0},
+		{"busy workers", 2, 2},
+		{"some busy", 4, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			var logMu sync.Mutex
+			var records []record
+			previous := Log
+			Log = slog.New(recordingHandler{level: slog.LevelInfo, mu: &logMu, records: &records})
+			defer func() { Log = previous }()
+			restarted := func() []int {
+				logMu.Lock()
+				defer logMu.Unlock()
+				var workers []int
+				for _, r := range records {
+					if r.msg == "worker restarted" {
+						workers = append(workers, int(r.attrs["worker_id"].Int64()))
+					}
+				}
+				return workers
+			}
+			waitRestarted := func(n int) {
+				for wait := time.Now().Add(5 * time.Second); len(restarted()) < n; time.Sleep(time.Millisecond) {
+					if time.Now().After(wait) {
+						t.Fatalf("%d workers restarted, want %d", len(restarted()), n)
+					}
+				}
+			}
+
+			wp := &WorkerPool{Concurrency: tt.concurrency}
+			if err := wp.Start(); err != nil {
+				t.Fatal(err)
+			}
+			var started, processed atomic.Int32
+			release := make(chan struct{})
+			for i := range tt.busy {
+				if err := wp.Submit(Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+					started.Add(1)
+					<-release
+					processed.Add(1)
+					return nil, nil
+				}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for wait := time.Now().Add(5 * time.Second); started.Load() < int32(tt.busy); time.Sleep(time.Millisecond) {
+				if time.Now().After(wait) {
+					t.Fatalf("%d workers busy, want %d", started.Load(), tt.busy)
+				}
+			}
+
+			time.Sleep(10 * time.Millisecond) // idle workers reach their select, a worker not started yet needs no restart
+			wp.Restart()
+			waitRestarted(tt.concurrency - tt.busy)
+			time.Sleep(20 * time.Millisecond) // busy workers must not be replaced mid-task
+			if n := len(restarted()); n != tt.concurrency-tt.busy {
+				t.Fatalf("%d workers restarted with %d busy, want %d", n, tt.busy, tt.concurrency-tt.busy)
+			}
+			close(release)
+			for i := range 6 {
+				if err := wp.Submit(Task{Id: 100 + i, Work: func(done <-chan struct{}) (any, error) {
+					processed.Add(1)
+					return nil, nil
+				}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			waitRestarted(tt.concurrency)
+			wp.Close()
+
+			workers := restarted()
+			slices.Sort(workers)
+			if want := seq(tt.concurrency); !slices.Equal(workers, want) {
+				t.Errorf("workers %v restarted, want each of %v once", workers, want)
+			}
+			if got := processed.Load(); got != int32(tt.busy+6) {
+				t.Errorf("%d tasks processed across the restart, want %d", got, tt.busy+6)
+			}
+		})
+	}
+}
+
+// TestRestartKeepsQueuedTasks restarts a pool whose workers are all busy with tasks queued
+// behind them, and checks from the records that every queued task is processed afterwards by
+// the fresh worker of its slot
+func TestRestartKeepsQueuedTasks(t *testing.T) {
+	tests := []struct {
+		name     string
+		stack    bool
+		affinity bool // Queue the tasks on the workers' affinity channels instead of the shared one
+		queued   int
+	}{
+		{"shared channel", false, false, 2},
+		{"affinity channels", false, true, 4},
+		{"stack", true, false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			var logMu sync.Mutex
+			var records []record
+			previous := Log
+			Log = slog.New(recordingHandler{level: slog.LevelInfo, mu: &logMu, records: &records})
+			defer func() { Log = previous }()
+
+			const concurrency = 2
+			wp := &WorkerPool{Concurrency: concurrency, Stack: tt.stack}
+			if err := wp.Start(); err != nil {
+				t.Fatal(err)
+			}
+			started, release := make(chan struct{}, concurrency), make(chan struct{})
+			for i := range concurrency {
+				if err := wp.Submit(Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+					started <- struct{}{}
+					<-release
+					return nil, nil
+				}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for range concurrency {
+				<-started
+			}
+			queued := map[int]bool{}
+			for i := range tt.queued {
+				task := Task{Id: 100 + i, Work: func(done <-chan struct{}) (any, error) { return nil, nil }}
+				if tt.affinity {
+					task.Affinity = i + 1
+				}
+				if err := wp.Submit(task); err != nil {
+					t.Fatal(err)
+				}
+				queued[task.Id] = true
+			}
+
+			wp.Restart()
+			close(release)
+			// each busy worker hands over once its task finished, before the pool's Log is restored
+			for wait := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+				logMu.Lock()
+				n := 0
+				for _, r := range records {
+					if r.msg == "worker restarted" {
+						n++
+					}
+				}
+				logMu.Unlock()
+				if n == concurrency {
+					break
+				}
+				if time.Now().After(wait) {
+					t.Fatalf("%d workers restarted, want %d", n, concurrency)
+				}
+			}
+			wp.Close()
+
+			// a queued task finishes after its worker slot was handed to a fresh worker
+			logMu.Lock()
+			defer logMu.Unlock()
+			restarted := map[int]bool{}
+			for _, r := range records {
+				worker := int(r.attrs["worker_id"].Int64())
+				switch {
+				case r.msg == "worker restarted":
+					restarted[worker] = true
+				case r.msg == "task finished" && queued[int(r.attrs["task_id"].Int64())]:
+					if !restarted[worker] {
+						t.Errorf("queued task %d processed by worker %d before its restart", r.attrs["task_id"].Int64(), worker)
+					}
+					delete(queued, int(r.attrs["task_id"].Int64()))
+				}
+			}
+			if len(queued) > 0 {
+				t.Errorf("queued tasks %v lost across the restart", slices.Sorted(maps.Keys(queued)))
+			}
+		})
+	}
+}
+
+// seq returns the integers 0 to n-1
+func seq(n int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = i
+	}
+	return s
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+Dependency support for the multi-type worker pool.
+Tasks that expose an ID can be depended on, tasks that expose DependsOn are only dispatched
+once every task they depend on has finished. The dependency graph is validated before
+anything runs: unknown IDs, duplicate IDs and cycles are reported as errors.
+If a dependency fails, every task depending on it (directly or transitively) is skipped.
+*/
+
+// ErrDependencyCycle is returned when the task dependencies form a cycle
+var ErrDependencyCycle = errors.New("task dependencies contain a cycle")
+
+// ErrDependencyFailed is reported for tasks skipped because one of their dependencies failed
+var ErrDependencyFailed = errors.New("dependency failed")
+
+// MultiTaskID identifies a task so other tasks can depend on it
+type MultiTaskID string
+
+// IdentifiedTask is implemented by tasks that other tasks can depend on
+type IdentifiedTask interface {
+	ID() MultiTaskID
+}
+
+// DependentTask is implemented by tasks that must wait for other tasks to finish first
+type DependentTask interface {
+	DependsOn() []MultiTaskID
+}
+
+// dependentTask wraps a MultiTask with an ID and its dependencies
+type dependentTask struct {
+	MultiTask
+	id   MultiTaskID
+	deps []MultiTaskID
+}
+
+// ID returns the ID of the wrapped task
+func (d *dependentTask) ID() MultiTaskID {
+	return d.id
+}
+
+// DependsOn returns the IDs of the tasks that must finish before this one
+func (d *dependentTask) DependsOn() []MultiTaskID {
+	return d.deps
+}
+
+// Unwrap returns the wrapped task, so the pool still finds the interfaces it implements
+// (ResultTask, ResourceTask, CompensableTask) behind the wrapper
+func (d *dependentTask) Unwrap() MultiTask {
+	return d.MultiTask
+}
+
+// taskAs finds the first task in the chain of wrapped tasks (see Unwrap) implementing T
+func taskAs[T any](task MultiTask) (T, bool) {
+	for task != nil {
+		if t, ok := task.(T); ok {
+			return t, true
+		}
+		w, ok := task.(interface{ Unwrap() MultiTask })
+		if !ok {
+			break
+		}
+		task = w.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// WithDependencies gives a task an ID and the IDs of the tasks it depends on,
+// so existing task types can take part in a dependency graph without changes
+func WithDependencies(id MultiTaskID, task MultiTask, deps ...MultiTaskID) MultiTask {
+	return &dependentTask{MultiTask: task, id: id, deps: deps}
+}
+
+// taskGraph tracks which tasks are ready to run as their dependencies complete
+type taskGraph struct {
+	tasks      []MultiTask
+	byID       map[MultiTaskID]int // Index of each identified task
+	dependents [][]int             // Tasks waiting on each task
+	pending    []int               // Number of unfinished dependencies per task
+	blocked    []bool              // Whether a dependency of the task failed
+}
+
+// hasDependencies reports whether any task declares dependencies
+func hasDependencies(tasks []MultiTask) bool {
+	for _, task := range tasks {
+		if dt, ok := task.(DependentTask); ok && len(dt.DependsOn()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// newTaskGraph builds and validates the dependency graph of the tasks
+func newTaskGraph(tasks []MultiTask) (*taskGraph, error) {
+	g := &taskGraph{
+		tasks:      tasks,
+		byID:       make(map[MultiTaskID]int),
+		dependents: make([][]int, len(tasks)),
+		pending:    make([]int, len(tasks)),
+		blocked:    make([]bool, len(tasks)),
+	}
+	for i, task := range tasks {
+		it, ok := task.(IdentifiedTask)
+		if !ok {
+			continue
+		}
+		if _, dup := g.byID[it.ID()]; dup {
+			return nil, fmt.Errorf("duplicate task ID %q", it.ID())
+		}
+		g.byID[it.ID()] = i
+	}
+	for i, task := range tasks {
+		dt, ok := task.(DependentTask)
+		if !ok {
+			continue
+		}
+		for _, dep := range dt.DependsOn() {
+			j, ok := g.byID[dep]
+			if !ok {
+				return nil, fmt.Errorf("task depends on unknown task ID %q", dep)
+			}
+			g.dependents[j] = append(g.dependents[j], i)
+			g.pending[i]++
+		}
+	}
+
+	// Kahn's algorithm: if not every task can be reached from the roots there is a cycle
+	pending := append([]int(nil), g.pending...)
+	queue := g.roots()
+	visited := 0
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, d := range g.dependents[i] {
+			pending[d]--
+			if pending[d] == 0 {
+				queue = append(queue, d)
+			}
+		}
+	}
+	if visited != len(tasks) {
+		return nil, ErrDependencyCycle
+	}
+	return g, nil
+}
+
+// roots returns the tasks without dependencies, in their original order
+func (g *taskGraph) roots() []int {
+	var roots []int
+	for i, n := range g.pending {
+		if n == 0 {
+			roots = append(roots, i)
+		}
+	}
+	return roots
+}
+
+// complete marks a task as finished and returns the tasks that became ready to run and
+// the tasks that must be skipped because the finished task (or a skipped one) failed
+func (g *taskGraph) complete(task MultiTask, err error) (ready, skipped []int) {
+	it, ok := task.(IdentifiedTask)
+	if !ok {
+		return nil, nil
+	}
+
+	type finished struct {
+		index  int
+		failed bool
+	}
+	queue := []finished{{g.byID[it.ID()], err != nil}}
+	for len(queue) > 0 {
+		f := queue[0]
+		queue = queue[1:]
+		for _, d := range g.dependents[f.index] {
+			if f.failed {
+				g.blocked[d] = true
+			}
+			g.pending[d]--
+			if g.pending[d] > 0 {
+				continue
+			}
+			if g.blocked[d] {
+				skipped = append(skipped, d)
+				queue = append(queue, finished{d, true})
+			} else {
+				ready = append(ready, d)
+			}
+		}
+	}
+	return ready, skipped
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestDeadline runs ten tasks of 100ms of fake time on two workers against a Deadline and checks
+// which tasks completed, which were left unfinished and the error the batch ended with
+func TestDeadline(t *testing.T) {
+	const taskTime = 100 * time.Millisecond
+	start := time.Unix(0, 0)
+	tests := []struct {
+		name           string
+		deadline       time.Time
+		advances       []time.Duration // Fake time passed once both workers wait on the clock
+		wantErr        error
+		wantCompleted  []int
+		wantUnfinished []int
+	}{
+		{
+			name:           "deadline mid-batch",
+			deadline:       start.Add(250 * time.Millisecond),
+			advances:       []time.Duration{taskTime, taskTime, 50 * time.Millisecond},
+			wantErr:        context.DeadlineExceeded,
+			wantCompleted:  []int{1, 2, 3, 4},
+			wantUnfinished: []int{5, 6, 7, 8, 9, 10},
+		},
+		{
+			name:           "deadline after the batch",
+			deadline:       start.Add(time.Second),
+			advances:       []time.Duration{taskTime, taskTime, taskTime, taskTime, taskTime},
+			wantCompleted:  []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			wantUnfinished: []int{},
+		},
+		{
+			name:           "deadline already passed",
+			deadline:       start.Add(-time.Second),
+			wantErr:        context.DeadlineExceeded,
+			wantCompleted:  []int{},
+			wantUnfinished: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			clock := NewFakeClock(start)
+			tasks := make([]Task, 10)
+			for i := range tasks {
+				tasks[i] = Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+					timer := clock.NewTimer(taskTime)
+					defer timer.Stop()
+					select {
+					case <-timer.C():
+						return nil, nil
+					case <-done:
+						return nil, ErrTaskCancelled
+					}
+				}}
+			}
+			wp := &WorkerPool{Tasks: tasks, Concurrency: 2, Clock: clock, Deadline: tt.deadline}
+			errc := make(chan error, 1)
+			go func() {
+				_, err := wp.RunWithContext(context.Background())
+				errc <- err
+			}()
+
+			for _, d := range tt.advances {
+				// the deadline and both running tasks are on the clock
+				for wait := time.Now().Add(5 * time.Second); clock.Waiters() < 3; {
+					if time.Now().After(wait) {
+						t.Fatalf("%d timers on the clock at %v, want 3", clock.Waiters(), clock.Now().Sub(start))
+					}
+					time.Sleep(time.Millisecond)
+				}
+				clock.Advance(d)
+			}
+			var err error
+			select {
+			case err = <-errc:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("batch still running at %v of fake time", clock.Now().Sub(start))
+			}
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("batch ended with %v, want %v", err, tt.wantErr)
+			}
+			completed, unfinished := wp.Completed(), wp.Unfinished()
+			if !slices.Equal(completed, tt.wantCompleted) || !slices.Equal(unfinished, tt.wantUnfinished) {
+				t.Errorf("completed %v, unfinished %v; want %v, %v", completed, unfinished, tt.wantCompleted, tt.wantUnfinished)
+			}
+		})
+	}
+}
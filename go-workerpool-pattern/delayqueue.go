@@ -0,0 +1,129 @@
+package main
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+/*
+Timer-backed delay queue used by WorkerPool.SubmitAfter.
+Delayed tasks are kept in a min-heap ordered by due time and a single
+goroutine sleeps until the earliest one is due, then releases it to the workers.
+Once the pool is cancelled every waiting task is released immediately, so the workers
+can skip it instead of the pool waiting for its due time.
+*/
+
+// delayedTask is a task waiting in the delay queue until its due time
+type delayedTask struct {
+	task Task      // Task to release when due
+	due  time.Time // Time at which the task becomes runnable
+	seq  int       // Submission order, used to break ties between equal due times
+}
+
+// delayHeap orders delayed tasks by due time (earliest first)
+type delayHeap []*delayedTask
+
+func (h delayHeap) Len() int { return len(h) }
+func (h delayHeap) Less(i, j int) bool {
+	if h[i].due.Equal(h[j].due) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].due.Before(h[j].due)
+}
+func (h delayHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *delayHeap) Push(x any)   { *h = append(*h, x.(*delayedTask)) }
+func (h *delayHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// delayQueue holds delayed tasks and releases them in due-time order
+type delayQueue struct {
+	mu      sync.Mutex
+	items   delayHeap
+	seq     int
+	wake    chan struct{}   // Signals the scheduler that a new task was pushed
+	stop    chan struct{}   // Closed to stop the scheduler goroutine
+	done    <-chan struct{} // Closed when the pool is cancelled, making every task due
+	clock   Clock           // Source of time deciding when tasks are due
+	release func(Task)      // Called with each task once it is due
+}
+
+// newDelayQueue creates a delay queue and starts its scheduler goroutine
+func newDelayQueue(done <-chan struct{}, clock Clock, release func(Task)) *delayQueue {
+	dq := &delayQueue{
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    done,
+		clock:   clock,
+		release: release,
+	}
+	go dq.run()
+	return dq
+}
+
+// push adds a task that becomes runnable at the given time
+func (dq *delayQueue) push(task Task, due time.Time) {
+	dq.mu.Lock()
+	heap.Push(&dq.items, &delayedTask{task: task, due: due, seq: dq.seq})
+	dq.seq++
+	dq.mu.Unlock()
+
+	// wake the scheduler so it can re-arm its timer for the new earliest task
+	select {
+	case dq.wake <- struct{}{}:
+	default:
+	}
+}
+
+// close stops the scheduler goroutine; tasks still waiting are discarded
+func (dq *delayQueue) close() {
+	close(dq.stop)
+}
+
+// run releases due tasks and sleeps until the next one is due
+func (dq *delayQueue) run() {
+	done, cancelled := dq.done, false
+	for {
+		dq.mu.Lock()
+		now := dq.clock.Now()
+		var due []Task
+		for dq.items.Len() > 0 && (cancelled || !dq.items[0].due.After(now)) {
+			due = append(due, heap.Pop(&dq.items).(*delayedTask).task)
+		}
+		next := time.Duration(-1)
+		if dq.items.Len() > 0 {
+			next = dq.items[0].due.Sub(now)
+		}
+		dq.mu.Unlock()
+
+		// release outside the lock, in due-time order
+		for _, task := range due {
+			dq.release(task)
+		}
+
+		// a nil channel never fires, so without waiting tasks only a push or stop wakes us
+		var fired <-chan time.Time
+		stopTimer := func() {}
+		if next >= 0 {
+			timer := dq.clock.NewTimer(next)
+			fired, stopTimer = timer.C(), timer.Stop
+		}
+
+		// a push, the cancellation or stop may wake us before the timer fired, release it
+		select {
+		case <-dq.wake:
+			stopTimer()
+		case <-fired:
+		case <-done:
+			stopTimer()
+			done, cancelled = nil, true
+		case <-dq.stop:
+			stopTimer()
+			return
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+/*
+Follow-up tasks for recursive workflows.
+A FollowUp callback turns the result of a task into new tasks for the same pool, e.g. crawling
+a page enqueues the links found on it. Follow-ups are counted in the WaitGroup before the task
+that produced them is marked done, so Run and Close wait for the whole expansion even though
+the batch grows while it runs. MaxFollowUpDepth and MaxFollowUps keep the expansion finite.
+*/
+
+// enqueueFollowUps passes a processed task and its result to FollowUp and enqueues the returned
+// tasks. Must be called before the task is marked done in the WaitGroup.
+func (wp *WorkerPool) enqueueFollowUps(parent Task, result Result) {
+	if wp.FollowUp == nil {
+		return
+	}
+	for _, task := range wp.FollowUp(parent, result) {
+		task.depth = parent.depth + 1
+		if !wp.admitFollowUp(task) {
+			wp.counters.pruned.Add(1)
+			continue
+		}
+		// dispatch from another goroutine, the worker must not block on a queue it consumes
+		go wp.dispatch(task)
+	}
+}
+
+// admitFollowUp checks a follow-up against the limits and counts it as part of the batch.
+// Unlike Submit it also accepts tasks while Run or Close wait for the batch to drain.
+func (wp *WorkerPool) admitFollowUp(task Task) bool {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	if wp.MaxFollowUpDepth > 0 && task.depth > wp.MaxFollowUpDepth {
+		return false
+	}
+	if wp.MaxFollowUps > 0 && wp.followUps >= wp.MaxFollowUps {
+		return false
+	}
+	wp.followUps++
+	wp.addTask()
+	wp.batch.submit(task)
+	wp.progress.grow()
+	return true
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestFollowUpBoundedExpansion expands a binary tree without end, every task n enqueueing 2n and
+// 2n+1 as follow-ups, and checks that MaxFollowUpDepth and MaxFollowUps bound it: which tasks
+// were processed, how many follow-ups were pruned, and that Run or Close return only once the
+// whole expansion finished
+func TestFollowUpBoundedExpansion(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		maxDepth    int
+		maxTotal    int
+		streaming   bool // Submit the root to a started pool and Close it instead of Run
+		wantCount   int
+		wantIds     []int // Processed tasks, nil where follow-ups race for the total limit
+		wantPruned  int64
+	}{
+		{"depth limit", 4, 2, 0, false, 7, []int{1, 2, 3, 4, 5, 6, 7}, 8},
+		{"depth limit on one worker", 1, 2, 0, false, 7, []int{1, 2, 3, 4, 5, 6, 7}, 8},
+		{"depth limit streaming", 4, 2, 0, true, 7, []int{1, 2, 3, 4, 5, 6, 7}, 8},
+		{"total limit", 4, 0, 6, false, 7, nil, 8},
+		{"total limit on one worker", 1, 0, 4, false, 5, nil, 6},
+		{"depth limit below the total limit", 4, 1, 10, false, 3, []int{1, 2, 3}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			var mu sync.Mutex
+			var processed []int
+			node := func(id int) Task {
+				return Task{Id: id, Work: func(done <-chan struct{}) (any, error) {
+					mu.Lock()
+					processed = append(processed, id)
+					mu.Unlock()
+					return nil, nil
+				}}
+			}
+			wp := &WorkerPool{Concurrency: tt.concurrency, MaxFollowUpDepth: tt.maxDepth, MaxFollowUps: tt.maxTotal,
+				FollowUp: func(task Task, result Result) []Task {
+					return []Task{node(2 * task.Id), node(2*task.Id + 1)}
+				}}
+			if tt.streaming {
+				if err := wp.Start(); err != nil {
+					t.Fatal(err)
+				}
+				if err := wp.Submit(node(1)); err != nil {
+					t.Fatal(err)
+				}
+				wp.Close()
+			} else {
+				wp.Tasks = []Task{node(1)}
+				if err := wp.Run(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// the expansion is complete once Run or Close returned
+			mu.Lock()
+			got := slices.Sorted(slices.Values(processed))
+			mu.Unlock()
+			if len(got) != tt.wantCount || (tt.wantIds != nil && !slices.Equal(got, tt.wantIds)) {
+				t.Errorf("processed %v, want %d tasks %v", got, tt.wantCount, tt.wantIds)
+			}
+			for i, id := range got {
+				if (i > 0 && id == got[i-1]) || (id > 1 && !slices.Contains(got, id/2)) {
+					t.Errorf("processed %v: task %d twice or without its parent", got, id)
+				}
+			}
+			if stats := wp.Stats(); stats.Pruned != tt.wantPruned || stats.Processed != int64(tt.wantCount) {
+				t.Errorf("Stats() processed %d and pruned %d, want %d and %d", stats.Processed, stats.Pruned, tt.wantCount, tt.wantPruned)
+			}
+		})
+	}
+}
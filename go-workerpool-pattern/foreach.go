@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+Single-call API on top of the WorkerPool.
+ForEach covers the common case of running one function over a slice with bounded concurrency:
+it builds the pool, runs the batch and returns the first error, cancelling the remaining work.
+*/
+
+// ForEach calls fn for every item on at most workers goroutines and returns the first error.
+// The first error cancels the context passed to fn, and items not started yet are skipped.
+// It returns only after every call of fn has returned.
+func ForEach[T any](items []T, workers int, fn func(context.Context, T) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var once sync.Once
+	var first error
+	tasks := make([]Task, len(items))
+	for i, item := range items {
+		tasks[i] = Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+			err := fn(ctx, item)
+			if err != nil {
+				once.Do(func() {
+					first = err
+					cancel()
+				})
+			}
+			return nil, err
+		}}
+	}
+
+	wp := WorkerPool{Tasks: tasks, Concurrency: max(workers, 1)}
+	_ = wp.runBatch(ctx)
+	return first
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+Futures for tasks submitted to a running WorkerPool.
+SubmitFuture returns at once with a Future that resolves when that specific task completes, so
+a caller can fire off work and await individual results selectively instead of the whole batch.
+A Future resolves exactly once; every Get returns the same cached value.
+*/
+
+// Future is a value that becomes available later, once it is resolved
+type Future[T any] struct {
+	done  chan struct{} // Closed once the value is set
+	once  sync.Once
+	value T
+}
+
+// newFuture creates an unresolved future
+func newFuture[T any]() *Future[T] {
+	return &Future[T]{done: make(chan struct{})}
+}
+
+// resolve sets the value and wakes every waiter, only the first call has an effect
+func (f *Future[T]) resolve(value T) {
+	f.once.Do(func() {
+		f.value = value
+		close(f.done)
+	})
+}
+
+// Get waits until the future is resolved and returns its value, or returns ctx.Err() if ctx
+// is done first. It may be called any number of times, from any goroutine.
+func (f *Future[T]) Get(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, nil
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Done returns a channel that is closed once the future is resolved, for use in a select
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// SubmitFuture submits a task like Submit and returns a Future resolving to its Result once the
+// task completed, failed or was skipped because of cancellation. If the task cannot be
+// submitted the future is already resolved, with ErrPoolClosed in Result.Err.
+func (wp *WorkerPool) SubmitFuture(task Task) *Future[Result] {
+	f := newFuture[Result]()
+	task.future = f
+	if err := wp.Submit(task); err != nil {
+		f.resolve(Result{TaskId: task.Id, Err: err})
+	}
+	return f
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestFutureConcurrentGet has many goroutines Get the same future while its task is still
+// running and checks that they all receive the same result, that a later Get returns it again
+// from the cache, and that an impatient Get gives up without spoiling the future
+func TestFutureConcurrentGet(t *testing.T) {
+	failure := errors.New("report unavailable")
+	tests := []struct {
+		name      string
+		err       error // Error returned by the task
+		closed    bool  // Submit after the pool was closed
+		wantRuns  int32
+		wantValue any
+		wantErr   error
+	}{
+		{"successful task", nil, false, 1, "quarterly report", nil},
+		{"failing task", failure, false, 1, "quarterly report", failure},
+		{"closed pool", nil, true, 0, nil, ErrPoolClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			wp := &WorkerPool{Concurrency: 2}
+			if err := wp.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer wp.Close()
+			if tt.closed {
+				wp.Close()
+			}
+			var runs atomic.Int32
+			release := make(chan struct{})
+			future := wp.SubmitFuture(Task{Id: 1, Work: func(done <-chan struct{}) (any, error) {
+				<-release
+				runs.Add(1)
+				return "quarterly report", tt.err
+			}})
+
+			// an impatient Get returns ctx.Err() and leaves the future to the others
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if _, err := future.Get(ctx); !tt.closed && !errors.Is(err, context.Canceled) {
+				t.Errorf("Get with a cancelled context returned %v before the task finished, want context.Canceled", err)
+			}
+
+			const getters = 50
+			var wg sync.WaitGroup
+			got := make([]Result, getters)
+			for i := range getters {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					got[i], _ = future.Get(context.Background())
+				}()
+			}
+			close(release)
+			wg.Wait()
+			cached, err := future.Get(context.Background())
+
+			want := got[0]
+			if want.TaskId != 1 || want.Value != tt.wantValue || !errors.Is(want.Err, tt.wantErr) || (tt.wantErr == nil && want.Err != nil) {
+				t.Fatalf("Get returned %+v, want task 1 with %v and %v", want, tt.wantValue, tt.wantErr)
+			}
+			for i, r := range got {
+				if r != want {
+					t.Fatalf("Get %d returned %+v, Get 0 returned %+v", i, r, want)
+				}
+			}
+			if err != nil || cached != want {
+				t.Errorf("Get after resolution returned %+v, %v; want %+v", cached, err, want)
+			}
+			select {
+			case <-future.Done():
+			default:
+				t.Error("Done() still open after the future resolved")
+			}
+			if n := runs.Load(); n != tt.wantRuns {
+				t.Errorf("task ran %d times, want %d", n, tt.wantRuns)
+			}
+		})
+	}
+}
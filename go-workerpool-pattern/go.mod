@@ -1,3 +1,7 @@
 module go_workerpool_pattern
 
 go 1.23
+
+replace go_concurrency_helpers => ../go-concurrency/helpers
+
+require go_concurrency_helpers v0.0.0
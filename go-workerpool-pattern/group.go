@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+Named sub-batches of the WorkerPool.
+Tasks tagged with the same Group share a context derived from the pool's context, so several
+logical batches can share one pool and CancelGroup can abandon one of them: its queued tasks
+are skipped and its in-flight tasks see their done channel closed, while other groups and
+ungrouped tasks keep running.
+*/
+
+// taskGroups holds the context of every group seen so far
+type taskGroups struct {
+	mu        sync.Mutex
+	ctxs      map[string]context.Context
+	cancels   map[string]context.CancelFunc
+	cancelled map[string]bool // Groups cancelled with CancelGroup, also before their first task
+}
+
+// context returns the context of the named group, derived from parent on first use
+func (g *taskGroups) context(parent context.Context, name string) context.Context {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if ctx, ok := g.ctxs[name]; ok {
+		return ctx
+	}
+	if g.ctxs == nil {
+		g.ctxs = make(map[string]context.Context)
+		g.cancels = make(map[string]context.CancelFunc)
+	}
+	ctx, cancel := context.WithCancel(parent)
+	if g.cancelled[name] {
+		cancel()
+	}
+	g.ctxs[name], g.cancels[name] = ctx, cancel
+	return ctx
+}
+
+// cancel cancels the named group, including tasks of it that are submitted later
+func (g *taskGroups) cancel(name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.cancelled == nil {
+		g.cancelled = make(map[string]bool)
+	}
+	g.cancelled[name] = true
+	if cancel, ok := g.cancels[name]; ok {
+		cancel()
+	}
+}
+
+// taskContext returns the context a task runs under: its group's, or the pool's if it has none
+func (wp *WorkerPool) taskContext(task Task) context.Context {
+	if task.Group == "" {
+		return wp.ctx
+	}
+	return wp.groups.context(wp.ctx, task.Group)
+}
+
+// CancelGroup cancels every task whose Group is name without affecting other tasks: queued
+// ones are skipped with ErrTaskCancelled and in-flight ones see their done channel closed
+// (tasks ignoring it run to completion). Tasks of the group submitted afterwards are skipped
+// too. Cancelling the whole pool still cancels every group.
+func (wp *WorkerPool) CancelGroup(name string) {
+	wp.groups.cancel(name)
+}
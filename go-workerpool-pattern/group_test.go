@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestCancelGroup runs two groups and an ungrouped task on a shared pool, cancels one group
+// while a task of each group is in flight and another is queued, and checks that only the
+// tasks of the cancelled group are cancelled, including one submitted after CancelGroup
+func TestCancelGroup(t *testing.T) {
+	const a1, a2, a3, b1, b2, ungrouped = 1, 2, 3, 11, 12, 21
+	tests := []struct {
+		name          string
+		cancel        string
+		wantCancelled map[int]bool
+	}{
+		{"cancel group A", "A", map[int]bool{a1: true, a2: true, a3: true}},
+		{"cancel group B", "B", map[int]bool{b1: true, b2: true}},
+		{"cancel a group without tasks", "C", map[int]bool{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			var mu sync.Mutex
+			errs, ran := map[int]error{}, map[int]bool{}
+			wp := &WorkerPool{Concurrency: 2, OnResult: func(task Task, result Result) {
+				mu.Lock()
+				errs[task.Id] = result.Err
+				mu.Unlock()
+			}}
+			if err := wp.Start(); err != nil {
+				t.Fatal(err)
+			}
+
+			// the in-flight task of a group finishes once its group is released or cancelled
+			started := make(chan struct{}, 2)
+			release := map[string]chan struct{}{"A": make(chan struct{}), "B": make(chan struct{})}
+			blocking := func(id int, group string) func(done <-chan struct{}) (any, error) {
+				return func(done <-chan struct{}) (any, error) {
+					mu.Lock()
+					ran[id] = true
+					mu.Unlock()
+					started <- struct{}{}
+					select {
+					case <-done:
+						return nil, ErrTaskCancelled
+					case <-release[group]:
+						return nil, nil
+					}
+				}
+			}
+			quick := func(id int) func(done <-chan struct{}) (any, error) {
+				return func(done <-chan struct{}) (any, error) {
+					mu.Lock()
+					ran[id] = true
+					mu.Unlock()
+					return nil, nil
+				}
+			}
+			submit := func(tasks ...Task) {
+				for _, task := range tasks {
+					if err := wp.Submit(task); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			// a task of each group holds a worker, a second one of each waits in the queue
+			submit(Task{Id: a1, Group: "A", Work: blocking(a1, "A")}, Task{Id: b1, Group: "B", Work: blocking(b1, "B")})
+			<-started
+			<-started
+			submit(Task{Id: a2, Group: "A", Work: quick(a2)}, Task{Id: b2, Group: "B", Work: quick(b2)})
+			wp.CancelGroup(tt.cancel)
+			for group, ch := range release {
+				if group != tt.cancel {
+					close(ch)
+				}
+			}
+			submit(Task{Id: a3, Group: "A", Work: quick(a3)}, Task{Id: ungrouped, Work: quick(ungrouped)})
+			wp.Close()
+
+			for _, id := range []int{a1, a2, a3, b1, b2, ungrouped} {
+				cancelled := errors.Is(errs[id], ErrTaskCancelled)
+				if cancelled != tt.wantCancelled[id] || (!cancelled && errs[id] != nil) {
+					t.Errorf("task %d finished with %v, want cancelled %v", id, errs[id], tt.wantCancelled[id])
+				}
+				// only the in-flight tasks ran before the cancellation, queued ones are skipped
+				if wantRan := id == a1 || id == b1 || !tt.wantCancelled[id]; ran[id] != wantRan {
+					t.Errorf("task %d ran %v, want %v", id, ran[id], wantRan)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import "time"
+
+/*
+Liveness heartbeat of the WorkerPool.
+Heartbeat ticks every HeartbeatInterval while the workers make progress, so an external
+watchdog can tell a busy pool from a wedged one: no heartbeat for longer than the slowest
+task means every worker is stuck. Ticks stop while the pool is idle and the channel is
+closed once the pool has shut down.
+*/
+
+// defaultHeartbeatInterval is used when HeartbeatInterval is not set
+const defaultHeartbeatInterval = time.Second
+
+// Heartbeat returns a channel receiving the time at most once per HeartbeatInterval, but only
+// for intervals in which at least one task finished processing. A tick that is not received
+// in time is dropped, like with time.Ticker. The channel is closed once the pool has shut down.
+// It may be called before or after the pool starts; every call returns the same channel.
+func (wp *WorkerPool) Heartbeat() <-chan time.Time {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	if wp.heartbeat == nil {
+		wp.heartbeat = make(chan time.Time, 1)
+		if wp.done != nil {
+			go wp.beat(wp.heartbeat)
+		}
+	}
+	return wp.heartbeat
+}
+
+// beat sends the heartbeats on out until the pool has shut down, then closes it
+func (wp *WorkerPool) beat(out chan time.Time) {
+	defer close(out)
+	interval := wp.HeartbeatInterval
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+	}
+	ticker := wp.clock().NewTicker(interval)
+	defer ticker.Stop()
+
+	last := wp.counters.processed.Load()
+	for {
+		select {
+		case now := <-ticker.C():
+			processed := wp.counters.processed.Load()
+			if processed == last {
+				continue // idle or wedged, stay silent
+			}
+			last = processed
+			select {
+			case out <- now:
+			default:
+			}
+		case <-wp.done:
+			return
+		}
+	}
+}
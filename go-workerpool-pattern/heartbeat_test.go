@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestHeartbeat processes a number of tasks in each HeartbeatInterval of a FakeClock and checks
+// that a heartbeat arrives exactly for the intervals with progress, and that the channel is
+// closed on shutdown
+func TestHeartbeat(t *testing.T) {
+	const interval = time.Second
+	tests := []struct {
+		name      string
+		processed []int // Tasks processed in each interval
+	}{
+		{"busy in every interval", []int{2, 1, 3}},
+		{"idle pool", []int{0, 0, 0}},
+		{"busy, then idle", []int{4, 0, 0}},
+		{"idle, then busy", []int{0, 0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			clock := NewFakeClock(time.Unix(0, 0))
+			wp := &WorkerPool{Concurrency: 2, Clock: clock, HeartbeatInterval: interval}
+			if err := wp.Start(); err != nil {
+				t.Fatal(err)
+			}
+			beats := wp.Heartbeat()
+			for clock.Waiters() == 0 {
+				time.Sleep(time.Millisecond) // until the heartbeat ticker is on the clock
+			}
+
+			id := 0
+			for i, n := range tt.processed {
+				for range n {
+					id++
+					if err := wp.Submit(Task{Id: id, Work: func(done <-chan struct{}) (any, error) { return nil, nil }}); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if err := wp.WaitIdle(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+				clock.Advance(interval)
+
+				if n > 0 {
+					select {
+					case at := <-beats:
+						if !at.Equal(clock.Now()) {
+							t.Errorf("interval %d: heartbeat at %v, want %v", i, at, clock.Now())
+						}
+					case <-time.After(time.Second):
+						t.Fatalf("interval %d: no heartbeat after %d tasks were processed", i, n)
+					}
+					continue
+				}
+				select {
+				case at := <-beats:
+					t.Fatalf("interval %d: heartbeat at %v without progress", i, at)
+				case <-time.After(20 * time.Millisecond):
+				}
+			}
+
+			wp.Close()
+			select {
+			case _, ok := <-beats:
+				if ok {
+					t.Error("heartbeat after Close, want the channel closed")
+				}
+			case <-time.After(time.Second):
+				t.Error("heartbeat channel still open a second after Close")
+			}
+		})
+	}
+}
+
+// TestHeartbeatStopsAfterBacklog processes a backlog of tasks taking 600ms of fake time each
+// with one heartbeat per second, and checks that heartbeats arrive while the backlog is worked
+// off and stop once it has drained
+func TestHeartbeatStopsAfterBacklog(t *testing.T) {
+	const interval, taskTime, step = time.Second, 600 * time.Millisecond, 100 * time.Millisecond
+	tests := []struct {
+		name      string
+		backlog   int
+		wantBeats []time.Duration // Fake time of every heartbeat
+	}{
+		// tasks finish at 0.6s, 1.2s, 1.8s and 2.4s: one beat for each of the first three seconds
+		{"four tasks", 4, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}},
+		{"one task", 1, []time.Duration{time.Second}},
+		{"no backlog", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			clock := NewFakeClock(time.Unix(0, 0))
+			begin := clock.Now()
+			wp := &WorkerPool{Concurrency: 1, Clock: clock, HeartbeatInterval: interval}
+			beats := wp.Heartbeat()
+			if err := wp.Start(); err != nil {
+				t.Fatal(err)
+			}
+			var mu sync.Mutex
+			var got []time.Duration
+			received := func() int {
+				mu.Lock()
+				defer mu.Unlock()
+				return len(got)
+			}
+			collected := make(chan struct{})
+			go func() {
+				defer close(collected)
+				for at := range beats {
+					mu.Lock()
+					got = append(got, at.Sub(begin))
+					mu.Unlock()
+				}
+			}()
+			// the queue holds one task per worker, the rest of the backlog waits in Submit
+			submitted := make(chan error, 1)
+			go func() {
+				for i := range tt.backlog {
+					if err := wp.Submit(Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+						<-clock.After(taskTime)
+						return nil, nil
+					}}); err != nil {
+						submitted <- err
+						return
+					}
+				}
+				submitted <- nil
+			}()
+
+			// run two seconds past the backlog, one step at a time once the tasks due so far are
+			// processed and the running one is on the clock next to the heartbeat ticker
+			drained := time.Duration(tt.backlog) * taskTime
+			for clock.Now().Sub(begin) < drained+2*interval {
+				elapsed := clock.Now().Sub(begin)
+				processed := min(int64(elapsed/taskTime), int64(tt.backlog))
+				want := 1
+				if elapsed < drained {
+					want = 2
+				}
+				for wait := time.Now().Add(5 * time.Second); wp.Stats().Processed != processed || clock.Waiters() != want; time.Sleep(time.Millisecond) {
+					if time.Now().After(wait) {
+						t.Fatalf("%d processed and %d timers on the clock at %v, want %d and %d",
+							wp.Stats().Processed, clock.Waiters(), elapsed, processed, want)
+					}
+				}
+				clock.Advance(step)
+
+				// a due heartbeat is received before the next task can finish
+				due := 0
+				for _, at := range tt.wantBeats {
+					if at <= clock.Now().Sub(begin) {
+						due++
+					}
+				}
+				for wait := time.Now().Add(time.Second); received() < due && time.Now().Before(wait); {
+					time.Sleep(time.Millisecond)
+				}
+			}
+			if err := <-submitted; err != nil {
+				t.Fatal(err)
+			}
+			wp.Close()
+			<-collected
+
+			if !slices.Equal(got, tt.wantBeats) {
+				t.Errorf("heartbeats at %v, want %v", got, tt.wantBeats)
+			}
+		})
+	}
+}
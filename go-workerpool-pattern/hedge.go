@@ -0,0 +1,71 @@
+package main
+
+import "context"
+
+/*
+Hedged requests for slow idempotent tasks.
+When an attempt of an Idempotent task has not finished after HedgeAfter, a duplicate is
+dispatched to another worker and whichever copy finishes first provides the outcome; the
+other one is cancelled through its done channel. This trims tail latency caused by a slow
+replica or an unlucky worker, at the cost of doing some work twice.
+*/
+
+// hedgeRun links a hedge duplicate to the attempt it races against
+type hedgeRun struct {
+	ctx context.Context     // Cancelled once the race is decided, stopping the loser
+	out chan attemptOutcome // Receives the duplicate's outcome, buffered so it never blocks
+}
+
+// hedgedAttempt runs one attempt, racing it against a duplicate on another worker if it
+// takes longer than HedgeAfter. Tasks that are not Idempotent are never duplicated.
+// ctx bounds the attempt and the duplicate: the pool context, or the task's CumulativeTimeout.
+func (wp *WorkerPool) hedgedAttempt(ctx context.Context, task Task) (any, error) {
+	if wp.HedgeAfter <= 0 || !task.Idempotent {
+		return wp.attempt(ctx, task)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // cancels whichever copy lost the race
+
+	primary := runAsync(func() (any, error) { return wp.attempt(ctx, task) })
+	timer := wp.clock().NewTimer(wp.HedgeAfter)
+	defer timer.Stop()
+	select {
+	case o := <-primary:
+		return o.result()
+	case <-timer.C():
+	}
+
+	// dispatch the duplicate to another worker, without affinity so it can run anywhere
+	duplicate := task
+	duplicate.Affinity = 0
+	duplicate.hedge = &hedgeRun{ctx: ctx, out: make(chan attemptOutcome, 1)}
+	wp.counters.hedged.Add(1)
+	wp.addTask()
+	go wp.dispatch(duplicate)
+
+	select {
+	case o := <-primary:
+		return o.result()
+	case o := <-duplicate.hedge.out:
+		wp.counters.hedgeWins.Add(1)
+		return o.result()
+	}
+}
+
+// processHedge runs a hedge duplicate picked up by a worker. Its outcome only goes to the
+// original attempt; a duplicate whose race was already decided is dropped without running.
+// The duplicate takes its own CostBudget, MaxInFlight and adaptive permits like any task. It is
+// not registered in InFlight: it is another attempt of a task the primary already reports there.
+// If the limits are exhausted (e.g. by the primary) it waits for a permit and is dropped once the
+// race is decided without it.
+func (wp *WorkerPool) processHedge(task Task) {
+	ctx := task.hedge.ctx
+	if ctx.Err() != nil || !wp.acquirePermits(ctx, task) {
+		return
+	}
+	start := wp.clock().Now()
+	value, err := wp.attempt(ctx, task)
+	wp.releasePermits(task, wp.clock().Now().Sub(start), err)
+	task.hedge.out <- attemptOutcome{value: value, err: err}
+}
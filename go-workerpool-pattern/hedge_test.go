@@ -0,0 +1,60 @@
+package main
+
+import (
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestHedgeListedOnceInFlight keeps a slow task running until its hedge duplicate started and
+// checks that InFlight lists the task once, not once per copy
+func TestHedgeListedOnceInFlight(t *testing.T) {
+	tests := []struct {
+		name       string
+		idempotent bool
+		wantRuns   int32 // Copies of the task that start running
+	}{
+		{"hedged idempotent task", true, 2},
+		{"task that is not idempotent", false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			var runs atomic.Int32
+			release := make(chan struct{})
+			task := Task{Id: 7, Idempotent: tt.idempotent, Work: func(done <-chan struct{}) (any, error) {
+				runs.Add(1)
+				select {
+				case <-release:
+				case <-done:
+				}
+				return nil, nil
+			}}
+			wp := &WorkerPool{Concurrency: 2, HedgeAfter: 5 * time.Millisecond}
+			wp.Start()
+			if err := wp.Submit(task); err != nil {
+				t.Fatal(err)
+			}
+
+			for runs.Load() < tt.wantRuns {
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(20 * time.Millisecond) // well past HedgeAfter, a hedge would have started
+			if got := wp.InFlight(); !slices.Equal(got, []int{7}) {
+				t.Errorf("InFlight() = %v while %d copies run, want [7]", got, runs.Load())
+			}
+			if got := runs.Load(); got != tt.wantRuns {
+				t.Errorf("%d copies of the task ran, want %d", got, tt.wantRuns)
+			}
+
+			close(release)
+			wp.Close()
+			if got := wp.InFlight(); len(got) != 0 {
+				t.Errorf("InFlight() = %v after Close, want none", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+/*
+Bucketed latency histogram.
+Durations are counted in log-linear buckets: every power of two is split into 8 equal buckets,
+so a percentile is off by at most 1/16 of its value. Recording is a single atomic add, cheap
+enough for every worker to update the same histogram after each task.
+*/
+
+// histogramSubBuckets is the number of buckets every power of two is split into
+const histogramSubBuckets = 8
+
+// DurationHistogram counts durations for percentile queries. The zero value is ready to use
+// and it is safe for concurrent use.
+type DurationHistogram struct {
+	buckets [(64 - 2) * histogramSubBuckets]atomic.Int64
+}
+
+// bucketOf returns the bucket index of a duration of n nanoseconds
+func bucketOf(n uint64) int {
+	if n < histogramSubBuckets {
+		return int(n)
+	}
+	exp := bits.Len64(n) - 1 // n is in [2^exp, 2^(exp+1))
+	sub := (n >> (exp - 3)) & (histogramSubBuckets - 1)
+	return (exp-2)*histogramSubBuckets + int(sub)
+}
+
+// bucketValue returns the midpoint of a bucket, in nanoseconds
+func bucketValue(i int) uint64 {
+	if i < histogramSubBuckets {
+		return uint64(i)
+	}
+	exp := i/histogramSubBuckets + 2
+	sub := uint64(i % histogramSubBuckets)
+	width := uint64(1) << (exp - 3)
+	return (histogramSubBuckets+sub)*width + width/2
+}
+
+// Observe records a duration, negative durations count as zero
+func (h *DurationHistogram) Observe(d time.Duration) {
+	h.buckets[bucketOf(uint64(max(d, 0)))].Add(1)
+}
+
+// Count returns the number of recorded durations
+func (h *DurationHistogram) Count() int64 {
+	var n int64
+	for i := range h.buckets {
+		n += h.buckets[i].Load()
+	}
+	return n
+}
+
+// Percentile returns the duration below which p percent (0-100) of the recorded durations fall,
+// e.g. Percentile(99) for the p99 latency. It returns 0 when nothing was recorded.
+func (h *DurationHistogram) Percentile(p float64) time.Duration {
+	var counts [len(h.buckets)]int64
+	var total int64
+	for i := range h.buckets {
+		counts[i] = h.buckets[i].Load()
+		total += counts[i]
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := int64(math.Ceil(min(max(p, 0), 100) / 100 * float64(total)))
+	rank = max(rank, 1)
+	var seen int64
+	for i, c := range counts {
+		seen += c
+		if seen >= rank {
+			return time.Duration(bucketValue(i))
+		}
+	}
+	return time.Duration(bucketValue(len(counts) - 1))
+}
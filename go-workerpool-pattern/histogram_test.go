@@ -0,0 +1,134 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// withinBucket reports whether got is within the histogram's precision (1/16) of want
+func withinBucket(got, want time.Duration) bool {
+	diff := got - want
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= want/16
+}
+
+// TestDurationHistogramPercentiles feeds known durations and checks the percentiles to within
+// the bucket precision
+func TestDurationHistogramPercentiles(t *testing.T) {
+	ms := time.Millisecond
+	spread := func(from, to, step time.Duration) []time.Duration {
+		var ds []time.Duration
+		for d := from; d <= to; d += step {
+			ds = append(ds, d)
+		}
+		return ds
+	}
+	tests := []struct {
+		name      string
+		durations []time.Duration
+		want      map[float64]time.Duration // Percentile to expected duration
+	}{
+		{"empty", nil, map[float64]time.Duration{50: 0, 99: 0}},
+		{"single duration", []time.Duration{42 * ms}, map[float64]time.Duration{0: 42 * ms, 50: 42 * ms, 100: 42 * ms}},
+		{"1ms to 100ms", spread(ms, 100*ms, ms), map[float64]time.Duration{50: 50 * ms, 95: 95 * ms, 99: 99 * ms, 100: 100 * ms}},
+		{"one slow in twenty", append(spread(5*ms, 5*ms+18*time.Nanosecond, time.Nanosecond), time.Second),
+			map[float64]time.Duration{50: 5 * ms, 95: 5 * ms, 99: time.Second}},
+		{"microseconds to seconds", []time.Duration{time.Microsecond, time.Millisecond, time.Second, time.Minute},
+			map[float64]time.Duration{25: time.Microsecond, 50: time.Millisecond, 75: time.Second, 100: time.Minute}},
+		{"sub-bucket nanoseconds", []time.Duration{0, 1, 2, 3, 4, 5, 6, 7}, map[float64]time.Duration{0: 0, 50: 3, 100: 7}},
+		{"negative counts as zero", []time.Duration{-time.Second, -1}, map[float64]time.Duration{50: 0, 100: 0}},
+		{"out of range percentiles clamped", spread(ms, 10*ms, ms), map[float64]time.Duration{-5: ms, 150: 10 * ms}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h DurationHistogram
+			for _, d := range tt.durations {
+				h.Observe(d)
+			}
+			if got := h.Count(); got != int64(len(tt.durations)) {
+				t.Errorf("Count() = %d, want %d", got, len(tt.durations))
+			}
+			for p, want := range tt.want {
+				if got := h.Percentile(p); !withinBucket(got, want) {
+					t.Errorf("Percentile(%v) = %v, want %v ± 1/16", p, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestDurationHistogramConcurrentObserve records from many goroutines at once and checks that
+// no observation is lost
+func TestDurationHistogramConcurrentObserve(t *testing.T) {
+	const goroutines, each = 16, 1000
+	var h DurationHistogram
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range each {
+				h.Observe(time.Duration(g*each+i) * time.Microsecond)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := h.Count(); got != goroutines*each {
+		t.Errorf("Count() = %d, want %d", got, goroutines*each)
+	}
+	if got, want := h.Percentile(50), goroutines*each/2*time.Microsecond; !withinBucket(got, want) {
+		t.Errorf("Percentile(50) = %v, want %v ± 1/16", got, want)
+	}
+}
+
+// TestStatsLatencyPercentiles runs tasks taking known fake durations and checks that Stats
+// reports their average and percentiles
+func TestStatsLatencyPercentiles(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name                      string
+		latency                   func(id int) time.Duration
+		wantAvg                   time.Duration
+		wantP50, wantP95, wantP99 time.Duration
+	}{
+		{"uniform", func(int) time.Duration { return 10 * ms }, 10 * ms, 10 * ms, 10 * ms, 10 * ms},
+		{"one slow in twenty", func(id int) time.Duration {
+			if id%20 == 0 {
+				return 100 * ms
+			}
+			return 5 * ms
+		}, 9750 * time.Microsecond, 5 * ms, 5 * ms, 100 * ms},
+		{"linear", func(id int) time.Duration { return time.Duration(id) * ms }, 20500 * time.Microsecond, 20 * ms, 38 * ms, 40 * ms},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewFakeClock(time.Unix(0, 0))
+			wp := &WorkerPool{Concurrency: 1, Clock: clock}
+			for id := 1; id <= 40; id++ {
+				wp.Tasks = append(wp.Tasks, Task{Id: id, Work: func(done <-chan struct{}) (any, error) {
+					clock.Advance(tt.latency(id))
+					return nil, nil
+				}})
+			}
+			wp.Run()
+			stats := wp.Stats()
+			if stats.AvgDuration != tt.wantAvg {
+				t.Errorf("AvgDuration = %v, want %v", stats.AvgDuration, tt.wantAvg)
+			}
+			for _, p := range []struct {
+				name      string
+				got, want time.Duration
+			}{{"P50", stats.P50, tt.wantP50}, {"P95", stats.P95, tt.wantP95}, {"P99", stats.P99, tt.wantP99}} {
+				if !withinBucket(p.got, p.want) {
+					t.Errorf("%s = %v, want %v ± 1/16", p.name, p.got, p.want)
+				}
+			}
+			if got := wp.Durations().Count(); got != 40 {
+				t.Errorf("Durations().Count() = %d, want 40", got)
+			}
+		})
+	}
+}
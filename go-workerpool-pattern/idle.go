@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+Waiting for a streaming WorkerPool to go idle.
+Close drains the pool and tears it down. WaitIdle only waits: it returns once every task
+submitted so far (follow-ups and hedge duplicates included) has been processed and its result
+handled, and the pool keeps accepting tasks afterwards, e.g. to checkpoint a stream without
+shutting the pool. The outstanding tasks are counted next to the WaitGroup the drain waits on,
+and waiters sleep on a condition variable that is signalled whenever the count drops to zero.
+*/
+
+// idleTracker counts the outstanding (queued or in-flight) tasks behind WaitIdle
+type idleTracker struct {
+	mu      sync.Mutex
+	cond    *sync.Cond // Broadcast when the count drops to zero or a waiter's context is done
+	pending int        // Tasks accepted and not yet finished
+	cleared int        // How often pending dropped to zero, so waiters notice a brief idle
+}
+
+// init creates the condition variable on first use, caller must hold mu
+func (t *idleTracker) init() {
+	if t.cond == nil {
+		t.cond = sync.NewCond(&t.mu)
+	}
+}
+
+// add counts a task accepted by the pool
+func (t *idleTracker) add() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending++
+}
+
+// done counts a finished task and wakes the waiters once none is left
+func (t *idleTracker) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending--
+	if t.pending == 0 {
+		t.cleared++
+		t.init()
+		t.cond.Broadcast()
+	}
+}
+
+// outstanding returns the number of tasks accepted and not yet finished
+func (t *idleTracker) outstanding() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pending
+}
+
+// wait blocks until no task is outstanding, returning ctx.Err() if ctx is done first. A backlog
+// that clears and is refilled before the waiter wakes up still counts as cleared.
+func (t *idleTracker) wait(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.init()
+	stop := context.AfterFunc(ctx, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.cond.Broadcast()
+	})
+	defer stop()
+
+	cleared := t.cleared
+	for t.pending > 0 && t.cleared == cleared {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		t.cond.Wait()
+	}
+	return nil
+}
+
+// addTask counts a task the drain and WaitIdle wait for
+func (wp *WorkerPool) addTask() {
+	wp.wg.Add(1)
+	wp.idle.add()
+}
+
+// taskDone marks a task counted by addTask as finished
+func (wp *WorkerPool) taskDone() {
+	wp.idle.done()
+	wp.wg.Done()
+}
+
+// WaitIdle blocks until the pool has no queued, delayed or in-flight task, i.e. every task
+// submitted so far has been processed and its result handled, and returns nil. Unlike Close it
+// leaves the pool running, so more tasks can be submitted afterwards. Tasks submitted while it
+// waits are waited for too, unless the backlog cleared in between. It returns ctx.Err() if ctx
+// is done first.
+func (wp *WorkerPool) WaitIdle(ctx context.Context) error {
+	return wp.idle.wait(ctx)
+}
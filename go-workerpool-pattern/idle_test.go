@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestWaitIdle releases a backlog of blocking tasks one at a time, the last one possibly a
+// delayed task, and checks that WaitIdle returns exactly once the last result was handled, not
+// before, and that the pool stays open for the next checkpoint
+func TestWaitIdle(t *testing.T) {
+	tests := []struct {
+		name    string
+		backlog int  // Blocking tasks, each released on its own
+		delayed bool // Also submit a task due one second of fake time later
+	}{
+		{"idle pool", 0, false},
+		{"one task", 1, false},
+		{"backlog on both workers and the queue", 4, false},
+		{"delayed task", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			clock := NewFakeClock(time.Unix(0, 0))
+			var handled atomic.Int32
+			wp := &WorkerPool{Concurrency: 2, Clock: clock, OnResult: func(Task, Result) { handled.Add(1) }}
+			if err := wp.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer wp.Close()
+
+			releases := make([]chan struct{}, tt.backlog)
+			for i := range releases {
+				releases[i] = make(chan struct{})
+				if err := wp.Submit(Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+					<-releases[i]
+					return nil, nil
+				}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			total := int32(tt.backlog)
+			if tt.delayed {
+				total++
+				if err := wp.SubmitAfter(Task{Id: 100, Work: func(done <-chan struct{}) (any, error) { return nil, nil }}, time.Second); err != nil {
+					t.Fatal(err)
+				}
+			}
+			idle := make(chan int32, 1) // Results handled when WaitIdle returned
+			go func() {
+				if err := wp.WaitIdle(context.Background()); err != nil {
+					t.Error(err)
+				}
+				idle <- handled.Load()
+			}()
+
+			// every step but the last leaves work behind, WaitIdle must keep waiting
+			var steps []func()
+			for _, release := range releases {
+				steps = append(steps, func() { close(release) })
+			}
+			if tt.delayed {
+				steps = append(steps, func() { clock.Advance(time.Second) })
+			}
+			for i, step := range steps {
+				select {
+				case n := <-idle:
+					t.Fatalf("WaitIdle returned after %d of %d steps with %d results handled", i, len(steps), n)
+				case <-time.After(20 * time.Millisecond):
+				}
+				step()
+			}
+			select {
+			case n := <-idle:
+				if n != total {
+					t.Errorf("WaitIdle returned with %d results handled, want %d", n, total)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("WaitIdle still waiting after the backlog cleared")
+			}
+
+			// the pool is still open, and the next checkpoint waits for the task submitted since
+			release := make(chan struct{})
+			if err := wp.Submit(Task{Id: 200, Work: func(done <-chan struct{}) (any, error) { <-release; return nil, nil }}); err != nil {
+				t.Fatalf("Submit after WaitIdle: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			if err := wp.WaitIdle(ctx); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("WaitIdle with a task running returned %v, want context.DeadlineExceeded", err)
+			}
+			close(release)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"slices"
+	"sync"
+)
+
+/*
+Snapshot of the tasks a WorkerPool is processing right now.
+Workers register the Id of a task when they start processing it and deregister it once it has
+finished, so InFlight can report a "currently running" view at any time, e.g. for diagnostics
+of a batch that seems slow.
+*/
+
+// inFlightTracker records the Ids of the tasks being processed
+type inFlightTracker struct {
+	mu  sync.Mutex
+	ids map[int]int // Number of tasks per Id being processed
+}
+
+// begin registers a task that started processing
+func (t *inFlightTracker) begin(id int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ids == nil {
+		t.ids = make(map[int]int)
+	}
+	t.ids[id]++
+}
+
+// end deregisters a task that finished processing
+func (t *inFlightTracker) end(id int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ids[id]--; t.ids[id] <= 0 {
+		delete(t.ids, id)
+	}
+}
+
+// InFlight returns the Ids of the tasks being processed at this moment, in ascending order; an
+// Id processed by several tasks at once appears once per task. A task counts from the moment a
+// worker starts processing it (queued tasks and tasks waiting for CostBudget or MaxInFlight are
+// not included) until its processing, retries included, has finished. It is safe to call at any
+// time, but the snapshot may be outdated as soon as it is returned.
+func (wp *WorkerPool) InFlight() []int {
+	wp.running.mu.Lock()
+	defer wp.running.mu.Unlock()
+	ids := make([]int, 0, len(wp.running.ids))
+	for id, n := range wp.running.ids {
+		for range n {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestInFlightSnapshot runs a batch of tasks that each block until released, releases them one
+// by one and checks InFlight at every checkpoint where the set of running tasks is known: after
+// the released task's result was handled and the task taking over its worker has started
+func TestInFlightSnapshot(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		tasks       int
+		retried     []int   // Tasks whose first attempt fails, they block in their retry
+		release     []int   // Order in which the tasks are released
+		want        [][]int // InFlight before the first release and after each one
+	}{
+		{
+			name: "released in order", concurrency: 2, tasks: 4,
+			release: []int{1, 2, 3, 4},
+			want:    [][]int{{1, 2}, {2, 3}, {3, 4}, {4}, {}},
+		},
+		{
+			name: "released out of order", concurrency: 2, tasks: 4,
+			release: []int{2, 1, 4, 3},
+			want:    [][]int{{1, 2}, {1, 3}, {3, 4}, {3}, {}},
+		},
+		{
+			name: "single worker", concurrency: 1, tasks: 3,
+			release: []int{1, 2, 3},
+			want:    [][]int{{1}, {2}, {3}, {}},
+		},
+		{
+			name: "more workers than tasks", concurrency: 4, tasks: 2,
+			release: []int{2, 1},
+			want:    [][]int{{1, 2}, {1}, {}},
+		},
+		{
+			name: "in flight across retries", concurrency: 2, tasks: 3, retried: []int{1, 3},
+			release: []int{1, 2, 3},
+			want:    [][]int{{1, 2}, {2, 3}, {3}, {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			started, finished := make(chan int, tt.tasks), make(chan int, tt.tasks)
+			release := map[int]chan struct{}{}
+			tasks := make([]Task, tt.tasks)
+			for i := range tasks {
+				id := i + 1
+				release[id] = make(chan struct{})
+				failFirst := slices.Contains(tt.retried, id)
+				tasks[i] = Task{Id: id, Work: func(done <-chan struct{}) (any, error) {
+					if failFirst {
+						failFirst = false
+						return nil, errors.New("first attempt failed")
+					}
+					started <- id
+					<-release[id]
+					return nil, nil
+				}}
+			}
+			wp := &WorkerPool{Tasks: tasks, Concurrency: tt.concurrency, MaxRetries: 1,
+				OnResult: func(task Task, result Result) { finished <- task.Id }}
+			errc := make(chan error, 1)
+			go func() { errc <- wp.Run() }()
+
+			// snapshots taken concurrently with the workers registering and deregistering tasks
+			stop, polled := make(chan struct{}), make(chan struct{})
+			go func() {
+				defer close(polled)
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+						wp.InFlight()
+					}
+				}
+			}()
+			defer func() { close(stop); <-polled }()
+
+			receive := func(ch <-chan int, what string) int {
+				select {
+				case id := <-ch:
+					return id
+				case <-time.After(5 * time.Second):
+					t.Fatalf("no task %s within 5s, InFlight() = %v", what, wp.InFlight())
+					return 0
+				}
+			}
+			running := min(tt.concurrency, tt.tasks)
+			for range running {
+				receive(started, "started")
+			}
+			queued := tt.tasks - running
+			check := func(step string, want []int) {
+				if got := wp.InFlight(); !slices.Equal(got, want) {
+					t.Errorf("InFlight() %s = %v, want %v", step, got, want)
+				}
+			}
+			check("before any release", tt.want[0])
+			for i, id := range tt.release {
+				close(release[id])
+				if got := receive(finished, "finished"); got != id {
+					t.Fatalf("task %d finished after releasing task %d", got, id)
+				}
+				if queued > 0 {
+					receive(started, "started")
+					queued--
+				}
+				check(fmt.Sprintf("after releasing task %d", id), tt.want[i+1])
+			}
+			if err := <-errc; err != nil {
+				t.Fatal(err)
+			}
+			check("after Run", []int{})
+		})
+	}
+}
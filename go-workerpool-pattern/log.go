@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+/*
+Structured logging of the worker pools.
+Key events (a task finished, was retried or skipped, a worker was recycled or restarted, the stall
+watchdog fired) are logged to Log as slog records with attributes such as task_id, worker_id and
+duration. Records of tasks are logged with the task's context, so a handler can add request-scoped
+values (e.g. a trace id) carried by the context passed to RunWithContext. Log discards everything
+by default, the demos keep printing their own output.
+*/
+
+// Log receives the structured log records of the pools, e.g. slog.Default() or
+// slog.New(slog.NewJSONHandler(os.Stderr, nil)). Set it before starting a pool, it is not
+// guarded against concurrent changes.
+var Log = slog.New(discardHandler{})
+
+// discardHandler is a slog.Handler that drops every record. It reports every level as disabled,
+// so the pools skip building the records altogether.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// logTask logs an event of a task at info level, or warn level if it failed. worker is the index
+// of the worker that processed it, or -1 if unknown.
+func logTask(ctx context.Context, msg string, worker, taskId, attempts int, elapsed time.Duration, err error) {
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelWarn
+	}
+	if !Log.Enabled(ctx, level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.Int("task_id", taskId),
+		slog.Int("attempts", attempts),
+		slog.Duration("duration", elapsed),
+	}
+	if worker >= 0 {
+		attrs = append(attrs, slog.Int("worker_id", worker))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	Log.LogAttrs(ctx, level, msg, attrs...)
+}
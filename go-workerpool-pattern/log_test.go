@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+// requestIdKey is the context key of the request id picked up by recordingHandler
+type requestIdKey struct{}
+
+// record is a log record kept by recordingHandler
+type record struct {
+	level     slog.Level
+	msg       string
+	requestId any // Request id found in the context the record was logged with
+	attrs     map[string]slog.Value
+}
+
+// recordingHandler is a slog.Handler keeping the records it receives at or above level
+type recordingHandler struct {
+	level   slog.Level
+	mu      *sync.Mutex
+	records *[]record
+}
+
+func (h recordingHandler) Enabled(_ context.Context, level slog.Level) bool { return level >= h.level }
+func (h recordingHandler) WithAttrs([]slog.Attr) slog.Handler               { return h }
+func (h recordingHandler) WithGroup(string) slog.Handler                    { return h }
+
+func (h recordingHandler) Handle(ctx context.Context, r slog.Record) error {
+	rec := record{level: r.Level, msg: r.Message, requestId: ctx.Value(requestIdKey{}), attrs: map[string]slog.Value{}}
+	r.Attrs(func(a slog.Attr) bool {
+		rec.attrs[a.Key] = a.Value
+		return true
+	})
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.records = append(*h.records, rec)
+	return nil
+}
+
+// TestTaskFinishedRecords runs a single task with Log capturing the records and checks the
+// "task finished" record: its message, level and attributes, and the request id of the context
+// passed to RunWithContext
+func TestTaskFinishedRecords(t *testing.T) {
+	failure := errors.New("upstream unavailable")
+	tests := []struct {
+		name         string
+		handlerLevel slog.Level
+		maxRetries   int
+		err          error // Error of every attempt of the task
+		wantRecord   bool
+		wantLevel    slog.Level
+		wantAttempts int64
+	}{
+		{"successful task", slog.LevelInfo, 0, nil, true, slog.LevelInfo, 1},
+		{"failing task", slog.LevelInfo, 0, failure, true, slog.LevelWarn, 1},
+		{"failing task after retries", slog.LevelInfo, 2, failure, true, slog.LevelWarn, 3},
+		{"successful task below the handler level", slog.LevelWarn, 0, nil, false, 0, 0},
+		{"failing task at the handler level", slog.LevelWarn, 0, failure, true, slog.LevelWarn, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var records []record
+			previous := Log
+			Log = slog.New(recordingHandler{level: tt.handlerLevel, mu: &mu, records: &records})
+			defer func() { Log = previous }()
+
+			wp := WorkerPool{Concurrency: 1, MaxRetries: tt.maxRetries, Tasks: []Task{
+				{Id: 7, Work: func(done <-chan struct{}) (any, error) { return nil, tt.err }},
+			}}
+			ctx := context.WithValue(context.Background(), requestIdKey{}, "req-42")
+			if _, err := wp.RunWithContext(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			var finished []record
+			for _, r := range records {
+				if r.msg == "task finished" {
+					finished = append(finished, r)
+				}
+			}
+			if !tt.wantRecord {
+				if len(finished) != 0 {
+					t.Fatalf("got %d task finished records below the handler level, want none", len(finished))
+				}
+				return
+			}
+			if len(finished) != 1 {
+				t.Fatalf("got %d task finished records, want 1", len(finished))
+			}
+			r := finished[0]
+			if r.level != tt.wantLevel || r.requestId != "req-42" {
+				t.Errorf("record at level %v with request id %v, want %v and req-42", r.level, r.requestId, tt.wantLevel)
+			}
+			if id := r.attrs["task_id"]; id.Kind() != slog.KindInt64 || id.Int64() != 7 {
+				t.Errorf("task_id = %v, want 7", id)
+			}
+			if n := r.attrs["attempts"]; n.Kind() != slog.KindInt64 || n.Int64() != tt.wantAttempts {
+				t.Errorf("attempts = %v, want %d", n, tt.wantAttempts)
+			}
+			if w := r.attrs["worker_id"]; w.Kind() != slog.KindInt64 || w.Int64() != 0 {
+				t.Errorf("worker_id = %v, want 0", w)
+			}
+			if d := r.attrs["duration"]; d.Kind() != slog.KindDuration || d.Duration() < 0 {
+				t.Errorf("duration = %v, want a non-negative duration", d)
+			}
+			e, logged := r.attrs["error"]
+			switch {
+			case tt.err == nil && logged:
+				t.Errorf("error = %v logged for a successful task", e)
+			case tt.err != nil && (!logged || e.Kind() != slog.KindAny || !errors.Is(e.Any().(error), tt.err)):
+				t.Errorf("error = %v, want %v", e, tt.err)
+			}
+		})
+	}
+}
@@ -1,13 +1,56 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 func main() {
 	// comment out one of the following function calls to test either implementation
 	WorkerPoolWithOneTypeOfTask()
 	WorkerPoolWithMultipleTypeOfTasks()
+	WorkerPoolWithDelayedTasks()
+	WorkerPoolWithCircuitBreaker()
+	WorkerPoolWithTaskAffinity()
+	WorkerPoolWithResultCallback()
+	WorkerPoolWithCancellation()
+	WorkerPoolDeterministic()
+	WorkerPoolWithDependencies()
+	WorkerPoolWithIdleTimeout()
+	WorkerPoolWithCostBudget()
+	WorkerPoolWithRetries()
+	WorkerPoolWithTaskErrors()
+	WorkerPoolWithTracingHooks()
+	WorkerPoolWithObjectPool()
+	WorkerPoolWithStackDispatch()
+	WorkerPoolWithHedging()
+	WorkerPoolWithResultsChannel()
+	WorkerPoolWithRetryBudget()
+	WorkerPoolWithRunStream()
+	WorkerPoolWithResultTasks()
+	WorkerPoolWithProgress()
+	WorkerPoolWithTaskCancellation()
+	WorkerPoolWithErrorMap()
+	WorkerPoolWithPlan()
+	WorkerPoolForEach()
+	WorkerPoolWithSafeMap()
+	WorkerPoolWithTags()
+	WorkerPoolWithResume()
+	WorkerPoolWithPipe()
+	WorkerPoolWithFakeClock()
+	WorkerPoolWithRunReport()
+	RetryExample()
+	WorkerPoolWithStructuredLogging()
+	WorkerPoolWithSpscQueue()
 }
 
 func WorkerPoolWithOneTypeOfTask() {
@@ -54,6 +97,1021 @@ func WorkerPoolWithMultipleTypeOfTasks() {
 		Concurrency: 3,
 	}
 
-	wp.Run()
+	summary, _ := wp.Run()
 	fmt.Println("All tasks completed.")
+	fmt.Printf("Summary: total=%d byType=%v wallClock=%v longest=%s (%v)\n",
+		summary.Total, summary.ByType, summary.WallClock.Round(time.Millisecond),
+		typeNameOf(summary.Longest), summary.LongestDuration.Round(time.Millisecond))
+}
+
+func WorkerPoolWithDelayedTasks() {
+
+	//start an empty worker pool and schedule tasks on it
+	wp := WorkerPool{
+		Concurrency: 2,
+	}
+	wp.Start()
+
+	//delayed tasks are released in due-time order, not submission order
+	wp.SubmitAfter(Task{Id: 3}, 3*time.Second)
+	wp.SubmitAfter(Task{Id: 1}, 1*time.Second)
+	wp.SubmitAfter(Task{Id: 2}, 2*time.Second)
+	wp.Submit(Task{Id: 0})
+
+	wp.Close()
+	fmt.Println("All delayed tasks completed.")
+}
+
+// UnavailableImageTask simulates an image task whose downstream service is down
+type UnavailableImageTask struct {
+	ImageURL string
+}
+
+// Process runs the task, dropping its error
+func (u *UnavailableImageTask) Process() {
+	_ = u.ProcessErr()
+}
+
+// ProcessErr always fails, like calling an image service that is unavailable
+func (u *UnavailableImageTask) ProcessErr() error {
+	fmt.Println("Processing image from URL:", u.ImageURL)
+	time.Sleep(200 * time.Millisecond)
+	return errors.New("image service unavailable")
+}
+
+// TypeName returns the same type name as ImageProcessingTask so both share a breaker
+func (u *UnavailableImageTask) TypeName() string {
+	return "image"
+}
+
+func WorkerPoolWithCircuitBreaker() {
+
+	//the image service keeps failing while emails keep working
+	var multiTask []MultiTask
+	for i := 1; i <= 6; i++ {
+		multiTask = append(multiTask,
+			&UnavailableImageTask{ImageURL: fmt.Sprintf("IMG-%d", i)},
+			&EmailTask{EmailId: fmt.Sprintf("user%d", i), Subject: "hello", Message: "message"},
+		)
+	}
+
+	//after 2 consecutive image failures the breaker opens and fast-fails the remaining image tasks
+	wp := NewWorkerPool{
+		MultiTasks:  multiTask,
+		Concurrency: 1,
+		Breaker:     &CircuitBreaker{FailureThreshold: 2, Cooldown: 10 * time.Second},
+	}
+
+	wp.Run()
+	metrics := wp.Metrics()
+	fmt.Printf("Processed=%d Failed=%d Rejected=%d Breakers=%v\n",
+		metrics.Processed, metrics.Failed, metrics.Rejected, metrics.Breakers)
+}
+
+func WorkerPoolWithTaskAffinity() {
+
+	//tasks sharing an affinity key reuse the cache of the same worker, the rest are load-balanced
+	tasks := []Task{
+		{Id: 1, Affinity: 7},
+		{Id: 2},
+		{Id: 3, Affinity: 8},
+		{Id: 4, Affinity: 7},
+		{Id: 5},
+		{Id: 6, Affinity: 8},
+	}
+
+	wp := WorkerPool{
+		Tasks:       tasks,
+		Concurrency: 3,
+	}
+
+	//show the pool's debug records, where each worker reports the caches it builds
+	previous := Log
+	Log = debugLog()
+	defer func() { Log = previous }()
+	wp.Run()
+	fmt.Println("All affinity tasks completed.")
+}
+
+func WorkerPoolWithResultCallback() {
+
+	//create tasks that compute a value
+	tasks := make([]Task, 10)
+	for i := range tasks {
+		id := i + 1
+		tasks[i] = Task{Id: id, Work: func(done <-chan struct{}) (any, error) {
+			time.Sleep(100 * time.Millisecond)
+			return id * id, nil
+		}}
+	}
+
+	//stream each result into a "database" as soon as it is produced
+	var mu sync.Mutex
+	database := make(map[int]any)
+
+	wp := WorkerPool{
+		Tasks:       tasks,
+		Concurrency: 4,
+		OnResult: func(task Task, result Result) {
+			// called concurrently by the workers, so guard the shared map
+			mu.Lock()
+			defer mu.Unlock()
+			database[result.TaskId] = result.Value
+			fmt.Printf("Stored result of task %d: %v\n", result.TaskId, result.Value)
+		},
+	}
+
+	wp.Run()
+	fmt.Println("All results stored:", len(database))
+}
+
+func WorkerPoolWithCancellation() {
+
+	//tasks that respect the done channel stop early when the pool is cancelled
+	tasks := make([]Task, 8)
+	for i := range tasks {
+		tasks[i] = Task{Id: i + 1}
+	}
+	onResult := func(task Task, result Result) {
+		if result.Err != nil {
+			fmt.Printf("Task %d: %v\n", task.Id, result.Err)
+		}
+	}
+
+	//channel-based: Cancel() closes the done channel shared by all in-flight tasks
+	wp := WorkerPool{Tasks: tasks, Concurrency: 2, OnResult: onResult}
+	time.AfterFunc(time.Second, wp.Cancel)
+	wp.Run()
+	fmt.Println("Pool cancelled with Cancel().")
+
+	//context-based: cancelling the context has the same effect and Run reports the error
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	wp2 := WorkerPool{Tasks: tasks, Concurrency: 2, OnResult: onResult}
+	_, err := wp2.RunWithContext(ctx)
+	fmt.Println("Pool cancelled with context:", err)
+}
+
+func WorkerPoolDeterministic() {
+
+	//every run prints the same output in the same order, whatever the Concurrency
+	tasks := make([]Task, 5)
+	for i := range tasks {
+		id := i + 1
+		tasks[i] = Task{Id: id, Work: func(done <-chan struct{}) (any, error) {
+			fmt.Println("Deterministic task:", id)
+			return nil, nil
+		}}
+	}
+
+	wp := WorkerPool{
+		Tasks:         tasks,
+		Concurrency:   4,
+		Deterministic: true,
+	}
+
+	wp.Run()
+	fmt.Println("All deterministic tasks completed in submission order.")
+}
+
+func WorkerPoolWithDependencies() {
+
+	//each email links to an image, so it may only be sent once that image is processed
+	multiTask := []MultiTask{
+		WithDependencies("email-abc", &EmailTask{EmailId: "abc", Subject: "your image", Message: "link to ABC"}, "image-abc"),
+		WithDependencies("email-def", &EmailTask{EmailId: "def", Subject: "your image", Message: "link to DEF"}, "image-def"),
+		WithDependencies("image-abc", &ImageProcessingTask{"ABC"}),
+		WithDependencies("image-def", &ImageProcessingTask{"DEF"}),
+	}
+
+	wp := NewWorkerPool{
+		MultiTasks:  multiTask,
+		Concurrency: 2,
+	}
+	if _, err := wp.Run(); err != nil {
+		fmt.Println("Error running dependent tasks:", err)
+		return
+	}
+	fmt.Println("All dependent tasks completed.")
+
+	//a cycle is detected before anything runs
+	cyclic := NewWorkerPool{
+		MultiTasks: []MultiTask{
+			WithDependencies("a", &EmailTask{EmailId: "a"}, "b"),
+			WithDependencies("b", &EmailTask{EmailId: "b"}, "a"),
+		},
+		Concurrency: 2,
+	}
+	if _, err := cyclic.Run(); err != nil {
+		fmt.Println("Error running cyclic tasks:", err)
+	}
+}
+
+func WorkerPoolWithIdleTimeout() {
+
+	//the pool shuts itself down once nothing is submitted for IdleTimeout
+	wp := WorkerPool{
+		Concurrency: 2,
+		IdleTimeout: 2 * time.Second,
+	}
+	wp.Start()
+
+	for i := 1; i <= 3; i++ {
+		wp.Submit(Task{Id: i, Work: func(done <-chan struct{}) (any, error) {
+			fmt.Println("Processing streamed task:", i)
+			return nil, nil
+		}})
+		time.Sleep(time.Second) // each submission resets the idle timer
+	}
+
+	<-wp.Done()
+	fmt.Println("Pool shut down, idle-triggered:", wp.ClosedByIdleTimeout())
+	if err := wp.Submit(Task{Id: 4}); err != nil {
+		fmt.Println("Submit after shutdown:", err)
+	}
+}
+
+func WorkerPoolWithCostBudget() {
+
+	//a mix of heavy (cost 5) and cheap (cost 1) tasks sharing a budget of 10
+	const budget = 10
+	var inFlight, peak atomic.Int64
+
+	tasks := make([]Task, 20)
+	for i := range tasks {
+		weight := 1
+		if i%4 == 0 {
+			weight = 5
+		}
+		tasks[i] = Task{Id: i + 1, Weight: weight, Work: func(done <-chan struct{}) (any, error) {
+			current := inFlight.Add(int64(weight))
+			for {
+				p := peak.Load()
+				if current <= p || peak.CompareAndSwap(p, current) {
+					break
+				}
+			}
+			time.Sleep(200 * time.Millisecond)
+			inFlight.Add(-int64(weight))
+			return nil, nil
+		}}
+	}
+
+	//plenty of workers, the cost budget is what limits concurrency
+	wp := WorkerPool{
+		Tasks:       tasks,
+		Concurrency: 10,
+		CostBudget:  budget,
+	}
+
+	wp.Run()
+	fmt.Printf("All weighted tasks completed, peak in-flight cost %d (budget %d).\n", peak.Load(), budget)
+}
+
+func WorkerPoolWithRetries() {
+
+	//each task fails twice before succeeding, as if a shared downstream was overloaded
+	tasks := make([]Task, 4)
+	for i := range tasks {
+		id := i + 1
+		var attempts atomic.Int32
+		tasks[i] = Task{Id: id, Work: func(done <-chan struct{}) (any, error) {
+			n := attempts.Add(1)
+			fmt.Printf("Task %d attempt %d at %s\n", id, n, time.Now().Format("15:04:05.000"))
+			if n < 3 {
+				return nil, errors.New("downstream overloaded")
+			}
+			return nil, nil
+		}}
+	}
+
+	//jitter spreads the retries of all tasks instead of retrying them in lockstep
+	wp := WorkerPool{
+		Tasks:       tasks,
+		Concurrency: 4,
+		MaxRetries:  3,
+		Backoff:     JitteredBackoff(100*time.Millisecond, time.Second),
+		OnResult: func(task Task, result Result) {
+			fmt.Printf("Task %d finished, err=%v\n", task.Id, result.Err)
+		},
+	}
+
+	wp.Run()
+	fmt.Println("All retried tasks completed.")
+}
+
+// ErrPaymentDeclined is an example sentinel error returned by a task
+var ErrPaymentDeclined = errors.New("payment declined")
+
+func WorkerPoolWithTaskErrors() {
+
+	//a task that always fails with a sentinel error wrapped with %w
+	tasks := []Task{
+		{Id: 1, Work: func(done <-chan struct{}) (any, error) {
+			return nil, fmt.Errorf("charging card: %w", ErrPaymentDeclined)
+		}},
+		{Id: 2, Work: func(done <-chan struct{}) (any, error) { return "ok", nil }},
+	}
+
+	wp := WorkerPool{
+		Tasks:       tasks,
+		Concurrency: 2,
+		MaxRetries:  2,
+		OnResult: func(task Task, result Result) {
+			if result.Err == nil {
+				return
+			}
+			var taskErr *TaskError
+			if errors.As(result.Err, &taskErr) {
+				fmt.Printf("Task %d failed on attempt %d: %v\n", taskErr.TaskId, taskErr.Attempt, taskErr.Err)
+			}
+			fmt.Println("Is payment declined:", errors.Is(result.Err, ErrPaymentDeclined))
+		},
+	}
+
+	wp.Run()
+}
+
+// span is a minimal stand-in for a tracing span
+type span struct {
+	name  string
+	start time.Time
+}
+
+func WorkerPoolWithTracingHooks() {
+
+	//the second task is too slow and times out, its span is still ended
+	tasks := []Task{
+		{Id: 1, Work: func(done <-chan struct{}) (any, error) {
+			time.Sleep(50 * time.Millisecond)
+			return nil, nil
+		}},
+		{Id: 2, Work: func(done <-chan struct{}) (any, error) {
+			select {
+			case <-time.After(time.Second):
+			case <-done:
+			}
+			return nil, nil
+		}},
+	}
+
+	wp := WorkerPool{
+		Tasks:       tasks,
+		Concurrency: 2,
+		TaskTimeout: 200 * time.Millisecond,
+		BeforeProcess: func(task Task) any {
+			return &span{name: fmt.Sprintf("task-%d", task.Id), start: time.Now()}
+		},
+		AfterProcess: func(task Task, handle any, err error) {
+			s := handle.(*span)
+			fmt.Printf("Span %s ended after %v, err=%v\n", s.name, time.Since(s.start).Round(10*time.Millisecond), err)
+		},
+	}
+
+	wp.Run()
+}
+
+func WorkerPoolWithObjectPool() {
+
+	//every task renders a report into a 64KB scratch buffer borrowed from the pool
+	const numTasks = 200
+	var created atomic.Int32
+	buffers := NewObjectPool(func() *bytes.Buffer {
+		created.Add(1)
+		return bytes.NewBuffer(make([]byte, 0, 64*1024))
+	})
+
+	tasks := make([]Task, numTasks)
+	for i := range tasks {
+		tasks[i] = Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+			buf := buffers.Get()
+			buf.Reset()
+			for range 1000 {
+				buf.WriteString("report line\n")
+			}
+			buffers.Put(buf)
+			return nil, nil
+		}}
+	}
+	wp := WorkerPool{Tasks: tasks, Concurrency: 4}
+	wp.Run()
+
+	//see BenchmarkScratchBufferPooled for the allocations saved
+	fmt.Printf("Rendered %d reports, scratch buffers created: %d\n", numTasks, created.Load())
+}
+
+func WorkerPoolWithStackDispatch() {
+
+	//a single worker, the tasks queued while it is busy are processed newest first
+	tasks := make([]Task, 6)
+	for i := range tasks {
+		id := i + 1
+		tasks[i] = Task{Id: id, Work: func(done <-chan struct{}) (any, error) {
+			fmt.Printf("Processing task %d (LIFO)\n", id)
+			time.Sleep(50 * time.Millisecond)
+			return nil, nil
+		}}
+	}
+
+	wp := WorkerPool{Tasks: tasks, Concurrency: 1, Stack: true}
+	wp.Run()
+}
+
+func WorkerPoolWithHedging() {
+
+	//every 4th task hits a slow replica on its first try, a hedged duplicate gets a fast one
+	tasks := make([]Task, 8)
+	for i := range tasks {
+		id := i + 1
+		var calls atomic.Int32
+		tasks[i] = Task{Id: id, Idempotent: true, Work: func(done <-chan struct{}) (any, error) {
+			latency := 50 * time.Millisecond
+			if calls.Add(1) == 1 && id%4 == 0 {
+				latency = 2 * time.Second
+			}
+			select {
+			case <-time.After(latency):
+				return latency, nil
+			case <-done:
+				fmt.Printf("Task %d: slow copy cancelled\n", id)
+				return nil, ErrTaskCancelled
+			}
+		}}
+	}
+
+	wp := WorkerPool{
+		Tasks:       tasks,
+		Concurrency: 4,
+		HedgeAfter:  200 * time.Millisecond,
+		OnResult: func(task Task, result Result) {
+			fmt.Printf("Task %d finished using the copy that took %v\n", task.Id, result.Value)
+		},
+	}
+
+	start := time.Now()
+	wp.Run()
+	stats := wp.Stats()
+	fmt.Printf("Batch took %v, hedged %d attempt(s), duplicate won %d\n",
+		time.Since(start).Round(10*time.Millisecond), stats.Hedged, stats.HedgeWins)
+}
+
+func WorkerPoolWithResultsChannel() {
+
+	//stream results, then abandon the channel midway by cancelling the context
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wp := WorkerPool{Concurrency: 4}
+	wp.Start()
+	results := wp.ResultsCtx(ctx)
+
+	go func() {
+		for i := 1; i <= 100; i++ {
+			if wp.Submit(Task{Id: i, Work: func(done <-chan struct{}) (any, error) {
+				return i * i, nil
+			}}) != nil {
+				return
+			}
+		}
+	}()
+
+	for result := range results {
+		fmt.Printf("Streamed result of task %d: %v\n", result.TaskId, result.Value)
+		if result.TaskId >= 5 {
+			cancel() // nobody reads the remaining results, the workers must not block on them
+			break
+		}
+	}
+
+	wp.Close()
+	for range results {
+		// the channel is closed promptly, drain whatever was sent before the cancellation
+	}
+}
+
+func WorkerPoolWithRetryBudget() {
+
+	//the downstream is down: every task fails, but only 5 retries are spent across the batch
+	var calls atomic.Int32
+	tasks := make([]Task, 10)
+	for i := range tasks {
+		tasks[i] = Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+			calls.Add(1)
+			return nil, errors.New("downstream unavailable")
+		}}
+	}
+
+	wp := WorkerPool{Tasks: tasks, Concurrency: 2, MaxRetries: 3, RetryBudget: 5}
+	wp.Run()
+
+	stats := wp.Stats()
+	fmt.Printf("%d tasks failed after %d calls (%d without the budget), retries left: %d\n",
+		stats.Failed, calls.Load(), len(tasks)*(1+wp.MaxRetries), stats.RetriesLeft)
+}
+
+func WorkerPoolWithRunStream() {
+
+	//an upstream stage producing tasks, e.g. the output of a pipeline
+	in := make(chan Task)
+	go func() {
+		defer close(in)
+		for i := 1; i <= 5; i++ {
+			in <- Task{Id: i, Work: func(done <-chan struct{}) (any, error) {
+				return fmt.Sprintf("thumbnail-%d.png", i), nil
+			}}
+		}
+	}()
+
+	wp := WorkerPool{Concurrency: 3}
+	for result := range wp.RunStream(in) {
+		fmt.Printf("Stream result of task %d: %v\n", result.TaskId, result.Value)
+	}
+	fmt.Println("Result stream closed after the input stream drained.")
+}
+
+// ThumbnailTask produces the URL of the generated thumbnail, opting into result collection
+type ThumbnailTask struct {
+	ImageURL string
+}
+
+func (t *ThumbnailTask) ProcessResult() (any, error) {
+	time.Sleep(100 * time.Millisecond)
+	return t.ImageURL + "?size=thumb", nil
+}
+
+func (t *ThumbnailTask) Process() {
+	_, _ = t.ProcessResult()
+}
+
+func (t *ThumbnailTask) TypeName() string {
+	return "thumbnail"
+}
+
+func WorkerPoolWithResultTasks() {
+
+	//plain side-effect tasks and result-producing tasks in one batch
+	multiTask := []MultiTask{
+		&EmailTask{EmailId: "abc", Subject: "thumbnails", Message: "coming up"},
+		&ThumbnailTask{"https://example.com/a.png"},
+		&ThumbnailTask{"https://example.com/b.png"},
+	}
+
+	wp := NewWorkerPool{MultiTasks: multiTask, Concurrency: 3}
+	summary, _ := wp.Run()
+	results := wp.Results()
+	fmt.Printf("Processed %d tasks, %d produced a result:\n", summary.Total, len(results))
+	for _, r := range results {
+		fmt.Printf("  %s -> %v (err=%v)\n", typeNameOf(r.Task), r.Value, r.Err)
+	}
+}
+
+func WorkerPoolWithProgress() {
+
+	//render a progress line for a batch of 8 tasks
+	tasks := make([]Task, 8)
+	for i := range tasks {
+		tasks[i] = Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+			time.Sleep(20 * time.Millisecond)
+			return nil, nil
+		}}
+	}
+	wp := WorkerPool{
+		Tasks:       tasks,
+		Concurrency: 3,
+		OnProgress: func(done, total int) {
+			fmt.Printf("%d/%d complete\n", done, total)
+		},
+	}
+	wp.Run()
+
+	//in streaming mode the total is unknown and reported as -1
+	streaming := WorkerPool{
+		Concurrency: 2,
+		OnProgress: func(done, total int) {
+			fmt.Printf("%d complete (total %d)\n", done, total)
+		},
+	}
+	streaming.Start()
+	for i := 1; i <= 3; i++ {
+		streaming.Submit(Task{Id: i, Work: func(done <-chan struct{}) (any, error) { return nil, nil }})
+	}
+	streaming.Close()
+}
+
+func WorkerPoolWithTaskCancellation() {
+
+	//one worker, task 1 finds out that the queued tasks 3 and 5 are obsolete
+	var wp WorkerPool
+	tasks := make([]Task, 5)
+	for i := range tasks {
+		tasks[i] = Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+			time.Sleep(20 * time.Millisecond)
+			if i == 0 {
+				fmt.Println("Cancel queued task 3:", wp.CancelTask(3))
+				fmt.Println("Cancel queued task 5:", wp.CancelTask(5))
+				fmt.Println("Cancel running task 1:", wp.CancelTask(1))
+			}
+			return nil, nil
+		}}
+	}
+
+	wp = WorkerPool{
+		Tasks:       tasks,
+		Concurrency: 1,
+		OnResult: func(task Task, result Result) {
+			fmt.Printf("Task %d: err=%v\n", task.Id, result.Err)
+		},
+	}
+	wp.Run()
+	fmt.Printf("Completed: %v, unfinished: %v\n", wp.Completed(), wp.Unfinished())
+}
+
+func WorkerPoolWithErrorMap() {
+
+	//orders keyed by Id, odd orders fail; order 4 is submitted twice and fails once
+	var attempts4 atomic.Int32
+	tasks := make([]Task, 0, 6)
+	for id := 1; id <= 5; id++ {
+		tasks = append(tasks, Task{Id: id, Work: func(done <-chan struct{}) (any, error) {
+			if id%2 == 1 {
+				return nil, fmt.Errorf("order %d: %w", id, ErrPaymentDeclined)
+			}
+			if id == 4 && attempts4.Add(1) == 2 {
+				return nil, errors.New("order 4: duplicate charge rejected")
+			}
+			return nil, nil
+		}})
+	}
+	tasks = append(tasks, tasks[3])
+
+	wp := WorkerPool{Tasks: tasks, Concurrency: 1}
+	errs := wp.RunMap()
+	for id := 1; id <= 5; id++ {
+		fmt.Printf("Order %d: err=%v\n", id, errs[id])
+	}
+}
+
+func WorkerPoolWithPlan() {
+
+	//per-customer tasks pinned by affinity plus a few heavy load-balanced exports
+	tasks := []Task{
+		{Id: 1, Affinity: 101},
+		{Id: 2, Affinity: 102},
+		{Id: 3, Weight: 5},
+		{Id: 4, Affinity: 101},
+		{Id: 5, Weight: 3},
+	}
+
+	//inspect the schedule without running anything
+	wp := WorkerPool{Tasks: tasks, Concurrency: 3, CostBudget: 6}
+	for _, p := range wp.Plan() {
+		worker := "any"
+		if p.Worker != AnyWorker {
+			worker = fmt.Sprint(p.Worker)
+		}
+		fmt.Printf("#%d task %d -> worker %s, cost %d\n", p.Position, p.Id, worker, p.Cost)
+	}
+
+	//in stack mode the newest task is dispatched first
+	wp.Stack = true
+	fmt.Println("Stack mode first task:", wp.Plan()[0].Id)
+}
+
+func WorkerPoolForEach() {
+
+	//fetch ten URLs, three at a time; the fourth one is broken
+	urls := make([]string, 10)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("https://example.com/page/%d", i+1)
+	}
+	var fetched, aborted atomic.Int32
+	err := ForEach(urls, 3, func(ctx context.Context, url string) error {
+		if url == urls[3] {
+			time.Sleep(20 * time.Millisecond)
+			return fmt.Errorf("fetch %s: 404 not found", url)
+		}
+		select {
+		case <-time.After(50 * time.Millisecond):
+			fetched.Add(1)
+			return nil
+		case <-ctx.Done():
+			aborted.Add(1)
+			return ctx.Err()
+		}
+	})
+	fmt.Printf("ForEach error: %v\n", err)
+	fmt.Printf("Fetched %d, aborted %d, skipped %d\n", fetched.Load(), aborted.Load(), len(urls)-1-int(fetched.Load()+aborted.Load()))
+}
+
+func WorkerPoolWithSafeMap() {
+
+	//100 tasks compute a price each and 50 tasks keep bumping shared hit counters
+	var prices SafeMap[int, float64]
+	var hits SafeMap[string, int]
+	tasks := make([]Task, 150)
+	for i := range tasks {
+		id := i + 1
+		tasks[i] = Task{Id: id, Work: func(done <-chan struct{}) (any, error) {
+			if id <= 100 {
+				prices.Store(id, float64(id)*1.5)
+			} else {
+				for _, page := range []string{"home", "cart"} {
+					hits.Update(page, func(n int, _ bool) int { return n + 1 })
+				}
+			}
+			//readers run alongside the writers
+			prices.Load(id / 2)
+			prices.Range(func(int, float64) bool { return false })
+			return nil, nil
+		}}
+	}
+	wp := WorkerPool{Tasks: tasks, Concurrency: 16}
+	wp.Run()
+
+	price, ok := prices.Load(42)
+	fmt.Printf("Prices stored: %d, task 42 -> %.1f (%t), hits: %v\n", prices.Len(), price, ok, hits.Snapshot())
+}
+
+func WorkerPoolWithTags() {
+
+	//nightly sync jobs per region; only the us ones failed last night and need a rerun
+	regions := []string{"us", "eu", "us", "apac", "eu", "us"}
+	tasks := make([]Task, len(regions))
+	for i, region := range regions {
+		tasks[i] = Task{Id: i + 1, Tags: map[string]string{"region": region}, Work: func(done <-chan struct{}) (any, error) {
+			fmt.Printf("Re-syncing job %d (%s)\n", i+1, region)
+			return nil, nil
+		}}
+	}
+
+	wp := WorkerPool{Tasks: tasks, Concurrency: 2}
+	skipped := wp.RunWhere(HasTag("region", "us"))
+	fmt.Println("Left untouched:", taskIds(skipped))
+}
+
+func WorkerPoolWithResume() {
+
+	//the same import batch is rebuilt by every process start
+	newBatch := func(label string) []Task {
+		tasks := make([]Task, 8)
+		for i := range tasks {
+			tasks[i] = Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+				select {
+				case <-time.After(time.Duration(i+1) * 30 * time.Millisecond):
+					fmt.Printf("[%s] imported file %d\n", label, i+1)
+					return nil, nil
+				case <-done:
+					return nil, ErrTaskCancelled
+				}
+			}}
+		}
+		return tasks
+	}
+
+	//first process: interrupted after 130ms, e.g. by a deploy
+	first := WorkerPool{Tasks: newBatch("first run"), Concurrency: 2, Deadline: time.Now().Add(130 * time.Millisecond)}
+	first.Run()
+	var state bytes.Buffer
+	if err := first.SaveState(&state); err != nil {
+		fmt.Println("Save failed:", err)
+		return
+	}
+	fmt.Print("Saved state: ", state.String())
+
+	//second process: load the state and run the same batch, only unfinished files are imported
+	resumed := WorkerPool{Tasks: newBatch("resumed"), Concurrency: 2}
+	if err := resumed.LoadState(&state); err != nil {
+		fmt.Println("Load failed:", err)
+		return
+	}
+	resumed.Run()
+	fmt.Println("Completed after resume:", resumed.Completed())
+}
+
+func WorkerPoolWithPipe() {
+
+	//stage 1 resizes images, one of them is corrupt
+	images := []string{"cat.png", "dog.png", "broken.png", "bird.png"}
+	resize := WorkerPool{Concurrency: 2}
+	for i, name := range images {
+		resize.Tasks = append(resize.Tasks, Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+			if name == "broken.png" {
+				return nil, errors.New("cannot decode " + name)
+			}
+			time.Sleep(20 * time.Millisecond)
+			return "thumb-" + name, nil
+		}})
+	}
+
+	//stage 2 uploads the thumbnails
+	var mu sync.Mutex
+	var uploaded []string
+	upload := WorkerPool{Concurrency: 1}
+	resize.Pipe(&upload, func(r Result) Task {
+		return Task{Id: r.TaskId, Work: func(done <-chan struct{}) (any, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			uploaded = append(uploaded, r.Value.(string))
+			return nil, nil
+		}}
+	})
+
+	//Run returns once both stages are done
+	errs := resize.RunMap()
+	slices.Sort(uploaded)
+	fmt.Printf("Uploaded %v, resize error: %v\n", uploaded, errs[3])
+}
+
+func WorkerPoolWithFakeClock() {
+
+	//an hour-long delay and a one-minute task timeout run instantly on a fake clock
+	clock := NewFakeClock(time.Date(2025, time.January, 1, 9, 0, 0, 0, time.UTC))
+	results := make(chan Result, 2)
+	wp := WorkerPool{
+		Concurrency: 2,
+		Clock:       clock,
+		TaskTimeout: time.Minute,
+		OnResult: func(task Task, result Result) {
+			fmt.Printf("Task %d finished at %s: err=%v\n", task.Id, clock.Now().Format("15:04"), result.Err)
+			results <- result
+		},
+	}
+	hang := func(done <-chan struct{}) (any, error) {
+		<-done
+		return nil, ErrTaskCancelled
+	}
+	report := func(done <-chan struct{}) (any, error) { return "report", nil }
+	start := time.Now()
+	wp.Start()
+
+	//advance only once the pool is waiting on the clock, so no timer is missed
+	advance := func(d time.Duration) {
+		for clock.Waiters() == 0 {
+			runtime.Gosched()
+		}
+		clock.Advance(d)
+		<-results
+	}
+
+	wp.Submit(Task{Id: 1, Work: hang})
+	advance(time.Minute)
+
+	wp.SubmitAfter(Task{Id: 2, Work: report}, time.Hour)
+	advance(time.Hour)
+
+	wp.Close()
+	fmt.Printf("%v of fake time took %v of real time\n",
+		clock.Now().Sub(time.Date(2025, time.January, 1, 9, 0, 0, 0, time.UTC)), time.Since(start).Round(time.Millisecond))
+}
+
+func WorkerPoolWithRunReport() {
+
+	//one task for every way a task can end, on a single worker so they run in order
+	flaky := 0
+	wait := func(d time.Duration, err error) func(done <-chan struct{}) (any, error) {
+		return func(done <-chan struct{}) (any, error) {
+			select {
+			case <-time.After(d):
+				return "ok", err
+			case <-done:
+				return nil, ErrTaskCancelled
+			}
+		}
+	}
+	wp := WorkerPool{
+		Tasks: []Task{
+			{Id: 1, Work: wait(0, nil)},
+			{Id: 2, Work: wait(0, errors.New("bad input"))},
+			{Id: 3, Work: func(done <-chan struct{}) (any, error) {
+				if flaky++; flaky == 1 {
+					return nil, errors.New("connection reset")
+				}
+				return "ok", nil
+			}},
+			{Id: 4, Work: wait(time.Hour, nil)},
+			{Id: 5, Work: wait(80*time.Millisecond, nil)},
+			{Id: 6, Work: wait(0, nil)},
+		},
+		Concurrency: 1,
+		MaxRetries:  1,
+		TaskTimeout: 100 * time.Millisecond,
+	}
+
+	//task 4 times out twice (200ms), the run is cancelled while task 5 is running
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	report := wp.RunWithReport(ctx)
+
+	for _, t := range report.Tasks {
+		fmt.Printf("Task %d: %s after %d attempt(s)\n", t.Id, t.Outcome, t.Attempts)
+	}
+	fmt.Printf("Counts: succeeded=%d failed=%d timed out=%d cancelled=%d, deadline exceeded: %v\n",
+		report.Counts[OutcomeSucceeded], report.Counts[OutcomeFailed], report.Counts[OutcomeTimedOut],
+		report.Counts[OutcomeCancelled], errors.Is(report.Err, context.DeadlineExceeded))
+}
+
+func RetryExample() {
+
+	//a flaky call that succeeds on its third attempt, retried without a pool
+	calls := 0
+	err := Retry(context.Background(), 5, JitteredBackoff(10*time.Millisecond, 50*time.Millisecond), func() error {
+		if calls++; calls < 3 {
+			return errors.New("service unavailable")
+		}
+		return nil
+	})
+	fmt.Printf("Flaky call: err=%v after %d call(s)\n", err, calls)
+
+	//a call that keeps failing reports the attempts and its last error
+	err = Retry(context.Background(), 3, nil, func() error { return errors.New("invalid credentials") })
+	var retryErr *RetryError
+	if errors.As(err, &retryErr) {
+		fmt.Printf("Broken call: %v (attempts=%d)\n", err, retryErr.Attempts)
+	}
+
+	//cancelling the context stops the retries during the backoff
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = Retry(ctx, 10, func(int) time.Duration { return time.Second }, func() error { return errors.New("timeout") })
+	fmt.Printf("Cancelled retries: %v, deadline exceeded: %v\n", err, errors.Is(err, context.DeadlineExceeded))
+}
+
+func WorkerPoolWithStructuredLogging() {
+
+	//print the pool's records as text, leaving out the attributes that vary from run to run
+	previous := Log
+	Log = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	defer func() { Log = previous }()
+
+	wp := WorkerPool{Concurrency: 1, Tasks: []Task{
+		{Id: 1, Work: func(done <-chan struct{}) (any, error) { return "ok", nil }},
+		{Id: 2, Work: func(done <-chan struct{}) (any, error) { return nil, errors.New("upstream unavailable") }},
+	}}
+	wp.Run()
+}
+
+func WorkerPoolWithSpscQueue() {
+
+	//a single worker fed by a single submitting goroutine: the lock-free queue fits
+	var processed atomic.Int32
+	wp := WorkerPool{Concurrency: 1, Queue: NewSpscQueue(4)}
+	wp.Start()
+	for i := range 10 {
+		wp.Submit(Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+			processed.Add(1)
+			return nil, nil
+		}})
+	}
+	wp.Close()
+	fmt.Printf("SPSC queue processed %d tasks\n", processed.Load())
+
+	//a second producer breaks the contract: the overlapping Push panics instead of corrupting the ring
+	q := NewSpscQueue(4)
+	var misuse atomic.Value
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					misuse.Store(r)
+				}
+			}()
+			for i := range 1000 {
+				q.Push(Task{Id: i})
+			}
+		}()
+	}
+	go func() {
+		for {
+			if _, ok := q.Pop(); !ok {
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	q.Close()
+	fmt.Println("Second producer:", misuse.Load())
+}
+
+// debugLog returns a logger printing every record of the pools as text on stdout, without the
+// timestamp, so demos can show the pool's debug output
+func debugLog() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
 }
@@ -0,0 +1,35 @@
+package main
+
+import "sync"
+
+/*
+Generic object pool for reusable scratch values.
+Heavy tasks often need a temporary buffer; borrowing one from an ObjectPool instead of
+allocating it per task keeps allocations (and GC work) low under high throughput.
+It is a thin typed wrapper over sync.Pool, so idle objects may be dropped by the GC at any time.
+*/
+
+// ObjectPool hands out reusable values of type T, creating new ones with its factory when empty.
+// T should be a pointer type (e.g. *bytes.Buffer): storing non-pointer values in the pool
+// allocates on every Put and defeats its purpose.
+type ObjectPool[T any] struct {
+	pool sync.Pool
+}
+
+// NewObjectPool creates an object pool that uses factory to create values when none is available
+func NewObjectPool[T any](factory func() T) *ObjectPool[T] {
+	return &ObjectPool[T]{
+		pool: sync.Pool{New: func() any { return factory() }},
+	}
+}
+
+// Get borrows a value from the pool. The value keeps whatever state it had when it was
+// returned, so callers should reset it before use.
+func (p *ObjectPool[T]) Get() T {
+	return p.pool.Get().(T)
+}
+
+// Put returns a value to the pool so a later Get can reuse it. The caller must not use it afterwards.
+func (p *ObjectPool[T]) Put(v T) {
+	p.pool.Put(v)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// newReportBuffer creates the 64KB scratch buffer of a task. Called through a variable, like a
+// factory handed to a pool, it is not inlined, so the buffer lives on the heap as in a real task.
+var newReportBuffer = func() *bytes.Buffer {
+	return bytes.NewBuffer(make([]byte, 0, 64*1024))
+}
+
+// renderReport fills a scratch buffer like a task rendering a 12KB report
+func renderReport(buf *bytes.Buffer) {
+	for range 1000 {
+		buf.WriteString("report line\n")
+	}
+}
+
+// BenchmarkScratchBufferAllocated allocates a fresh 64KB buffer per task
+func BenchmarkScratchBufferAllocated(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			renderReport(newReportBuffer())
+		}
+	})
+}
+
+// BenchmarkScratchBufferPooled borrows the 64KB buffer of each task from an ObjectPool
+func BenchmarkScratchBufferPooled(b *testing.B) {
+	buffers := NewObjectPool(newReportBuffer)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			buf := buffers.Get()
+			buf.Reset()
+			renderReport(buf)
+			buffers.Put(buf)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package main
+
+import "hash/fnv"
+
+/*
+Custom task-to-worker routing for the WorkerPool.
+By default only tasks with an affinity key are pinned to a worker (key modulo the number of
+workers) and the rest go to the shared channel. A Partitioner replaces that mapping, e.g. to
+hash a string key, so per-worker caches and ordered processing within a partition work for any
+notion of key. KeyPartitioner builds one from a key function.
+*/
+
+// KeyPartitioner returns a Partitioner that pins tasks with the same key to the same worker by
+// hashing the key (FNV-1a). Tasks with an empty key go to the shared channel.
+func KeyPartitioner(key func(Task) string) func(Task, int) int {
+	return func(task Task, numWorkers int) int {
+		k := key(task)
+		if k == "" {
+			return -1
+		}
+		h := fnv.New32a()
+		h.Write([]byte(k))
+		return int(h.Sum32() % uint32(numWorkers))
+	}
+}
+
+// route returns the index of the worker a task is pinned to among n workers, or -1 if it goes
+// to the shared channel. Hedge duplicates are never pinned, so they can run on a free worker.
+func (wp *WorkerPool) route(task Task, n int) int {
+	if task.hedge != nil {
+		return -1
+	}
+	if wp.Partitioner != nil {
+		idx := wp.Partitioner(task, n)
+		if idx < 0 {
+			return -1
+		}
+		return idx % n
+	}
+	if key := task.AffinityKey(); key != 0 {
+		return affinityWorker(key, n)
+	}
+	return -1
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestPartitionerPinsTasks runs tasks keyed by account through a Partitioner and checks from the
+// "task finished" records that tasks with the same key ran on the same worker, the one the
+// Partitioner chose, in submission order
+func TestPartitionerPinsTasks(t *testing.T) {
+	accounts := []string{"alice", "bob", "carol", "alice", "bob", "alice", "dave", "carol", "erin", "alice", "dave", "bob"}
+	key := func(task Task) string { return accounts[task.Id-1] }
+	tests := []struct {
+		name        string
+		concurrency int
+		partitioner func(Task, int) int
+		wantWorker  func(task Task, n int) int // Worker expected for a task, -1 for any
+	}{
+		{"key partitioner", 3, KeyPartitioner(key), KeyPartitioner(key)},
+		{"single worker", 1, KeyPartitioner(key), func(Task, int) int { return 0 }},
+		{"more workers than keys", 8, KeyPartitioner(key), KeyPartitioner(key)},
+		{"index wraps around", 3, func(task Task, n int) int { return len(key(task)) + 10 }, func(task Task, n int) int { return (len(key(task)) + 10) % n }},
+		{"empty keys shared", 3, KeyPartitioner(func(task Task) string {
+			if task.Id%2 == 0 {
+				return ""
+			}
+			return key(task)
+		}), func(task Task, n int) int {
+			if task.Id%2 == 0 {
+				return -1
+			}
+			return KeyPartitioner(key)(task, n)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			var logMu sync.Mutex
+			var records []record
+			previous := Log
+			Log = slog.New(recordingHandler{level: slog.LevelInfo, mu: &logMu, records: &records})
+			defer func() { Log = previous }()
+
+			var mu sync.Mutex
+			applied := map[string][]int{}
+			wp := &WorkerPool{Concurrency: tt.concurrency, Partitioner: tt.partitioner}
+			for i := range accounts {
+				wp.Tasks = append(wp.Tasks, Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					applied[accounts[i]] = append(applied[accounts[i]], i+1)
+					return nil, nil
+				}})
+			}
+			wp.Run()
+
+			workerOf := map[int]int{}
+			for _, r := range records {
+				if r.msg == "task finished" {
+					workerOf[int(r.attrs["task_id"].Int64())] = int(r.attrs["worker_id"].Int64())
+				}
+			}
+			if len(workerOf) != len(accounts) {
+				t.Fatalf("%d tasks finished, want %d", len(workerOf), len(accounts))
+			}
+			workersOfKey := map[string][]int{}
+			for _, task := range wp.Tasks {
+				want := tt.wantWorker(task, tt.concurrency)
+				if want < 0 {
+					continue
+				}
+				if got := workerOf[task.Id]; got != want {
+					t.Errorf("task %d (%s) ran on worker %d, want %d", task.Id, key(task), got, want)
+				}
+				if w := workerOf[task.Id]; !slices.Contains(workersOfKey[key(task)], w) {
+					workersOfKey[key(task)] = append(workersOfKey[key(task)], w)
+				}
+			}
+			for k, workers := range workersOfKey {
+				if len(workers) != 1 {
+					t.Errorf("key %s ran on workers %v, want one", k, workers)
+				}
+			}
+			for k, ids := range applied {
+				pinned := slices.DeleteFunc(ids, func(id int) bool { return tt.wantWorker(wp.Tasks[id-1], tt.concurrency) < 0 })
+				if !slices.IsSorted(pinned) {
+					t.Errorf("key %s applied in order %v, want submission order", k, pinned)
+				}
+			}
+		})
+	}
+}
+
+// TestKeyPartitioner checks that KeyPartitioner maps equal keys to the same index within range
+// and sends empty keys to the shared channel
+func TestKeyPartitioner(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 7, 64} {
+		t.Run(fmt.Sprintf("%d workers", n), func(t *testing.T) {
+			keys := []string{"alice", "bob", "carol", "dave", ""}
+			p := KeyPartitioner(func(task Task) string { return keys[task.Id%len(keys)] })
+			for id := range 50 {
+				task := Task{Id: id}
+				idx := p(task, n)
+				if keys[id%len(keys)] == "" {
+					if idx != -1 {
+						t.Errorf("empty key mapped to %d, want -1", idx)
+					}
+					continue
+				}
+				if idx < 0 || idx >= n {
+					t.Errorf("key %q mapped to %d, want within [0, %d)", keys[id%len(keys)], idx, n)
+				}
+				if again := p(Task{Id: id + len(keys)}, n); again != idx {
+					t.Errorf("key %q mapped to %d and %d", keys[id%len(keys)], idx, again)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+/*
+Tiered processing: one WorkerPool feeding another.
+Pipe turns the successful results of a pool into tasks for a second pool, e.g. images resized
+by the first pool are uploaded by the second. The downstream pool runs in streaming mode for
+as long as the upstream one: it is started with it, closed once the upstream batch drained,
+and the two share cancellation.
+*/
+
+// pipeTarget is the downstream pool a pool's results are piped into
+type pipeTarget struct {
+	dst       *WorkerPool
+	transform func(Result) Task
+}
+
+// Pipe feeds the successful results of this pool into dst: transform turns each of them into a
+// task that is submitted to dst from the worker that produced the result, so a full dst slows
+// this pool down. Failed results are not piped. Call it before running this pool; dst is started
+// together with it and closed once this pool drained, so Run returns after both stages finished.
+// Cancelling either pool (or this pool's context or Deadline) cancels both.
+func (wp *WorkerPool) Pipe(dst *WorkerPool, transform func(Result) Task) {
+	wp.pipe = &pipeTarget{dst: dst, transform: transform}
+	dst.upstream = wp
+}
+
+// forward submits the task derived from a successful result to the downstream pool
+func (wp *WorkerPool) forward(result Result) {
+	if wp.pipe == nil || result.Err != nil {
+		return
+	}
+	wp.pipe.dst.Submit(wp.pipe.transform(result))
+}
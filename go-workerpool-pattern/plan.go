@@ -0,0 +1,61 @@
+package main
+
+import "slices"
+
+/*
+Dry-run planning for the WorkerPool.
+Plan reports what Run would do with the current configuration (dispatch order, the worker an
+affinity task is pinned to and each task's cost) without starting workers or running any task,
+so scheduling settings can be checked before a real run.
+*/
+
+// AnyWorker is the TaskPlan.Worker of a task that goes to whichever worker is free first
+const AnyWorker = -1
+
+// TaskPlan describes how Run would schedule one task
+type TaskPlan struct {
+	Position int // Dispatch order, starting at 0
+	Id       int // Id of the task
+	Worker   int // Index of the worker the task is pinned to, AnyWorker if it is load-balanced
+	Cost     int // Cost counted against CostBudget
+}
+
+// Plan returns how Run would dispatch Tasks, without executing anything or changing the pool.
+// Tasks are dispatched in submission order, newest first in Stack mode or highest priority first
+// in Prioritize mode (assuming the batch is queued faster than the workers take tasks, so aging
+// plays no part); a custom Queue is assumed to keep submission order. Affinity tasks are pinned
+// to the worker owning their key (or the one chosen by the Partitioner), and in Deterministic
+// mode every task runs on the single worker 0. Tasks that completed before a restart (see
+// LoadState) are left out.
+func (wp *WorkerPool) Plan() []TaskPlan {
+	workers := wp.workerCount()
+	queued := (wp.Stack || wp.Prioritize || wp.Queue != nil) && !wp.Deterministic
+	order := wp.Tasks
+	switch {
+	case !queued, wp.Queue != nil:
+	case wp.Prioritize:
+		order = priorityOrder(wp.Tasks)
+	default:
+		order = slices.Clone(wp.Tasks)
+		slices.Reverse(order)
+	}
+
+	plans := make([]TaskPlan, 0, len(order))
+	for _, task := range order {
+		if wp.resumed[task.Id] {
+			continue
+		}
+
+		worker := AnyWorker
+		switch {
+		case wp.Deterministic:
+			worker = 0
+		case !queued:
+			if idx := wp.route(task, workers); idx >= 0 {
+				worker = idx
+			}
+		}
+		plans = append(plans, TaskPlan{Position: len(plans), Id: task.Id, Worker: worker, Cost: task.Cost()})
+	}
+	return plans
+}
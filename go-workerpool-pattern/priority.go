@@ -0,0 +1,39 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+)
+
+/*
+Priority dispatch with aging for the WorkerPool.
+With Prioritize set, queued tasks are kept in the bounded task stack but popped highest
+Task.Priority first, oldest first within a priority. Strict priorities can starve low-priority
+tasks forever under a steady stream of urgent ones, so AgingRate lets a waiting task gain
+priority over time until it outranks the newcomers.
+Aging is linear, so a task's effective priority at time t is
+	Priority + AgingRate * (t - queuedAt) = (Priority - AgingRate * queuedAt) + AgingRate * t
+The last term is the same for every queued task, so the order only depends on the rank in
+brackets, computed once when the task is queued: no re-sorting is needed while tasks wait.
+*/
+
+// newPriorityQueue creates a task queue holding at most capacity tasks that pops the task with
+// the highest effective priority, the one queued first among equals. agingRate is the priority
+// a task gains per second of waiting, measured on clock.
+func newPriorityQueue(capacity int, clock Clock, agingRate float64) *taskStack {
+	s := newTaskStack(capacity)
+	epoch := clock.Now()
+	s.rank = func(task Task) float64 {
+		waited := clock.Now().Sub(epoch)
+		return float64(task.Priority) - agingRate*waited.Seconds()
+	}
+	return s
+}
+
+// priorityOrder returns the order in which the queue pops tasks queued at the same moment:
+// highest priority first, submission order among equals
+func priorityOrder(tasks []Task) []Task {
+	ordered := slices.Clone(tasks)
+	slices.SortStableFunc(ordered, func(a, b Task) int { return cmp.Compare(b.Priority, a.Priority) })
+	return ordered
+}
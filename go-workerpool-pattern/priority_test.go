@@ -0,0 +1,72 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestPriorityOrder checks the dispatch order of a prioritized batch: highest priority first,
+// submission order among equal priorities
+func TestPriorityOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		priorities []int // Priority of the tasks with Ids 1, 2, ...
+		want       []int
+	}{
+		{"mixed priorities", []int{0, 5, 1, 5}, []int{2, 4, 3, 1}},
+		{"equal priorities keep submission order", []int{3, 3, 3}, []int{1, 2, 3}},
+		{"negative priorities last", []int{-1, 0, 2}, []int{3, 2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wp := &WorkerPool{Concurrency: 1, Prioritize: true}
+			for i, p := range tt.priorities {
+				wp.Tasks = append(wp.Tasks, Task{Id: i + 1, Priority: p})
+			}
+			var got []int
+			for _, plan := range wp.Plan() {
+				got = append(got, plan.Id)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("dispatch order %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAgingPreventsStarvation queues a priority 0 task, then has a priority 5 task arrive every
+// 100ms of fake time while a single worker takes one task per arrival, and checks after which
+// arrival the low-priority task runs: never without aging, sooner the higher the AgingRate
+func TestAgingPreventsStarvation(t *testing.T) {
+	const arrivals = 100
+	tests := []struct {
+		name      string
+		agingRate float64
+		wantRanAt int // Arrival after which the low-priority task is popped, 0 for never
+	}{
+		{"strict priorities", 0, 0},
+		{"slow aging", 4, 13},               // 0.4 priority per arrival: ahead after 12.5
+		{"aging just short of a tie", 9, 6}, // still 0.5 behind the fifth arrival
+		{"aging to a tie", 10, 5},           // level with the fifth arrival, and queued before it
+		{"fast aging", 40, 2},               // ahead of the second arrival already
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := NewFakeClock(time.Unix(0, 0))
+			queue := newPriorityQueue(2, clock, tt.agingRate)
+			queue.Push(Task{Id: 0, Priority: 0})
+			ranAt := 0
+			for i := 1; i <= arrivals && ranAt == 0; i++ {
+				clock.Advance(100 * time.Millisecond)
+				queue.Push(Task{Id: i, Priority: 5})
+				if task, _ := queue.Pop(); task.Id == 0 {
+					ranAt = i
+				}
+			}
+			if ranAt != tt.wantRanAt {
+				t.Errorf("low-priority task ran after arrival %d, want %d (0 for still waiting after %d)", ranAt, tt.wantRanAt, arrivals)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import "sync"
+
+/*
+Progress reporting of the WorkerPool.
+Workers finish tasks concurrently, so the count is kept under a mutex and OnProgress is called
+while holding it: calls are serialized and every call sees a count one higher than the previous.
+*/
+
+// progressTracker counts completed tasks and calls OnProgress for each of them
+type progressTracker struct {
+	mu    sync.Mutex
+	done  int
+	total int  // Number of tasks in the batch, only meaningful when known is set
+	known bool // Whether the batch size is known (Run), false in streaming mode
+}
+
+// setTotal records the size of a batch run with Run or RunWithContext
+func (p *progressTracker) setTotal(total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total, p.known = total, true
+}
+
+// count returns the number of completed tasks
+func (p *progressTracker) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.done
+}
+
+// grow adds a task that joined a running batch, e.g. a follow-up task
+func (p *progressTracker) grow() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total++
+}
+
+// completed counts a completed task and reports it, passing -1 as total when it is unknown
+func (p *progressTracker) completed(onProgress func(done, total int)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	if onProgress == nil {
+		return
+	}
+	total := -1
+	if p.known {
+		total = p.total
+	}
+	onProgress(p.done, total)
+}
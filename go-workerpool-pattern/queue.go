@@ -0,0 +1,59 @@
+package main
+
+import "time"
+
+/*
+Pluggable task queues for the WorkerPool.
+By default queued tasks wait in the task channel (and the per-worker affinity channels). A
+Queue replaces that storage with any ordering or backing store (FIFO, LIFO, priority, a queue
+persisted to disk): the pool pushes every dispatched task into it and the workers pop from it.
+Stack and Prioritize mode are built-in Queues; NewFIFOQueue and NewLIFOQueue expose the
+built-in orderings for reuse.
+*/
+
+// Queue stores the tasks waiting for a worker. Implementations must be safe for concurrent use
+// by the submitting goroutines and all workers.
+type Queue interface {
+	Push(task Task)    // Adds a task, blocking while the queue is full
+	Pop() (Task, bool) // Removes the next task, blocking until one is available; false once closed and empty
+	Len() int          // Number of queued tasks
+	Close()            // Wakes the workers blocked in Pop, called once the pool has drained; no Push follows
+}
+
+// boundedQueue is implemented by queues whose Push can give up, used by TrySubmit
+type boundedQueue interface {
+	pushWithin(task Task, timeout <-chan time.Time) bool
+}
+
+// chanQueue is a FIFO Queue backed by a buffered channel
+type chanQueue chan Task
+
+// NewFIFOQueue returns a first-in first-out Queue holding up to capacity tasks, backed by a
+// channel like the pool's default task channel
+func NewFIFOQueue(capacity int) Queue {
+	return make(chanQueue, capacity)
+}
+
+func (q chanQueue) Push(task Task) { q <- task }
+func (q chanQueue) Len() int       { return len(q) }
+func (q chanQueue) Close()         { close(q) }
+
+func (q chanQueue) Pop() (Task, bool) {
+	task, ok := <-q
+	return task, ok
+}
+
+func (q chanQueue) pushWithin(task Task, timeout <-chan time.Time) bool {
+	select {
+	case q <- task:
+		return true
+	case <-timeout:
+		return false
+	}
+}
+
+// NewLIFOQueue returns a last-in first-out Queue holding up to capacity tasks, the queue used in
+// Stack mode
+func NewLIFOQueue(capacity int) Queue {
+	return newTaskStack(capacity)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// sliceQueue is a Queue implemented outside the pool, an unbounded FIFO on a slice, recording
+// whether the pool closed it
+type sliceQueue struct {
+	mu     sync.Mutex
+	ready  *sync.Cond
+	tasks  []Task
+	closed bool
+}
+
+func newSliceQueue() *sliceQueue {
+	q := &sliceQueue{}
+	q.ready = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *sliceQueue) Push(task Task) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.tasks = append(q.tasks, task)
+	q.ready.Signal()
+}
+
+func (q *sliceQueue) Pop() (Task, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.tasks) == 0 && !q.closed {
+		q.ready.Wait()
+	}
+	if len(q.tasks) == 0 {
+		return Task{}, false
+	}
+	task := q.tasks[0]
+	q.tasks = q.tasks[1:]
+	return task, true
+}
+
+func (q *sliceQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.tasks)
+}
+
+func (q *sliceQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.ready.Broadcast()
+}
+
+// TestPoolWithQueue runs the pool on the built-in FIFO and LIFO queues and on a Queue
+// implemented outside the pool, and checks the order a single worker takes tasks queued while
+// the pool is paused, and that a batch on several workers processes every task exactly once
+func TestPoolWithQueue(t *testing.T) {
+	tests := []struct {
+		name      string
+		queue     func() Queue
+		wantOrder []int
+	}{
+		{"FIFO", func() Queue { return NewFIFOQueue(8) }, []int{1, 2, 3, 4, 5}},
+		{"LIFO", func() Queue { return NewLIFOQueue(8) }, []int{5, 4, 3, 2, 1}},
+		{"custom queue", func() Queue { return newSliceQueue() }, []int{1, 2, 3, 4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+"/order", func(t *testing.T) {
+			testutil.LeakCheck(t)
+			var mu sync.Mutex
+			var order []int
+			queue := tt.queue()
+			wp := &WorkerPool{Concurrency: 1, Queue: queue}
+			wp.Pause()
+			if err := wp.Start(); err != nil {
+				t.Fatal(err)
+			}
+			for i := range 5 {
+				if err := wp.Submit(Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+					mu.Lock()
+					order = append(order, i+1)
+					mu.Unlock()
+					return nil, nil
+				}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if n := queue.Len(); n != 5 {
+				t.Errorf("queue holds %d tasks while paused, want 5", n)
+			}
+			wp.Resume()
+			wp.Close()
+
+			if !slices.Equal(order, tt.wantOrder) {
+				t.Errorf("processed %v, want %v", order, tt.wantOrder)
+			}
+			if q, ok := queue.(*sliceQueue); ok && !q.closed {
+				t.Error("pool did not close the custom queue")
+			}
+		})
+		t.Run(tt.name+"/batch", func(t *testing.T) {
+			testutil.LeakCheck(t)
+			const tasks = 50
+			counts := make([]int, tasks+1)
+			var mu sync.Mutex
+			wp := &WorkerPool{Concurrency: 4, Queue: tt.queue()}
+			for i := 1; i <= tasks; i++ {
+				wp.Tasks = append(wp.Tasks, Task{Id: i, Work: func(done <-chan struct{}) (any, error) {
+					mu.Lock()
+					counts[i]++
+					mu.Unlock()
+					return nil, nil
+				}})
+			}
+			if err := wp.Run(); err != nil {
+				t.Fatal(err)
+			}
+			for id := 1; id <= tasks; id++ {
+				if counts[id] != 1 {
+					t.Errorf("task %d processed %d times, want once", id, counts[id])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import "log/slog"
+
+/*
+Maximum queue age of the WorkerPool.
+For real-time workloads (quotes, sensor readings, UI refreshes) a task that waited too long is
+worthless: processing it only delays the fresh work behind it. With MaxQueueAge set, the pool
+stamps every task when it enters the queue and the worker that dequeues it checks its age. A
+task that waited longer is not processed but reported with ErrTaskExpired.
+*/
+
+// stampQueued records when the task entered the queue, if MaxQueueAge is set
+func (wp *WorkerPool) stampQueued(task Task) Task {
+	if wp.MaxQueueAge > 0 {
+		task.queuedAt = wp.clock().Now()
+	}
+	return task
+}
+
+// expired reports whether a dequeued task waited longer than MaxQueueAge, and counts and logs
+// it if so
+func (wp *WorkerPool) expired(task Task) bool {
+	if wp.MaxQueueAge <= 0 || task.queuedAt.IsZero() {
+		return false
+	}
+	waited := wp.clock().Now().Sub(task.queuedAt)
+	if waited <= wp.MaxQueueAge {
+		return false
+	}
+	wp.counters.expired.Add(1)
+	Log.LogAttrs(wp.taskContext(task), slog.LevelWarn, "task expired",
+		slog.Int("task_id", task.Id), slog.Duration("waited", waited))
+	return true
+}
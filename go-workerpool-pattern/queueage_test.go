@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestMaxQueueAge keeps the single worker busy while a task waits in the queue for some fake
+// time, and checks that it is reported with ErrTaskExpired only if it waited longer than
+// MaxQueueAge, while a fresh task is processed either way
+func TestMaxQueueAge(t *testing.T) {
+	tests := []struct {
+		name        string
+		waited      time.Duration
+		wantExpired bool
+	}{
+		{"fresh task", 0, false},
+		{"waited less than MaxQueueAge", 500 * time.Millisecond, false},
+		{"waited exactly MaxQueueAge", time.Second, false},
+		{"waited longer than MaxQueueAge", 2 * time.Second, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			clock := NewFakeClock(time.Unix(0, 0))
+			var mu sync.Mutex
+			errs := map[int]error{}
+			processed := map[int]bool{}
+			wp := &WorkerPool{Concurrency: 1, Clock: clock, MaxQueueAge: time.Second,
+				OnResult: func(task Task, result Result) {
+					mu.Lock()
+					errs[task.Id] = result.Err
+					mu.Unlock()
+				}}
+			if err := wp.Start(); err != nil {
+				t.Fatal(err)
+			}
+			work := func(id int) func(done <-chan struct{}) (any, error) {
+				return func(done <-chan struct{}) (any, error) {
+					mu.Lock()
+					processed[id] = true
+					mu.Unlock()
+					return nil, nil
+				}
+			}
+
+			// task 1 holds the only worker while task 2 waits in the queue
+			started, release := make(chan struct{}), make(chan struct{})
+			if err := wp.Submit(Task{Id: 1, Work: func(done <-chan struct{}) (any, error) {
+				close(started)
+				<-release
+				return nil, nil
+			}}); err != nil {
+				t.Fatal(err)
+			}
+			<-started
+			if err := wp.Submit(Task{Id: 2, Work: work(2)}); err != nil {
+				t.Fatal(err)
+			}
+			clock.Advance(tt.waited)
+			close(release)
+			if err := wp.Submit(Task{Id: 3, Work: work(3)}); err != nil {
+				t.Fatal(err)
+			}
+			wp.Close()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if got := errors.Is(errs[2], ErrTaskExpired); got != tt.wantExpired || processed[2] == tt.wantExpired {
+				t.Errorf("task 2 waited %v: err %v, processed %v; want expired %v", tt.waited, errs[2], processed[2], tt.wantExpired)
+			}
+			if errs[3] != nil || !processed[3] {
+				t.Errorf("fresh task 3: err %v, processed %v; want processed", errs[3], processed[3])
+			}
+			var wantCount int64
+			if tt.wantExpired {
+				wantCount = 1
+			}
+			if got := wp.Stats().Expired; got != wantCount {
+				t.Errorf("Stats().Expired = %d, want %d", got, wantCount)
+			}
+		})
+	}
+}
+
+// TestMaxQueueAgeBatch runs a batch on one worker whose first task holds it for some fake time,
+// and checks that the tasks queued behind it are expired and listed by Unfinished only if the
+// hold exceeded MaxQueueAge, while a delayed task is stamped when it becomes due rather than
+// when it was submitted
+func TestMaxQueueAgeBatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		hold           time.Duration // Fake time task 1 holds the only worker
+		wantUnfinished []int
+	}{
+		{"queued within MaxQueueAge", 500 * time.Millisecond, []int{}},
+		{"starved past MaxQueueAge", 2 * time.Second, []int{2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			clock := NewFakeClock(time.Unix(0, 0))
+			var mu sync.Mutex
+			processed := map[int]bool{}
+			quick := func(id int) Task {
+				return Task{Id: id, Work: func(done <-chan struct{}) (any, error) {
+					mu.Lock()
+					processed[id] = true
+					mu.Unlock()
+					return nil, nil
+				}}
+			}
+			wp := &WorkerPool{Concurrency: 1, Clock: clock, MaxQueueAge: time.Second, Tasks: []Task{
+				{Id: 1, Work: func(done <-chan struct{}) (any, error) { <-clock.After(tt.hold); return nil, nil }},
+				quick(2), quick(3),
+			}}
+			errc := make(chan error, 1)
+			go func() { errc <- wp.Run() }()
+			for clock.Waiters() == 0 {
+				time.Sleep(time.Millisecond) // until task 1 holds the worker
+			}
+			clock.Advance(tt.hold)
+			if err := <-errc; err != nil {
+				t.Fatal(err)
+			}
+
+			if got := wp.Unfinished(); !slices.Equal(got, tt.wantUnfinished) {
+				t.Errorf("Unfinished() = %v, want %v", got, tt.wantUnfinished)
+			}
+			for _, id := range tt.wantUnfinished {
+				if processed[id] {
+					t.Errorf("expired task %d was processed", id)
+				}
+			}
+			if got := wp.Stats().Expired; got != int64(len(tt.wantUnfinished)) {
+				t.Errorf("Stats().Expired = %d, want %d", got, len(tt.wantUnfinished))
+			}
+		})
+	}
+
+	t.Run("delayed task", func(t *testing.T) {
+		testutil.LeakCheck(t)
+		clock := NewFakeClock(time.Unix(0, 0))
+		var errs []error
+		wp := &WorkerPool{Concurrency: 1, Clock: clock, MaxQueueAge: time.Second,
+			OnResult: func(task Task, result Result) { errs = append(errs, result.Err) }}
+		if err := wp.Start(); err != nil {
+			t.Fatal(err)
+		}
+		// submitted two MaxQueueAges before it is due, but it only enters the queue then
+		if err := wp.SubmitAfter(Task{Id: 1, Work: func(done <-chan struct{}) (any, error) { return nil, nil }}, 2*time.Second); err != nil {
+			t.Fatal(err)
+		}
+		advanceUntil(t, clock, time.Second, func() bool { return wp.Stats().Processed == 1 })
+		wp.Close()
+		if len(errs) != 1 || errs[0] != nil || wp.Stats().Expired != 0 {
+			t.Errorf("delayed task finished with %v and %d expired, want processed", errs, wp.Stats().Expired)
+		}
+	})
+}
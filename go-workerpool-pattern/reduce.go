@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+Result aggregation for the WorkerPool.
+Reduce folds the results into a single accumulated value as the tasks complete, e.g. a sum
+or a count per error kind, so a batch that only needs an aggregate does not buffer every
+result. The reducer is serialized, so it can update the accumulator without locking.
+*/
+
+// Reduce executes all tasks of wp like Run and folds every result into an accumulator starting
+// at initial, returning the final value. reducer is called once per task in completion order,
+// never concurrently, so it needs no synchronization of its own; failed and cancelled tasks
+// are passed too (check Result.Err). A slow reducer holds up the worker that called it.
+func Reduce[R any](wp *WorkerPool, initial R, reducer func(acc R, result Result) R) R {
+	var mu sync.Mutex
+	acc := initial
+	wp.collect = func(_ Task, result Result, _ int) {
+		mu.Lock()
+		defer mu.Unlock()
+		acc = reducer(acc, result)
+	}
+	_ = wp.runBatch(context.Background())
+
+	// Run returned after every worker finished, but take the lock for the memory ordering
+	mu.Lock()
+	defer mu.Unlock()
+	return acc
+}
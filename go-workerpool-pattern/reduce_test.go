@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestReduceConcurrentSum sums task values with Reduce on many workers and checks the total, that
+// every result was folded once, and that the reducer never ran concurrently with itself
+func TestReduceConcurrentSum(t *testing.T) {
+	type totals struct {
+		Sum, Failed, Calls int
+	}
+	tests := []struct {
+		name        string
+		concurrency int
+		tasks       int
+		failEvery   int // Every failEvery-th task fails, 0 for none
+		want        totals
+	}{
+		{"one worker", 1, 100, 0, totals{Sum: 5050, Calls: 100}},
+		{"many workers", 16, 1000, 0, totals{Sum: 500500, Calls: 1000}},
+		{"with failures", 8, 100, 25, totals{Sum: 5050 - 25 - 50 - 75 - 100, Failed: 4, Calls: 100}},
+		{"no tasks", 4, 0, 0, totals{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			wp := &WorkerPool{Concurrency: tt.concurrency}
+			for i := 1; i <= tt.tasks; i++ {
+				wp.Tasks = append(wp.Tasks, Task{Id: i, Work: func(done <-chan struct{}) (any, error) {
+					if tt.failEvery > 0 && i%tt.failEvery == 0 {
+						return nil, fmt.Errorf("document %d is unreadable", i)
+					}
+					return i, nil
+				}})
+			}
+
+			var inside, overlaps atomic.Int32
+			got := Reduce(wp, totals{}, func(acc totals, result Result) totals {
+				if inside.Add(1) > 1 {
+					overlaps.Add(1)
+				}
+				defer inside.Add(-1)
+				runtime.Gosched() // widen the window for a concurrent call
+				acc.Calls++
+				if result.Err != nil {
+					acc.Failed++
+					return acc
+				}
+				acc.Sum += result.Value.(int)
+				return acc
+			})
+
+			if got != tt.want {
+				t.Errorf("Reduce() = %+v, want %+v", got, tt.want)
+			}
+			if n := overlaps.Load(); n > 0 {
+				t.Errorf("reducer ran concurrently %d times", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+/*
+Structured report of a WorkerPool run.
+RunWithReport runs the batch and returns a single RunReport: the outcome of every task
+(succeeded, failed, timed out, panicked or cancelled) with its attempts and error, the count of
+each outcome and the overall error, so callers do not have to piece the result together from
+OnResult, Stats, Completed and the error of RunWithContext.
+*/
+
+// Outcome classifies how a task ended
+type Outcome int
+
+const (
+	OutcomeSucceeded Outcome = iota // The task returned without error
+	OutcomeFailed                   // The last attempt returned an error
+	OutcomeTimedOut                 // The last attempt exceeded TaskTimeout, or the task its CumulativeTimeout
+	OutcomePanicked                 // The last attempt panicked and the panic was recovered
+	OutcomeCancelled                // The pool was cancelled before or while the task ran
+)
+
+// String returns a readable name of the outcome
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeFailed:
+		return "failed"
+	case OutcomeTimedOut:
+		return "timed out"
+	case OutcomePanicked:
+		return "panicked"
+	case OutcomeCancelled:
+		return "cancelled"
+	default:
+		return "succeeded"
+	}
+}
+
+// outcomeOf classifies the final error of a task
+func outcomeOf(err error) Outcome {
+	switch {
+	case err == nil:
+		return OutcomeSucceeded
+	case errors.Is(err, ErrTaskPanicked):
+		return OutcomePanicked
+	case errors.Is(err, ErrTaskCancelled):
+		return OutcomeCancelled
+	case errors.Is(err, ErrTaskTimeout):
+		return OutcomeTimedOut
+	default:
+		return OutcomeFailed
+	}
+}
+
+// TaskReport is the outcome of one task of a run
+type TaskReport struct {
+	Id       int     // Id of the task
+	Outcome  Outcome // How the task ended
+	Attempts int     // Number of attempts made, 0 if the task was skipped
+	Value    any     // Value produced by the last attempt, nil for tasks without output
+	Err      error   // Final error of the task (a *TaskError), nil if it succeeded
+}
+
+// RunReport bundles everything a run produced
+type RunReport struct {
+	Tasks   []TaskReport    // One entry per task, in the order of Tasks
+	Counts  map[Outcome]int // Number of tasks per outcome, every outcome is present
+	Elapsed time.Duration   // Time from start to the end of the run, measured on the pool's Clock
+
+	// Err is nil if every task succeeded. Otherwise it joins the cancellation error of the run
+	// (context.Canceled or context.DeadlineExceeded) and the errors of the tasks that did not
+	// succeed, in task order. Once the run is cancelled the errors of the cancelled tasks are
+	// left out, as the cancellation error already explains them.
+	Err error
+}
+
+// Succeeded reports whether every task succeeded
+func (r *RunReport) Succeeded() bool {
+	return r.Err == nil
+}
+
+// RunWithReport executes all tasks like RunWithContext and returns a RunReport covering every
+// task, whichever way the run ended: completion, retries, timeouts, panics or cancellation.
+// Tasks with duplicate Ids get their entries in completion order. Tasks completed before a
+// restart (see LoadState) are not part of the report.
+func (wp *WorkerPool) RunWithReport(ctx context.Context) RunReport {
+	report := RunReport{Counts: make(map[Outcome]int)}
+	for o := OutcomeSucceeded; o <= OutcomeCancelled; o++ {
+		report.Counts[o] = 0
+	}
+
+	// reserve a slot per task so the report follows the order of Tasks, a task the workers
+	// never reported on counts as cancelled
+	slots := make(map[int][]int)
+	for _, task := range wp.Tasks {
+		if wp.resumed[task.Id] {
+			continue
+		}
+		slots[task.Id] = append(slots[task.Id], len(report.Tasks))
+		report.Tasks = append(report.Tasks, TaskReport{Id: task.Id, Outcome: OutcomeCancelled})
+	}
+
+	var mu sync.Mutex
+	wp.collect = func(task Task, result Result, attempts int) {
+		mu.Lock()
+		defer mu.Unlock()
+		free := slots[task.Id]
+		if len(free) == 0 {
+			return
+		}
+		slots[task.Id] = free[1:]
+		report.Tasks[free[0]] = TaskReport{
+			Id:       task.Id,
+			Outcome:  outcomeOf(result.Err),
+			Attempts: attempts,
+			Value:    result.Value,
+			Err:      result.Err,
+		}
+	}
+
+	start := wp.clock().Now()
+	runErr := wp.runBatch(ctx)
+	report.Elapsed = wp.clock().Now().Sub(start)
+
+	errs := []error{runErr}
+	for _, t := range report.Tasks {
+		report.Counts[t.Outcome]++
+		if t.Err != nil && (runErr == nil || t.Outcome != OutcomeCancelled) {
+			errs = append(errs, t.Err)
+		}
+	}
+	report.Err = errors.Join(errs...)
+	return report
+}
@@ -0,0 +1,65 @@
+package main
+
+import "sync"
+
+/*
+Per-resource serialization for the multi-type worker pool.
+Tasks exposing a ResourceKey never run concurrently with another task of the same key,
+e.g. two emails to the same address, while tasks of different keys still run in parallel.
+A worker that receives a task whose key is busy does not block: it parks the task in the
+key's queue and moves on, and the worker holding the key runs the parked tasks in order.
+*/
+
+// ResourceTask is implemented by tasks that must not run concurrently with other tasks
+// sharing the same resource key. An empty key means the task has no such constraint.
+type ResourceTask interface {
+	ResourceKey() string
+}
+
+// ResourceKey returns the address of the email, so emails to the same address are sent one at a time
+func (e *EmailTask) ResourceKey() string {
+	return e.EmailId
+}
+
+// resourceKeyOf returns the resource key of a task, or "" if it has none
+func resourceKeyOf(task MultiTask) string {
+	if rt, ok := taskAs[ResourceTask](task); ok {
+		return rt.ResourceKey()
+	}
+	return ""
+}
+
+// keySerializer tracks the busy resource keys and the tasks parked behind them
+type keySerializer struct {
+	mu     sync.Mutex
+	parked map[string][]MultiTask // Busy keys mapped to the tasks waiting for them, in arrival order
+}
+
+// acquire marks the key busy and returns true, or parks the task behind the current holder
+// and returns false if the key is already busy
+func (ks *keySerializer) acquire(key string, task MultiTask) bool {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.parked == nil {
+		ks.parked = make(map[string][]MultiTask)
+	}
+	if queue, busy := ks.parked[key]; busy {
+		ks.parked[key] = append(queue, task)
+		return false
+	}
+	ks.parked[key] = nil
+	return true
+}
+
+// next hands the holder of a key the next parked task, or releases the key if none is left
+func (ks *keySerializer) next(key string) (MultiTask, bool) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	queue := ks.parked[key]
+	if len(queue) == 0 {
+		delete(ks.parked, key)
+		return nil, false
+	}
+	ks.parked[key] = queue[1:]
+	return queue[0], true
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sendInterval is the time an email spent being sent
+type sendInterval struct {
+	address    string
+	start, end time.Time
+}
+
+// recordedEmail is an EmailTask that records when it was sent instead of sleeping for a second.
+// Its ResourceKey is the one of the embedded EmailTask.
+type recordedEmail struct {
+	*EmailTask
+	mu    *sync.Mutex
+	sends *[]sendInterval
+}
+
+func (r *recordedEmail) Process() {
+	start := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.sends = append(*r.sends, sendInterval{r.EmailId, start, time.Now()})
+}
+
+// TestEmailsToOneAddressDoNotOverlap sends several emails per address on four workers and checks
+// that emails to the same address never overlap in time while different addresses run in parallel
+func TestEmailsToOneAddressDoNotOverlap(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses []string
+		perAddr   int
+	}{
+		{"one address", []string{"ana@example.com"}, 5},
+		{"two addresses", []string{"ana@example.com", "bob@example.com"}, 3},
+		{"distinct addresses", []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var sends []sendInterval
+			var tasks []MultiTask
+			for i := range tt.perAddr {
+				for _, address := range tt.addresses {
+					tasks = append(tasks, &recordedEmail{
+						EmailTask: &EmailTask{EmailId: address, Subject: fmt.Sprintf("update %d", i)},
+						mu:        &mu,
+						sends:     &sends,
+					})
+				}
+			}
+			wp := NewWorkerPool{MultiTasks: tasks, Concurrency: 4}
+			if _, err := wp.Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(sends) != len(tasks) {
+				t.Fatalf("%d emails sent, want %d", len(sends), len(tasks))
+			}
+			parallel := false
+			for i, a := range sends {
+				for _, b := range sends[i+1:] {
+					overlap := a.start.Before(b.end) && b.start.Before(a.end)
+					if overlap && a.address == b.address {
+						t.Errorf("two emails to %s were sent at the same time", a.address)
+					}
+					parallel = parallel || overlap
+				}
+			}
+			if len(tt.addresses) > 1 && !parallel {
+				t.Errorf("emails to %d addresses were sent one at a time, want them in parallel", len(tt.addresses))
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+/*
+Results produced by tasks processed in the WorkerPool.
+*/
+
+// Result is the outcome of processing a single task
+type Result struct {
+	TaskId int   // Id of the task that produced the result
+	Value  any   // Value produced by the task, nil for tasks without output
+	Err    error // Error returned by the task, nil on success
+}
+
+// ErrTaskCancelled is reported for tasks stopped or skipped because the pool was cancelled
+var ErrTaskCancelled = errors.New("task cancelled")
+
+// ErrTaskTimeout is reported for attempts that exceeded the pool's TaskTimeout and for tasks
+// that exceeded its CumulativeTimeout
+var ErrTaskTimeout = errors.New("task timed out")
+
+// ErrTaskExpired is reported for tasks that waited in the queue longer than the pool's MaxQueueAge
+var ErrTaskExpired = errors.New("task expired in queue")
+
+// ErrPossibleDeadlock is matched by the StallError of a pool that stopped making progress
+var ErrPossibleDeadlock = errors.New("worker pool made no progress, possible deadlock")
+
+// ErrTaskPanicked is reported to AfterProcess for attempts that panicked, and as the task's error
+// when the PanicPolicy recovers the panic
+var ErrTaskPanicked = errors.New("task panicked")
+
+// ErrPoolClosed is returned when submitting a task to a pool that is closed
+var ErrPoolClosed = errors.New("worker pool is closed")
+
+// ErrQueueFull is returned by TrySubmit when the queue stayed full for the whole wait
+var ErrQueueFull = errors.New("worker pool queue is full")
+
+// RetryError is returned by Retry once every attempt failed or the retries were cancelled
+type RetryError struct {
+	Attempts int   // Number of attempts made
+	Err      error // Error of the last attempt, also wrapping the context error if cancelled
+}
+
+// Error describes the failure together with the number of attempts
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("failed after %d attempt(s): %v", e.Attempts, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// TaskError wraps the error of a failed task with the task Id and the attempt that failed.
+// It implements Unwrap, so errors.Is and errors.As see the underlying error.
+type TaskError struct {
+	TaskId  int   // Id of the failed task
+	Attempt int   // Number of attempts made, 0 if the task never started
+	Err     error // Error returned by the last attempt
+}
+
+// Error describes the failure together with the task context
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("task %d failed after %d attempt(s): %v", e.TaskId, e.Attempt, e.Err)
+}
+
+// Unwrap returns the underlying task error
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// StallError reports that tasks were outstanding but none completed for the pool's StallTimeout.
+// errors.Is matches it against ErrPossibleDeadlock.
+type StallError struct {
+	Pending int           // Tasks submitted but not completed when the stall was detected
+	Stalled time.Duration // How long no task had completed
+	Stacks  string        // Stack traces of all goroutines at detection time, showing where they block
+}
+
+// Error summarizes the stall, without the stack traces
+func (e *StallError) Error() string {
+	return fmt.Sprintf("%v: %d task(s) pending, none completed for %v", ErrPossibleDeadlock, e.Pending, e.Stalled.Round(time.Millisecond))
+}
+
+// Unwrap returns ErrPossibleDeadlock
+func (e *StallError) Unwrap() error {
+	return ErrPossibleDeadlock
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+/*
+Streaming results channels of the WorkerPool: ResultsCtx, cancellable through a context,
+and RunStream, the channels-in / channels-out shape.
+A consumer that goes away must not leave workers blocked on a send nobody will receive,
+so every send also selects on a stop channel. Closing the stream first closes stop, which
+releases any worker blocked in a send, then waits for the senders to leave and only then
+closes the results channel, so a result is never sent on a closed channel.
+With ResultTimeout a send also gives up after that long, so a stalled consumer drops results
+instead of blocking the workers.
+*/
+
+// resultStream delivers task results to a consumer until the pool shuts down or the consumer leaves
+type resultStream struct {
+	out  chan Result
+	stop chan struct{} // Closed when the stream is closed, releasing blocked senders
+
+	mu     sync.RWMutex // Held for reading by senders, for writing while closing out
+	closed bool
+	once   sync.Once
+}
+
+// send delivers a result unless the stream was closed; it never blocks once stop is closed.
+// It gives up once timeout fires (nil waits indefinitely), reporting the result as dropped.
+func (s *resultStream) send(r Result, timeout <-chan time.Time) (dropped bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return false
+	}
+	select {
+	case s.out <- r:
+		return false
+	case <-s.stop:
+		return false
+	case <-timeout:
+		return true
+	}
+}
+
+// close releases blocked senders and closes the results channel once they have left
+func (s *resultStream) close() {
+	s.once.Do(func() {
+		close(s.stop)
+		s.mu.Lock()
+		s.closed = true
+		close(s.out)
+		s.mu.Unlock()
+	})
+}
+
+// ResultsCtx streams the result of every task processed from now on. The channel is closed once
+// the pool shuts down and all results were delivered, or promptly when ctx is cancelled, which
+// also cancels the pool. Workers never block on a send after ctx is cancelled, so abandoning
+// the channel that way leaks no goroutine. Call it once, after Start and before submitting tasks.
+func (wp *WorkerPool) ResultsCtx(ctx context.Context) <-chan Result {
+	s := &resultStream{out: make(chan Result), stop: make(chan struct{})}
+	wp.results.Store(s)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			wp.Cancel()
+		case <-wp.done:
+		}
+		s.close()
+	}()
+	return s.out
+}
+
+// RunStream starts the pool, processes every task received from in and streams the results on
+// the returned channel, so the pool can be wired into a channel pipeline without the Tasks
+// slice or Submit. After in is closed and the submitted tasks drained, the results channel is
+// closed. Read it until then: workers wait for the reader. Cancel stops reading from in, skips
+// the queued tasks and still closes the results channel once the workers are done.
+// If the options conflict nothing is started and the returned channel is already closed; call
+// Validate beforehand to get the error.
+func (wp *WorkerPool) RunStream(in <-chan Task) <-chan Result {
+	s := &resultStream{out: make(chan Result), stop: make(chan struct{})}
+	if err := wp.start(context.Background()); err != nil {
+		Log.Error("worker pool not started", slog.Any("error", err))
+		s.close()
+		return s.out
+	}
+	wp.results.Store(s)
+
+	go func() {
+		defer s.close()
+		defer wp.Close()
+		for {
+			select {
+			case task, ok := <-in:
+				if !ok || wp.Submit(task) != nil {
+					return
+				}
+			case <-wp.ctx.Done():
+				return
+			}
+		}
+	}()
+	return s.out
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestResultsCtxCancelLeaksNoGoroutine abandons a ResultsCtx stream mid-way by cancelling its
+// context and checks that the channel closes and the goroutine count returns to the baseline
+func TestResultsCtxCancelLeaksNoGoroutine(t *testing.T) {
+	tests := []struct {
+		name          string
+		concurrency   int
+		resultTimeout time.Duration
+		read          int  // Results read before the cancellation
+		drain         bool // Drain the channel after the cancellation instead of abandoning it
+	}{
+		{"cancel after the first result", 4, 0, 1, false},
+		{"cancel mid-stream and drain", 4, 0, 5, true},
+		{"cancel mid-stream with a single worker", 1, 0, 5, false},
+		{"cancel with ResultTimeout", 4, 10 * time.Millisecond, 5, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			wp := &WorkerPool{Concurrency: tt.concurrency, ResultTimeout: tt.resultTimeout}
+			if err := wp.Start(); err != nil {
+				t.Fatal(err)
+			}
+			results := wp.ResultsCtx(ctx)
+
+			submitted := make(chan struct{})
+			go func() {
+				defer close(submitted)
+				for i := 1; i <= 100; i++ {
+					if wp.Submit(Task{Id: i, Work: func(done <-chan struct{}) (any, error) { return i, nil }}) != nil {
+						return
+					}
+				}
+			}()
+
+			for range tt.read {
+				if _, ok := <-results; !ok {
+					t.Fatal("results channel closed before the cancellation")
+				}
+			}
+			cancel() // nobody reads the remaining results, the workers must not block on them
+
+			if !tt.drain {
+				wp.Close() // closes the stream without anybody reading from it
+			}
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+				for range results {
+				}
+			}()
+			wp.Close()
+			for what, ch := range map[string]chan struct{}{"submitter": submitted, "results reader": closed} {
+				select {
+				case <-ch:
+				case <-time.After(time.Second):
+					t.Fatalf("%s still blocked a second after the cancellation", what)
+				}
+			}
+		})
+	}
+}
+
+// TestResultTimeoutStalledConsumer has a ResultsCtx consumer take a few results and then stall,
+// and checks that every result it did not take is dropped after ResultTimeout of fake time and
+// counted in Stats().Dropped, so Close returns while the consumer is still stuck
+func TestResultTimeoutStalledConsumer(t *testing.T) {
+	const tasks, resultTimeout = 6, 50 * time.Millisecond
+	tests := []struct {
+		name          string
+		resultTimeout time.Duration
+		read          int // Results the consumer takes before it stalls
+		wantDropped   int64
+	}{
+		{"stalled from the start", resultTimeout, 0, tasks},
+		{"stalled after two results", resultTimeout, 2, tasks - 2},
+		{"consumer keeping up", resultTimeout, tasks, 0},
+		{"consumer keeping up without ResultTimeout", 0, tasks, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			clock := NewFakeClock(time.Unix(0, 0))
+			wp := &WorkerPool{Concurrency: 2, Clock: clock, ResultTimeout: tt.resultTimeout}
+			if err := wp.Start(); err != nil {
+				t.Fatal(err)
+			}
+			results := wp.ResultsCtx(context.Background())
+
+			stalled, resume := make(chan struct{}), make(chan struct{})
+			consumed := make(chan int, 1)
+			go func() {
+				n := 0
+				for ; n < tt.read; n++ {
+					<-results
+				}
+				close(stalled)
+				<-resume
+				for range results {
+					n++
+				}
+				consumed <- n
+			}()
+			submitted := make(chan error, 1)
+			go func() {
+				for i := 1; i <= tasks; i++ {
+					if err := wp.Submit(Task{Id: i, Work: func(done <-chan struct{}) (any, error) { return i, nil }}); err != nil {
+						submitted <- err
+						return
+					}
+				}
+				submitted <- nil
+			}()
+
+			<-stalled
+			advanceUntil(t, clock, resultTimeout, func() bool { return wp.Stats().Dropped >= tt.wantDropped })
+			if err := <-submitted; err != nil {
+				t.Fatal(err)
+			}
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+				wp.Close()
+			}()
+			select {
+			case <-closed:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Close blocked on the stalled consumer")
+			}
+			close(resume)
+
+			if n := <-consumed; n != tt.read {
+				t.Errorf("consumer took %d results, want %d", n, tt.read)
+			}
+			if stats := wp.Stats(); stats.Dropped != tt.wantDropped || stats.Processed != tasks {
+				t.Errorf("Stats() dropped %d of %d processed, want %d of %d", stats.Dropped, stats.Processed, tt.wantDropped, tasks)
+			}
+		})
+	}
+}
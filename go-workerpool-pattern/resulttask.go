@@ -0,0 +1,61 @@
+package main
+
+import "sync"
+
+/*
+Result-producing tasks for the multi-type worker pool.
+Plain MultiTasks only have side effects. Tasks that produce output opt in by also implementing
+ResultTask: the pool calls ProcessResult instead of Process for them and collects their
+values, so result collection can be adopted one task type at a time.
+*/
+
+// ResultTask is implemented by multi-type tasks that produce a value. A Go type cannot have two
+// Process methods with different signatures, so the result-producing variant is ProcessResult;
+// the pool calls it instead of Process. Process is still required to be a MultiTask and
+// typically calls ProcessResult and drops the value.
+type ResultTask interface {
+	MultiTask
+	ProcessResult() (any, error)
+}
+
+// MultiTaskResult is the outcome of a ResultTask processed by the multi-type pool
+type MultiTaskResult struct {
+	Task  MultiTask // The task that produced the result
+	Value any       // Value returned by ProcessResult
+	Err   error     // Error returned by ProcessResult
+}
+
+// resultCollector gathers the results of ResultTasks as workers complete them
+type resultCollector struct {
+	mu      sync.Mutex
+	results []MultiTaskResult
+}
+
+// add records the result of a ResultTask
+func (c *resultCollector) add(r MultiTaskResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results = append(c.results, r)
+}
+
+// runMultiTask processes a task, through ProcessResult for ResultTasks (reporting ok), through
+// ProcessErr for FallibleTasks and through Process for plain MultiTasks, which never fail
+func runMultiTask(task MultiTask) (value any, ok bool, err error) {
+	if t, ok := taskAs[ResultTask](task); ok {
+		value, err = t.ProcessResult()
+		return value, true, err
+	}
+	if t, ok := taskAs[FallibleTask](task); ok {
+		return nil, false, t.ProcessErr()
+	}
+	task.Process()
+	return nil, false, nil
+}
+
+// Results returns the results of the ResultTasks processed so far, in completion order.
+// Plain MultiTasks produce no result and are not included.
+func (wp *NewWorkerPool) Results() []MultiTaskResult {
+	wp.results.mu.Lock()
+	defer wp.results.mu.Unlock()
+	return append([]MultiTaskResult(nil), wp.results.results...)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+/*
+Retries for failed WorkerPool tasks.
+A failed task is processed again up to MaxRetries times, waiting Backoff(attempt) between
+attempts. JitteredBackoff provides exponential backoff with full jitter, which spreads the
+retries of many tasks over time instead of hitting a recovering downstream all at once.
+Retry offers the same loop for any function, without a pool.
+*/
+
+// Retry calls fn until it succeeds, at most attempts times (at least once), waiting
+// backoff(n) before retry attempt n (starting at 1); a nil backoff retries immediately, and
+// JitteredBackoff fits. It returns nil on success and otherwise a *RetryError with the number
+// of attempts made and the last error. Cancelling ctx stops the retries: the error then also
+// matches ctx.Err() with errors.Is. A ctx cancelled before the first attempt returns ctx.Err().
+func Retry(ctx context.Context, attempts int, backoff func(attempt int) time.Duration, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	attempts = max(attempts, 1)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt >= attempts {
+			return &RetryError{Attempts: attempt, Err: err}
+		}
+
+		if backoff != nil {
+			select {
+			case <-time.After(backoff(attempt)):
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			return &RetryError{Attempts: attempt, Err: fmt.Errorf("%w (retries stopped: %w)", err, ctx.Err())}
+		}
+	}
+}
+
+// JitteredBackoff returns an exponential backoff with full jitter: the wait before retry
+// attempt n (starting at 1) is a random duration in [0, min(max, base*2^(n-1))).
+func JitteredBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return JitteredBackoffWithSource(base, max, rand.NewSource(time.Now().UnixNano()))
+}
+
+// JitteredBackoffWithSource is JitteredBackoff with an injectable random source,
+// so tests can use a fixed seed and get deterministic waits.
+func JitteredBackoffWithSource(base, max time.Duration, src rand.Source) func(attempt int) time.Duration {
+	var mu sync.Mutex // rand.Rand is not safe for concurrent use and workers retry concurrently
+	rnd := rand.New(src)
+
+	return func(attempt int) time.Duration {
+		ceiling := max
+		if attempt < 1 {
+			attempt = 1
+		}
+		// double base per attempt, stopping before it overflows or passes max
+		if shift := attempt - 1; shift < 63 && base <= max>>shift {
+			ceiling = base << shift
+		}
+		if ceiling <= 0 {
+			return 0
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		return time.Duration(rnd.Int63n(int64(ceiling)))
+	}
+}
+
+// runWithRetries processes a task, retrying failed attempts up to MaxRetries times.
+// It returns the outcome of the last attempt and the number of attempts made.
+// Retries stop early when the pool (or the task's group, see taskContext) is cancelled,
+// the RetryBudget is spent or the CumulativeTimeout passed, in which case the task fails
+// with ErrTaskTimeout. A recovered panic is only retried with PanicRecoverRetry.
+func (wp *WorkerPool) runWithRetries(parent context.Context, task Task) (value any, attempts int, err error) {
+	ctx := parent
+	if wp.CumulativeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = withTimeout(parent, wp.clock(), wp.CumulativeTimeout)
+		defer cancel()
+		defer func() {
+			if err != nil && ctx.Err() != nil && parent.Err() == nil {
+				err = ErrTaskTimeout
+			}
+		}()
+	}
+
+	for attempt := 0; ; attempt++ {
+		value, err = wp.hedgedAttempt(ctx, task)
+		attempts = attempt + 1
+		if err == nil || attempt >= wp.MaxRetries || ctx.Err() != nil || !wp.retryable(err) || !wp.takeRetry() {
+			return value, attempts, err
+		}
+		if wp.Backoff == nil {
+			continue
+		}
+		timer := wp.clock().NewTimer(wp.Backoff(attempts))
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return value, attempts, err
+		}
+	}
+}
+
+// retryable reports whether a failed attempt may be retried: every error except a panic
+// recovered under PanicRecoverFail
+func (wp *WorkerPool) retryable(err error) bool {
+	return wp.PanicPolicy != PanicRecoverFail || !errors.Is(err, ErrTaskPanicked)
+}
+
+// takeRetry consumes one retry from the pool-wide RetryBudget, reporting false once it is spent
+func (wp *WorkerPool) takeRetry() bool {
+	if wp.RetryBudget <= 0 {
+		return true
+	}
+	return wp.counters.retries.Add(1) <= int64(wp.RetryBudget)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+/*
+Per-Id error reporting for the WorkerPool.
+RunMap runs the batch like Run and returns the final error of every task keyed by its Id,
+which is handier than a flat error slice for callers that key their work by Id.
+*/
+
+// RunMap executes all tasks like Run and returns the final error of each task by Id, nil for
+// tasks that succeeded. Every Id in Tasks appears as a key.
+// Duplicate Ids share one key: it holds nil only if every task with that Id succeeded,
+// otherwise the errors of the failed ones joined with errors.Join in completion order.
+func (wp *WorkerPool) RunMap() map[int]error {
+	var errs SafeMap[int, error]
+	for _, task := range wp.Tasks {
+		errs.Store(task.Id, nil)
+	}
+
+	wp.collect = func(task Task, result Result, _ int) {
+		if result.Err == nil {
+			return
+		}
+		errs.Update(task.Id, func(err error, _ bool) error { return errors.Join(err, result.Err) })
+	}
+	_ = wp.runBatch(context.Background())
+	return errs.Snapshot()
+}
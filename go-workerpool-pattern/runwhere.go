@@ -0,0 +1,26 @@
+package main
+
+import "context"
+
+/*
+Running a subset of the WorkerPool tasks.
+Tasks carry free-form Tags, and RunWhere processes only the tasks matching a predicate, e.g.
+to reprocess the tasks of one region, without rebuilding the Tasks slice.
+*/
+
+// RunWhere executes the tasks for which pred returns true, like Run, and returns the others
+// untouched in their original order. pred is evaluated for each task right before it would be
+// enqueued, on the calling goroutine. The batch size is not known upfront, so OnProgress
+// receives -1 as total.
+func (wp *WorkerPool) RunWhere(pred func(Task) bool) []Task {
+	skipped, _ := wp.run(context.Background(), pred)
+	return skipped
+}
+
+// HasTag returns a RunWhere predicate matching the tasks whose tag key has the given value
+func HasTag(key, value string) func(Task) bool {
+	return func(task Task) bool {
+		v, ok := task.Tags[key]
+		return ok && v == value
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import "sync"
+
+/*
+Concurrency-safe map for results keyed by task Id.
+Collecting results over channels and a WaitGroup works for lists, but keyed lookups need a map
+that several workers can write at once. SafeMap guards a plain map with a sync.RWMutex, so
+readers do not block each other; RunMap uses it to collect the errors of a batch.
+*/
+
+// SafeMap is a map safe for concurrent use. The zero value is an empty map ready to use.
+type SafeMap[K comparable, V any] struct {
+	mu sync.RWMutex
+	m  map[K]V
+}
+
+// Store sets the value for a key
+func (s *SafeMap[K, V]) Store(key K, value V) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m == nil {
+		s.m = make(map[K]V)
+	}
+	s.m[key] = value
+}
+
+// Load returns the value stored for a key and whether it was present
+func (s *SafeMap[K, V]) Load(key K) (V, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.m[key]
+	return v, ok
+}
+
+// Update replaces the value of a key with fn(current value, present) atomically, so
+// read-modify-write updates (counters, appending errors) do not race each other
+func (s *SafeMap[K, V]) Update(key K, fn func(V, bool) V) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.m == nil {
+		s.m = make(map[K]V)
+	}
+	v, ok := s.m[key]
+	s.m[key] = fn(v, ok)
+}
+
+// Range calls fn for every entry until it returns false, in no particular order.
+// It holds the read lock, so fn must not modify the map.
+func (s *SafeMap[K, V]) Range(fn func(K, V) bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for k, v := range s.m {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
+
+// Len returns the number of entries
+func (s *SafeMap[K, V]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.m)
+}
+
+// Snapshot returns a copy of the map that the caller owns
+func (s *SafeMap[K, V]) Snapshot() map[K]V {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot := make(map[K]V, len(s.m))
+	for k, v := range s.m {
+		snapshot[k] = v
+	}
+	return snapshot
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestSafeMapConcurrentAccess hammers a SafeMap from many goroutines mixing every method, so
+// `go test -race` reports any unguarded access, and checks that no Update was lost
+func TestSafeMapConcurrentAccess(t *testing.T) {
+	const goroutines, ops = 32, 500
+	var results SafeMap[int, string]
+	var counts SafeMap[string, int]
+
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range ops {
+				id := g*ops + i
+				results.Store(id, fmt.Sprint(id))
+				if v, ok := results.Load(id); !ok || v != fmt.Sprint(id) {
+					t.Errorf("Load(%d) = %q, %t right after Store", id, v, ok)
+				}
+				counts.Update("total", func(n int, _ bool) int { return n + 1 })
+				switch i % 50 {
+				case 0:
+					results.Range(func(int, string) bool { return true })
+				case 1:
+					_ = results.Snapshot()
+				case 2:
+					_ = results.Len()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := results.Len(); n != goroutines*ops {
+		t.Fatalf("Len() = %d, want %d", n, goroutines*ops)
+	}
+	if total, _ := counts.Load("total"); total != goroutines*ops {
+		t.Fatalf("counter = %d after concurrent Updates, want %d", total, goroutines*ops)
+	}
+}
+
+// TestRunMapConcurrentErrors collects the errors of tasks sharing Ids while several workers
+// report at once; the errors of each Id must all be joined under its key
+func TestRunMapConcurrentErrors(t *testing.T) {
+	errFailed := errors.New("failed")
+	var tasks []Task
+	for i := range 200 {
+		id := i % 10
+		tasks = append(tasks, Task{Id: id, Work: func(done <-chan struct{}) (any, error) {
+			if id%2 == 0 {
+				return nil, errFailed
+			}
+			return nil, nil
+		}})
+	}
+	wp := WorkerPool{Tasks: tasks, Concurrency: 8}
+
+	errs := wp.RunMap()
+	if len(errs) != 10 {
+		t.Fatalf("RunMap returned %d keys, want 10", len(errs))
+	}
+	for id, err := range errs {
+		if id%2 != 0 {
+			if err != nil {
+				t.Errorf("Id %d: got %v, want nil", id, err)
+			}
+			continue
+		}
+		if n := countErrors(err, errFailed); n != 20 {
+			t.Errorf("Id %d: got %v, want the 20 errors of its tasks joined", id, err)
+		}
+	}
+}
+
+// countErrors counts how often target occurs in the tree of errors wrapped by err
+func countErrors(err, target error) int {
+	switch e := err.(type) {
+	case nil:
+		return 0
+	case interface{ Unwrap() []error }:
+		n := 0
+		for _, inner := range e.Unwrap() {
+			n += countErrors(inner, target)
+		}
+		return n
+	}
+	if err == target {
+		return 1
+	}
+	return countErrors(errors.Unwrap(err), target)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+/*
+Compensating actions (saga semantics) for the multi-type worker pool.
+A task that fails midway may leave partial side effects behind, e.g. half of an uploaded image.
+Tasks that can undo them implement CompensableTask: once a task has failed for good (after its
+retries), the pool calls Compensate so the task rolls back what it did. A task that succeeds on a
+retry is never compensated. Compensation can fail too; those errors are kept apart from the task
+errors, since they mean the rollback itself needs attention.
+*/
+
+// CompensableTask is implemented by multi-type tasks that can roll back their partial side
+// effects. Compensate is called once, on the worker, after the task finally failed.
+type CompensableTask interface {
+	MultiTask
+	Compensate() error
+}
+
+// CompensationError reports a task whose compensation failed
+type CompensationError struct {
+	Task    MultiTask // The failed task
+	TaskErr error     // Error the task failed with
+	Err     error     // Error returned by Compensate
+}
+
+// Error describes the failed rollback together with the original failure
+func (e *CompensationError) Error() string {
+	return fmt.Sprintf("compensating %s task failed: %v (task error: %v)", typeNameOf(e.Task), e.Err, e.TaskErr)
+}
+
+// Unwrap returns the error returned by Compensate
+func (e *CompensationError) Unwrap() error {
+	return e.Err
+}
+
+// compensations gathers the compensation failures as workers report them
+type compensations struct {
+	mu     sync.Mutex
+	failed []*CompensationError
+}
+
+// add records a failed compensation
+func (c *compensations) add(err *CompensationError) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failed = append(c.failed, err)
+}
+
+// compensate rolls back a task that finally failed with err, if it is a CompensableTask
+func (wp *NewWorkerPool) compensate(task MultiTask, err error) {
+	ct, ok := taskAs[CompensableTask](task)
+	if !ok {
+		return
+	}
+	if cerr := ct.Compensate(); cerr != nil {
+		wp.compensations.add(&CompensationError{Task: task, TaskErr: err, Err: cerr})
+		Log.Warn("task compensation failed", slog.String("task_type", typeNameOf(task)), slog.Any("error", cerr))
+		return
+	}
+	wp.compensated.Add(1)
+}
+
+// CompensationErrors returns the failed compensations so far, in the order they happened.
+// Tasks whose compensation succeeded are counted in Metrics().Compensated.
+func (wp *NewWorkerPool) CompensationErrors() []*CompensationError {
+	wp.compensations.mu.Lock()
+	defer wp.compensations.mu.Unlock()
+	return append([]*CompensationError(nil), wp.compensations.failed...)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// uploadTask is a CompensableTask failing its first attempts, recording how many attempts it
+// had made when it was compensated
+type uploadTask struct {
+	failures      int   // Attempts that fail before one succeeds
+	cleanupErr    error // Error returned by Compensate
+	attempts      int
+	compensatedAt []int // Attempts made at each Compensate call
+}
+
+func (u *uploadTask) Process()         { _ = u.ProcessErr() }
+func (u *uploadTask) TypeName() string { return "upload" }
+
+func (u *uploadTask) ProcessErr() error {
+	u.attempts++
+	if u.attempts <= u.failures {
+		return fmt.Errorf("connection reset on attempt %d", u.attempts)
+	}
+	return nil
+}
+
+func (u *uploadTask) Compensate() error {
+	u.compensatedAt = append(u.compensatedAt, u.attempts)
+	return u.cleanupErr
+}
+
+// TestCompensation runs upload tasks that fail a number of times with MaxRetries and checks that
+// Compensate runs exactly once after the last attempt of a task that finally failed, never for
+// a task that succeeded on a retry, and that a failed rollback is reported separately
+func TestCompensation(t *testing.T) {
+	storageDown := errors.New("storage unavailable")
+	tests := []struct {
+		name            string
+		failures        int
+		maxRetries      int
+		cleanupErr      error
+		wantCompensated bool
+	}{
+		{"succeeds at once", 0, 1, nil, false},
+		{"retried then succeeded", 1, 1, nil, false},
+		{"succeeds on the last retry", 3, 3, nil, false},
+		{"fails without retries", 1, 0, nil, true},
+		{"fails after its retries", 5, 2, nil, true},
+		{"rollback fails", 5, 1, storageDown, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &uploadTask{failures: tt.failures, cleanupErr: tt.cleanupErr}
+			wp := NewWorkerPool{Concurrency: 1, MaxRetries: tt.maxRetries, MultiTasks: []MultiTask{task}}
+			if _, err := wp.Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !tt.wantCompensated {
+				if len(task.compensatedAt) != 0 {
+					t.Errorf("compensated after attempts %v, want no compensation", task.compensatedAt)
+				}
+				return
+			}
+			if want := tt.maxRetries + 1; len(task.compensatedAt) != 1 || task.compensatedAt[0] != want {
+				t.Errorf("compensated after attempts %v, want once after attempt %d", task.compensatedAt, want)
+			}
+			var wantCount int64 = 1
+			compErrs := wp.CompensationErrors()
+			if tt.cleanupErr != nil {
+				wantCount = 0
+				if len(compErrs) != 1 || !errors.Is(compErrs[0], tt.cleanupErr) || compErrs[0].TaskErr == nil || compErrs[0].Task != task {
+					t.Errorf("CompensationErrors() = %v, want the failed rollback with the task error", compErrs)
+				}
+			} else if len(compErrs) != 0 {
+				t.Errorf("CompensationErrors() = %v, want none", compErrs)
+			}
+			if got := wp.Metrics().Compensated; got != wantCount {
+				t.Errorf("Metrics().Compensated = %d, want %d", got, wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+Periodic task runner on top of the WorkerPool.
+A Scheduler submits a copy of its Task to a running pool on every tick of a fixed interval,
+so periodic jobs (cache refreshes, cleanups) share the pool's workers, limits and metrics.
+With SkipIfRunning a tick is skipped while the previous run is still queued or processing,
+so a run slower than the interval does not pile up copies of itself.
+*/
+
+// Scheduler runs Task on Pool every interval between Start and Stop
+type Scheduler struct {
+	Pool          *WorkerPool // Started pool executing the runs
+	Task          Task        // Task submitted on every tick, its Id is shared by all runs
+	SkipIfRunning bool        // Whether to skip a tick while the previous run has not finished
+
+	mu      sync.Mutex
+	stop    chan struct{} // Closed by Stop to end the ticking goroutine, nil when not started
+	stopped chan struct{} // Closed once the ticking goroutine has exited
+	runs    atomic.Int64  // Ticks that submitted a run
+	skipped atomic.Int64  // Ticks skipped because the previous run was still in flight
+
+	last atomic.Pointer[Future[Result]] // Resolved once the latest run finished or was dropped
+}
+
+// Start begins submitting Task every interval, measured on the pool's Clock. The first run
+// is submitted after one interval. Starting a started scheduler does nothing. Ticking stops
+// on its own once the pool no longer accepts tasks.
+func (s *Scheduler) Start(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.stopped = make(chan struct{})
+	go s.tick(s.Pool.clock().NewTicker(interval), s.stop, s.stopped)
+}
+
+// Stop ends the ticking and waits until no further run can be submitted. Runs already
+// submitted still complete on the pool. The scheduler may be started again afterwards.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	stop, stopped := s.stop, s.stopped
+	s.stop = nil
+	s.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-stopped
+}
+
+// Runs returns how many runs were submitted so far
+func (s *Scheduler) Runs() int64 {
+	return s.runs.Load()
+}
+
+// Skipped returns how many ticks were skipped because the previous run was still in flight
+func (s *Scheduler) Skipped() int64 {
+	return s.skipped.Load()
+}
+
+// tick submits a run on every tick until stop is closed or the pool is closed
+func (s *Scheduler) tick(ticker Ticker, stop <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+		case <-stop:
+			return
+		}
+
+		if s.SkipIfRunning && s.inFlight() {
+			s.skipped.Add(1)
+			continue
+		}
+		// the future is resolved on every outcome of the run, including the runs the pool drops
+		// without processing them (CancelTask, CancelGroup, MaxQueueAge, cancellation)
+		task := s.Task
+		task.future = newFuture[Result]()
+		task.untracked = true // one batch entry per tick would leak over the scheduler's lifetime
+		if err := s.Pool.Submit(task); err != nil {
+			return
+		}
+		s.last.Store(task.future)
+		s.runs.Add(1)
+	}
+}
+
+// inFlight reports whether the latest run is still queued or being processed
+func (s *Scheduler) inFlight() bool {
+	last := s.last.Load()
+	if last == nil {
+		return false
+	}
+	select {
+	case <-last.Done():
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestSchedulerLeavesNoBatchState ticks a scheduler many times on a FakeClock and checks that
+// its runs do not accumulate in the pool's batch bookkeeping
+func TestSchedulerLeavesNoBatchState(t *testing.T) {
+	const ticks = 200
+	tests := []struct {
+		name          string
+		skipIfRunning bool
+	}{
+		{"every tick", false},
+		{"skip if running", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			clock := NewFakeClock(time.Unix(0, 0))
+			var processed atomic.Int64
+			wp := &WorkerPool{Concurrency: 2, Clock: clock}
+			wp.Start()
+			defer wp.Close()
+			s := &Scheduler{Pool: wp, SkipIfRunning: tt.skipIfRunning, Task: Task{Id: 7, Work: func(done <-chan struct{}) (any, error) {
+				processed.Add(1)
+				return nil, nil
+			}}}
+			s.Start(time.Second)
+			defer s.Stop()
+
+			for i := range ticks {
+				clock.Advance(time.Second)
+				for s.Runs()+s.Skipped() < int64(i+1) {
+					time.Sleep(100 * time.Microsecond)
+				}
+				if err := wp.WaitIdle(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if n := processed.Load(); n != ticks {
+				t.Fatalf("processed %d runs, want %d", n, ticks)
+			}
+			wp.batch.mu.Lock()
+			submitted, queued, completed := len(wp.batch.submitted), len(wp.batch.queued), len(wp.batch.completed)
+			wp.batch.mu.Unlock()
+			if submitted+queued+completed != 0 {
+				t.Errorf("batch state grew to %d submitted, %d queued and %d completed entries, want none",
+					submitted, queued, completed)
+			}
+		})
+	}
+}
+
+// TestSchedulerSkipIfRunning ticks a scheduler whose run blocks for several intervals of a
+// FakeClock and checks that the runs pile up on the workers unless SkipIfRunning drops the ticks
+func TestSchedulerSkipIfRunning(t *testing.T) {
+	const ticks = 6
+	tests := []struct {
+		name          string
+		skipIfRunning bool
+		wantRuns      int64
+		wantSkipped   int64
+		wantInFlight  int64 // Most runs processing at once
+	}{
+		{"overlapping runs", false, ticks, 0, 4},
+		{"skip if running", true, 1, ticks - 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			clock := NewFakeClock(time.Unix(0, 0))
+			wp := &WorkerPool{Concurrency: 4, Clock: clock}
+			wp.Start()
+			defer wp.Close()
+			release := make(chan struct{})
+			var inFlight, maxInFlight atomic.Int64
+			s := &Scheduler{Pool: wp, SkipIfRunning: tt.skipIfRunning, Task: Task{Id: 1, Work: func(done <-chan struct{}) (any, error) {
+				n := inFlight.Add(1)
+				for m := maxInFlight.Load(); n > m && !maxInFlight.CompareAndSwap(m, n); m = maxInFlight.Load() {
+				}
+				<-release
+				inFlight.Add(-1)
+				return nil, nil
+			}}}
+			s.Start(time.Second)
+
+			for i := range ticks {
+				clock.Advance(time.Second)
+				for s.Runs()+s.Skipped() < int64(i+1) {
+					time.Sleep(100 * time.Microsecond)
+				}
+			}
+			for wait := time.Now().Add(time.Second); inFlight.Load() < min(tt.wantRuns, 4) && time.Now().Before(wait); {
+				time.Sleep(time.Millisecond)
+			}
+			s.Stop()
+			close(release)
+
+			if s.Runs() != tt.wantRuns || s.Skipped() != tt.wantSkipped || maxInFlight.Load() != tt.wantInFlight {
+				t.Errorf("%d runs, %d skipped, at most %d in flight; want %d, %d, %d",
+					s.Runs(), s.Skipped(), maxInFlight.Load(), tt.wantRuns, tt.wantSkipped, tt.wantInFlight)
+			}
+		})
+	}
+}
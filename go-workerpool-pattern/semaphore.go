@@ -0,0 +1,92 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+)
+
+/*
+Weighted semaphore used to bound the total cost of the tasks running at once (CostBudget)
+and, with a weight of 1 per task, their number (MaxInFlight).
+Waiters are served in FIFO order, so a heavy task is not starved by a stream of cheap ones.
+*/
+
+// semaphoreWaiter is a goroutine waiting to acquire n units
+type semaphoreWaiter struct {
+	n     int
+	ready chan struct{} // Closed once the units have been granted
+}
+
+// weightedSemaphore grants up to size units to concurrent holders
+type weightedSemaphore struct {
+	size    int
+	mu      sync.Mutex
+	cur     int        // Units currently held
+	waiters *list.List // FIFO queue of *semaphoreWaiter
+}
+
+// newWeightedSemaphore creates a semaphore with the given number of units
+func newWeightedSemaphore(size int) *weightedSemaphore {
+	return &weightedSemaphore{size: size, waiters: list.New()}
+}
+
+// acquire blocks until n units are available or done is closed, reporting whether the units
+// were acquired. Requests larger than the semaphore are capped to its size so they can still run.
+func (s *weightedSemaphore) acquire(done <-chan struct{}, n int) bool {
+	n = min(n, s.size)
+
+	s.mu.Lock()
+	if s.cur+n <= s.size && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return true
+	}
+	w := &semaphoreWaiter{n: n, ready: make(chan struct{})}
+	elem := s.waiters.PushBack(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return true
+	case <-done:
+		s.mu.Lock()
+		select {
+		case <-w.ready:
+			// granted while we were giving up, hand the units back
+			s.cur -= n
+			s.notifyWaiters()
+		default:
+			s.waiters.Remove(elem)
+			s.notifyWaiters()
+		}
+		s.mu.Unlock()
+		return false
+	}
+}
+
+// release returns n units and wakes the waiters that now fit
+func (s *weightedSemaphore) release(n int) {
+	n = min(n, s.size)
+
+	s.mu.Lock()
+	s.cur -= n
+	s.notifyWaiters()
+	s.mu.Unlock()
+}
+
+// notifyWaiters grants units to waiters in FIFO order while they fit. Caller must hold mu.
+func (s *weightedSemaphore) notifyWaiters() {
+	for {
+		front := s.waiters.Front()
+		if front == nil {
+			return
+		}
+		w := front.Value.(*semaphoreWaiter)
+		if s.cur+w.n > s.size {
+			return
+		}
+		s.cur += w.n
+		s.waiters.Remove(front)
+		close(w.ready)
+	}
+}
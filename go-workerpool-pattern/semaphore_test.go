@@ -0,0 +1,49 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestMaxInFlightCapsProcessing runs a batch with more or fewer workers than MaxInFlight and
+// checks that no more than MaxInFlight tasks are ever inside Process at once
+func TestMaxInFlightCapsProcessing(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		maxInFlight int
+		wantPeak    int32
+	}{
+		{"more workers than MaxInFlight", 16, 4, 4},
+		{"a single task at a time", 8, 1, 1},
+		{"fewer workers than MaxInFlight", 2, 4, 2},
+		{"no MaxInFlight", 6, 0, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var running, peak atomic.Int32
+			tasks := make([]Task, 40)
+			for i := range tasks {
+				tasks[i] = Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+					n := running.Add(1)
+					for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+					}
+					time.Sleep(5 * time.Millisecond)
+					running.Add(-1)
+					return nil, nil
+				}}
+			}
+			wp := WorkerPool{Tasks: tasks, Concurrency: tt.concurrency, MaxInFlight: tt.maxInFlight}
+			if err := wp.Run(); err != nil {
+				t.Fatal(err)
+			}
+			if got := peak.Load(); got != tt.wantPeak {
+				t.Errorf("peak of %d tasks in Process, want %d", got, tt.wantPeak)
+			}
+			if got := wp.Stats().Processed; got != int64(len(tasks)) {
+				t.Errorf("processed %d tasks, want %d", got, len(tasks))
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+/*
+Ordered shutdown of the WorkerPool workers.
+By default every worker exits on its own once the task channels are closed, in no particular
+order. With a ShutdownOrder each worker instead parks on its own stop channel after the last
+task, and the pool releases them one at a time in the configured order, reporting how many
+tasks each worker slot processed. This makes teardown (e.g. closing per-worker connections)
+deterministic.
+*/
+
+// ShutdownOrder is the order in which workers are stopped when the pool shuts down
+type ShutdownOrder int
+
+const (
+	ShutdownConcurrent   ShutdownOrder = iota // Workers stop on their own, in no particular order
+	ShutdownHighestFirst                      // Workers stop one at a time, highest index first
+	ShutdownLowestFirst                       // Workers stop one at a time, lowest index first
+)
+
+// workerStops holds the per-worker stop channels used by an ordered shutdown
+type workerStops struct {
+	stop    []chan struct{} // Closed to release a parked worker
+	stopped chan struct{}   // Receives once a released worker has exited
+	tasks   []int           // Tasks processed per worker slot, across recycled workers
+}
+
+// newWorkerStops creates the stop channels of n workers
+func newWorkerStops(n int) *workerStops {
+	ws := &workerStops{stop: make([]chan struct{}, n), stopped: make(chan struct{}), tasks: make([]int, n)}
+	for i := range ws.stop {
+		ws.stop[i] = make(chan struct{})
+	}
+	return ws
+}
+
+// retire parks a worker that ran out of tasks until its turn to stop comes
+func (wp *WorkerPool) retire(id int) {
+	if wp.stops == nil {
+		return
+	}
+	<-wp.stops.stop[id]
+	wp.stops.stopped <- struct{}{}
+}
+
+// stopWorkers releases the parked workers one at a time in ShutdownOrder, waiting for each to
+// exit and reporting its task count to OnWorkerStop. The task channels must already be closed.
+func (wp *WorkerPool) stopWorkers() {
+	if wp.stops == nil {
+		return
+	}
+	n := len(wp.stops.stop)
+	for i := 0; i < n; i++ {
+		id := i
+		if wp.ShutdownOrder == ShutdownHighestFirst {
+			id = n - 1 - i
+		}
+		close(wp.stops.stop[id])
+		<-wp.stops.stopped
+		if wp.OnWorkerStop != nil {
+			wp.OnWorkerStop(id, wp.stops.tasks[id])
+		}
+	}
+}
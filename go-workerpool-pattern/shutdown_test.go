@@ -0,0 +1,56 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestShutdownOrder pins tasks to workers with a Partitioner and checks that OnWorkerStop
+// reports the workers in the configured order, each with the number of tasks its slot processed
+func TestShutdownOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		order       ShutdownOrder
+		concurrency int
+		tasks       int
+		maxTasks    int // MaxTasksPerWorker, recycled workers report into the same slot
+		wantOrder   []int
+		wantTasks   []int // Tasks reported per worker, by index
+	}{
+		{"highest first", ShutdownHighestFirst, 4, 10, 0, []int{3, 2, 1, 0}, []int{3, 3, 2, 2}},
+		{"lowest first", ShutdownLowestFirst, 4, 10, 0, []int{0, 1, 2, 3}, []int{3, 3, 2, 2}},
+		{"single worker", ShutdownHighestFirst, 1, 5, 0, []int{0}, []int{5}},
+		{"idle workers", ShutdownHighestFirst, 6, 3, 0, []int{5, 4, 3, 2, 1, 0}, []int{1, 1, 1, 0, 0, 0}},
+		{"recycled workers", ShutdownLowestFirst, 3, 9, 1, []int{0, 1, 2}, []int{3, 3, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			var order []int
+			reported := make([]int, tt.concurrency)
+			wp := &WorkerPool{
+				Concurrency:       tt.concurrency,
+				ShutdownOrder:     tt.order,
+				MaxTasksPerWorker: tt.maxTasks,
+				Partitioner:       func(task Task, n int) int { return task.Id % n },
+				OnWorkerStop: func(worker, tasks int) {
+					order = append(order, worker)
+					reported[worker] = tasks
+				},
+			}
+			for id := range tt.tasks {
+				wp.Tasks = append(wp.Tasks, Task{Id: id, Work: func(done <-chan struct{}) (any, error) { return nil, nil }})
+			}
+			wp.Run()
+
+			if !slices.Equal(order, tt.wantOrder) {
+				t.Errorf("workers stopped in order %v, want %v", order, tt.wantOrder)
+			}
+			if !slices.Equal(reported, tt.wantTasks) {
+				t.Errorf("workers reported %v tasks, want %v", reported, tt.wantTasks)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+/*
+Single-producer single-consumer ring buffer Queue for the WorkerPool.
+With exactly one goroutine submitting and a single worker, the queue does not need a lock or a
+channel: the producer only writes the tail index and the consumer only writes the head index, so
+both sides hand tasks over with a few atomic loads and stores and never contend on a mutex. A
+waiting side spins, yielding the processor, and backs off to short sleeps when the wait drags on.
+The speed comes from that strict contract. A second producer or consumer would corrupt the ring,
+so the queue detects overlapping calls and panics instead of losing tasks silently.
+*/
+
+// spscSpins is how often a waiting side yields before it backs off to sleeping
+const spscSpins = 100
+
+// SpscQueue is a lock-free bounded FIFO Queue for exactly one producer and one consumer: a pool
+// with Concurrency 1, fed by a single goroutine calling Submit. SubmitAfter and FollowUp push
+// from other goroutines and must not be combined with it. A Push overlapping another Push, or a
+// Pop overlapping another Pop, panics; calls that merely alternate between goroutines cannot be
+// told apart from a single producer, so the check is best-effort.
+type SpscQueue struct {
+	buf    []Task
+	closed atomic.Bool
+
+	// each side's fields sit on their own cache line, so the producer and the consumer do not
+	// invalidate each other's cache on every operation (false sharing)
+	_          [64]byte
+	head       atomic.Uint64 // Next slot to pop, written by the consumer only
+	cachedTail uint64        // Consumer's last view of tail, refreshed only when the ring looks empty
+	popping    atomic.Bool   // Set while a Pop runs, to detect a second consumer
+	_          [64]byte
+	tail       atomic.Uint64 // Next slot to push, written by the producer only
+	cachedHead uint64        // Producer's last view of head, refreshed only when the ring looks full
+	pushing    atomic.Bool   // Set while a Push runs, to detect a second producer
+	_          [64]byte
+}
+
+// NewSpscQueue returns a single-producer single-consumer Queue holding up to capacity tasks
+// (at least 1)
+func NewSpscQueue(capacity int) *SpscQueue {
+	return &SpscQueue{buf: make([]Task, max(capacity, 1))}
+}
+
+// Push adds a task, waiting while the ring is full. It panics if another Push is in progress.
+func (q *SpscQueue) Push(task Task) {
+	if !q.pushing.CompareAndSwap(false, true) {
+		panic("SpscQueue: concurrent Push, the queue allows a single producer")
+	}
+
+	size := uint64(len(q.buf))
+	tail := q.tail.Load()
+	for spins := 0; tail-q.cachedHead >= size; spins++ {
+		if spins > 0 {
+			spscWait(spins)
+		}
+		q.cachedHead = q.head.Load()
+	}
+	q.buf[tail%size] = task
+	q.tail.Store(tail + 1) // publishes the slot to the consumer
+	q.pushing.Store(false)
+}
+
+// Pop removes the oldest task, waiting until one is available. It returns false once the queue
+// is closed and empty, and panics if another Pop is in progress.
+func (q *SpscQueue) Pop() (Task, bool) {
+	if !q.popping.CompareAndSwap(false, true) {
+		panic("SpscQueue: concurrent Pop, the queue allows a single consumer")
+	}
+
+	size := uint64(len(q.buf))
+	head := q.head.Load()
+	for spins := 0; head == q.cachedTail; spins++ {
+		if spins > 0 {
+			// no Push follows Close, so an empty closed queue stays empty
+			if q.closed.Load() && head == q.tail.Load() {
+				q.popping.Store(false)
+				return Task{}, false
+			}
+			spscWait(spins)
+		}
+		q.cachedTail = q.tail.Load()
+	}
+	task := q.buf[head%size]
+	q.buf[head%size] = Task{} // drop the reference to the task's closures
+	q.head.Store(head + 1)    // hands the slot back to the producer
+	q.popping.Store(false)
+	return task, true
+}
+
+// Len returns the number of queued tasks
+func (q *SpscQueue) Len() int {
+	head := q.head.Load()
+	return int(q.tail.Load() - head)
+}
+
+// Close lets the consumer's Pop return false once the queue is empty
+func (q *SpscQueue) Close() {
+	q.closed.Store(true)
+}
+
+// spscWait yields the processor for the first spscSpins rounds of a wait, then sleeps briefly
+// so an idle queue does not keep a core busy
+func spscWait(spins int) {
+	if spins < spscSpins {
+		runtime.Gosched()
+		return
+	}
+	time.Sleep(50 * time.Microsecond)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSpscQueueOrdering hands tasks from one producer goroutine to one consumer through a small
+// ring, so both sides keep wrapping around and waiting on each other, and fails on any lost,
+// duplicated or reordered task
+func TestSpscQueueOrdering(t *testing.T) {
+	const n = 100_000
+	q := NewSpscQueue(8)
+	go func() {
+		for i := range n {
+			q.Push(Task{Id: i})
+		}
+		q.Close()
+	}()
+
+	next := 0
+	for {
+		task, ok := q.Pop()
+		if !ok {
+			break
+		}
+		if task.Id != next {
+			t.Fatalf("popped task %d, want %d", task.Id, next)
+		}
+		next++
+	}
+	if next != n {
+		t.Fatalf("popped %d tasks, want %d", next, n)
+	}
+	if q.Len() != 0 {
+		t.Fatalf("Len() = %d after draining, want 0", q.Len())
+	}
+}
+
+// TestSpscQueueSecondProducerPanics overlaps a Push with another one blocked on the full ring
+func TestSpscQueueSecondProducerPanics(t *testing.T) {
+	q := NewSpscQueue(1)
+	q.Push(Task{Id: 1})
+	blocked := make(chan struct{})
+	go func() {
+		defer close(blocked)
+		q.Push(Task{Id: 2}) // waits for the consumer while holding the producer side
+	}()
+	for !q.pushing.Load() {
+		time.Sleep(time.Millisecond)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("a Push overlapping another Push did not panic")
+			}
+		}()
+		q.Push(Task{Id: 3})
+	}()
+
+	for _, want := range []int{1, 2} {
+		if task, _ := q.Pop(); task.Id != want {
+			t.Fatalf("popped task %d, want %d", task.Id, want)
+		}
+	}
+	<-blocked
+}
+
+// benchmarkHandOff measures handing b.N tasks from one producer goroutine to one consumer
+func benchmarkHandOff(b *testing.B, q Queue) {
+	b.ReportAllocs()
+	go func() {
+		for i := range b.N {
+			q.Push(Task{Id: i})
+		}
+		q.Close()
+	}()
+	for {
+		if _, ok := q.Pop(); !ok {
+			return
+		}
+	}
+}
+
+func BenchmarkSpscQueue(b *testing.B) {
+	benchmarkHandOff(b, NewSpscQueue(1024))
+}
+
+func BenchmarkChanQueue(b *testing.B) {
+	benchmarkHandOff(b, NewFIFOQueue(1024))
+}
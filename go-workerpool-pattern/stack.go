@@ -0,0 +1,127 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+/*
+Bounded LIFO queue used by WorkerPool when Stack is set, or priority queue when Prioritize is set.
+A channel can only hand out tasks in FIFO order, so the stack keeps the queued tasks in a
+mutex-protected slice and workers wait on a condition variable until a task is pushed.
+Pushing blocks while the stack is full, just like sending to the bounded task channel.
+In priority mode (see priority.go) pop takes the task with the highest rank instead of the newest.
+*/
+
+// taskStack is a bounded, blocking last-in first-out queue of tasks
+type taskStack struct {
+	mu       sync.Mutex
+	cond     *sync.Cond // Signalled when a task is pushed or popped, or the stack is closed
+	items    []Task
+	capacity int
+	closed   bool
+
+	rank  func(Task) float64 // Ranks a task when it is pushed, nil pops in LIFO order
+	ranks []float64          // Rank of each queued task in priority mode, parallel to items
+}
+
+// newTaskStack creates a stack holding at most capacity queued tasks
+func newTaskStack(capacity int) *taskStack {
+	s := &taskStack{capacity: capacity}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Push adds a task on top of the stack, blocking while the stack is full
+func (s *taskStack) Push(task Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.items) >= s.capacity {
+		s.cond.Wait()
+	}
+	s.add(task)
+}
+
+// pushWithin adds a task like Push but gives up once the stack stayed full until timeout
+// fires, reporting whether the task was pushed
+func (s *taskStack) pushWithin(task Task, timeout <-chan time.Time) bool {
+	expired := false // guarded by mu
+	pushed := make(chan struct{})
+	defer close(pushed)
+	go func() {
+		select {
+		case <-timeout:
+		case <-pushed:
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		expired = true
+		s.cond.Broadcast()
+	}()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.items) >= s.capacity {
+		if expired {
+			return false
+		}
+		s.cond.Wait()
+	}
+	s.add(task)
+	return true
+}
+
+// add puts a task on top of the stack. Caller must hold mu and have checked the capacity.
+func (s *taskStack) add(task Task) {
+	s.items = append(s.items, task)
+	if s.rank != nil {
+		s.ranks = append(s.ranks, s.rank(task))
+	}
+	// wake the waiters, both idle workers and blocked producers wait on the same condition
+	s.cond.Broadcast()
+}
+
+// Pop removes the most recently pushed task, or the highest ranked one in priority mode (the
+// first pushed among equals), blocking until one is available.
+// It returns false once the stack is closed and empty.
+func (s *taskStack) Pop() (Task, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.items) == 0 {
+		if s.closed {
+			return Task{}, false
+		}
+		s.cond.Wait()
+	}
+	next := len(s.items) - 1
+	if s.rank != nil {
+		next = 0
+		for i, r := range s.ranks {
+			if r > s.ranks[next] {
+				next = i
+			}
+		}
+		s.ranks = slices.Delete(s.ranks, next, next+1)
+	}
+	task := s.items[next]
+	s.items = slices.Delete(s.items, next, next+1) // zeroes the vacated slot, dropping the reference to the task's Work
+	s.cond.Broadcast()
+	return task, true
+}
+
+// Len returns the number of queued tasks
+func (s *taskStack) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+// Close wakes all workers so they exit once the stack is empty
+func (s *taskStack) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.cond.Broadcast()
+}
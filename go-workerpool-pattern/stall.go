@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+/*
+Stall watchdog of the WorkerPool.
+A bounded queue can deadlock the pool: every worker blocks inside Process waiting for something
+that only a queued task would provide, the queue is full and the producer blocks in Submit.
+Nothing crashes, the program simply hangs. With StallTimeout set, a watchdog notices that tasks
+are outstanding but none has completed for that long, captures the stacks of all goroutines to
+show where everything is blocked, and cancels the pool so the run returns a StallError instead
+of hanging forever.
+*/
+
+// watchStalls cancels the pool with a StallError once tasks are outstanding but none completed
+// for StallTimeout, until the pool has shut down. A paused pool is never considered stalled.
+func (wp *WorkerPool) watchStalls() {
+	clock := wp.clock()
+	ticker := clock.NewTicker(max(wp.StallTimeout/4, time.Millisecond))
+	defer ticker.Stop()
+
+	last, since := wp.progress.count(), clock.Now()
+	for {
+		select {
+		case <-ticker.C():
+		case <-wp.done:
+			return
+		}
+
+		done, now, pending := wp.progress.count(), clock.Now(), wp.idle.outstanding()
+		if done != last || pending <= 0 || wp.Paused() {
+			last, since = done, now
+			continue
+		}
+		if stalled := now.Sub(since); stalled >= wp.StallTimeout {
+			err := &StallError{Pending: pending, Stalled: stalled, Stacks: goroutineStacks()}
+			Log.Error("worker pool stalled", slog.Int("pending", pending), slog.Duration("stalled", stalled))
+			wp.mu.Lock()
+			wp.stall = err
+			wp.cancel()
+			wp.mu.Unlock()
+			return
+		}
+	}
+}
+
+// Stalled returns the StallError reported by the StallTimeout watchdog, nil if the pool never
+// stalled. RunWithContext returns the same error.
+func (wp *WorkerPool) Stalled() error {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	if wp.stall == nil {
+		return nil
+	}
+	return wp.stall
+}
+
+// goroutineStacks returns the stack traces of all goroutines, like an unrecovered panic prints them
+func goroutineStacks() string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
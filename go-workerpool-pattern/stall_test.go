@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestStallWatchdog advances a FakeClock under pools that are deadlocked, paused or slowly making
+// progress and checks that only the deadlocked one is cancelled with a StallError
+func TestStallWatchdog(t *testing.T) {
+	const stallTimeout = time.Minute
+	tests := []struct {
+		name      string
+		queue     Queue
+		tasks     func(clock *FakeClock) []Task
+		pause     bool // Pause the pool for ten StallTimeouts before letting it run
+		wantStall bool
+	}{
+		{
+			// task 1 waits for a signal only task 3 sends, but task 3 is stuck behind it: with a
+			// single worker and a queue of one, the worker, the queue and the producer all block
+			name:  "deadlocked bounded queue",
+			queue: NewFIFOQueue(1),
+			tasks: func(clock *FakeClock) []Task {
+				ready := make(chan struct{})
+				return []Task{
+					{Id: 1, Work: func(done <-chan struct{}) (any, error) {
+						select {
+						case <-ready:
+							return nil, nil
+						case <-done:
+							return nil, ErrTaskCancelled
+						}
+					}},
+					{Id: 2, Work: func(done <-chan struct{}) (any, error) { return nil, nil }},
+					{Id: 3, Work: func(done <-chan struct{}) (any, error) { close(ready); return nil, nil }},
+				}
+			},
+			wantStall: true,
+		},
+		{
+			name: "slow tasks making progress",
+			tasks: func(clock *FakeClock) []Task {
+				tasks := make([]Task, 6)
+				for i := range tasks {
+					tasks[i] = Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+						<-clock.After(stallTimeout / 2)
+						return nil, nil
+					}}
+				}
+				return tasks
+			},
+		},
+		{
+			name: "paused pool",
+			tasks: func(clock *FakeClock) []Task {
+				return []Task{{Id: 1, Work: func(done <-chan struct{}) (any, error) { return nil, nil }}}
+			},
+			pause: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			clock := NewFakeClock(time.Unix(0, 0))
+			wp := &WorkerPool{Concurrency: 1, Queue: tt.queue, Clock: clock, StallTimeout: stallTimeout, Tasks: tt.tasks(clock)}
+			if tt.pause {
+				wp.Pause()
+			}
+			errc := make(chan error, 1)
+			go func() {
+				_, err := wp.RunWithContext(context.Background())
+				errc <- err
+			}()
+
+			var err error
+			finished := func() bool {
+				select {
+				case err = <-errc:
+					return true
+				default:
+					return false
+				}
+			}
+			if tt.pause {
+				resumeAt := clock.Now().Add(10 * stallTimeout)
+				advanceUntil(t, clock, stallTimeout/4, func() bool { return !clock.Now().Before(resumeAt) })
+				if finished() {
+					t.Fatalf("paused run returned %v", err)
+				}
+				wp.Resume()
+			}
+			advanceUntil(t, clock, stallTimeout/4, finished)
+
+			var stall *StallError
+			if !tt.wantStall {
+				if err != nil || wp.Stalled() != nil {
+					t.Fatalf("run returned %v, Stalled() = %v; want no stall", err, wp.Stalled())
+				}
+				return
+			}
+			if !errors.As(err, &stall) || !errors.Is(err, ErrPossibleDeadlock) {
+				t.Fatalf("run returned %v, want a StallError", err)
+			}
+			if stall.Stalled < stallTimeout || stall.Pending == 0 || !strings.Contains(stall.Stacks, "goroutine ") {
+				t.Errorf("StallError reports %d pending for %v with %d bytes of stacks, want pending tasks stalled for at least %v and a goroutine dump",
+					stall.Pending, stall.Stalled, len(stall.Stacks), stallTimeout)
+			}
+			if !errors.Is(wp.Stalled(), ErrPossibleDeadlock) {
+				t.Errorf("Stalled() = %v, want the StallError", wp.Stalled())
+			}
+		})
+	}
+}
+
+// TestStalledStreaming runs the watchdog on a streaming pool and checks that a producer
+// deadlocked in Submit gets Stalled() reported and is released by the cancellation, while a pool
+// idling with nothing outstanding is never considered stalled
+func TestStalledStreaming(t *testing.T) {
+	const stallTimeout = time.Minute
+	tests := []struct {
+		name      string
+		deadlock  bool // Submit the deadlocking tasks of TestStallWatchdog, or nothing
+		wantStall bool
+	}{
+		{"producer deadlocked in Submit", true, true},
+		{"idle pool", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			clock := NewFakeClock(time.Unix(0, 0))
+			wp := &WorkerPool{Concurrency: 1, Queue: NewFIFOQueue(1), Clock: clock, StallTimeout: stallTimeout}
+			if err := wp.Start(); err != nil {
+				t.Fatal(err)
+			}
+			submitted := make(chan struct{})
+			go func() {
+				defer close(submitted)
+				if !tt.deadlock {
+					return
+				}
+				// task 1 waits for task 3, which the producer cannot queue behind task 2
+				ready := make(chan struct{})
+				wp.Submit(Task{Id: 1, Work: func(done <-chan struct{}) (any, error) {
+					select {
+					case <-ready:
+						return nil, nil
+					case <-done:
+						return nil, ErrTaskCancelled
+					}
+				}})
+				wp.Submit(Task{Id: 2, Work: func(done <-chan struct{}) (any, error) { return nil, nil }})
+				wp.Submit(Task{Id: 3, Work: func(done <-chan struct{}) (any, error) { close(ready); return nil, nil }})
+			}()
+
+			if tt.wantStall {
+				advanceUntil(t, clock, stallTimeout/4, func() bool { return wp.Stalled() != nil })
+			} else {
+				for end := clock.Now().Add(10 * stallTimeout); clock.Now().Before(end); {
+					clock.Advance(stallTimeout / 4)
+					time.Sleep(time.Millisecond)
+				}
+			}
+			select {
+			case <-submitted:
+			case <-time.After(5 * time.Second):
+				t.Fatal("producer still blocked in Submit")
+			}
+			wp.Close()
+
+			if stalled := wp.Stalled(); errors.Is(stalled, ErrPossibleDeadlock) != tt.wantStall {
+				t.Errorf("Stalled() = %v, want a StallError %v", stalled, tt.wantStall)
+			}
+		})
+	}
+}
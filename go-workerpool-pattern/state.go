@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+)
+
+/*
+Persisting a WorkerPool batch across restarts.
+Work functions cannot be serialized, so the state only records task Ids: which completed
+successfully and which did not. After a restart the program rebuilds the same Tasks, loads the
+state and runs again; tasks whose Id already succeeded are skipped, so only unfinished and failed
+work is redone.
+*/
+
+// batchState is the serialized form of a batch's progress
+type batchState struct {
+	Completed  []int `json:"completed"`  // Ids that finished successfully, in this or an earlier run
+	Unfinished []int `json:"unfinished"` // Ids that did not finish or failed, for information
+}
+
+// SaveState writes the Ids of the successful and the other tasks as JSON, so an interrupted batch
+// can be resumed with LoadState. Tasks that failed are saved as unfinished and run again on
+// resume. It may be called while the pool runs or after it stopped; tasks completed in a run
+// resumed from an earlier state are included.
+func (wp *WorkerPool) SaveState(w io.Writer) error {
+	done, unfinished := wp.batch.succeeded()
+	completed := make(map[int]bool)
+	for id := range wp.resumed {
+		completed[id] = true
+	}
+	for _, id := range done {
+		completed[id] = true
+	}
+
+	// tasks of the batch that were not submitted yet are unfinished as well
+	state := batchState{Completed: []int{}, Unfinished: []int{}}
+	seen := make(map[int]bool)
+	for _, id := range append(unfinished, taskIds(wp.Tasks)...) {
+		if !completed[id] && !seen[id] {
+			seen[id] = true
+			state.Unfinished = append(state.Unfinished, id)
+		}
+	}
+	for id := range completed {
+		state.Completed = append(state.Completed, id)
+	}
+	slices.Sort(state.Completed)
+	return json.NewEncoder(w).Encode(state)
+}
+
+// LoadState reads a state written by SaveState. The next Run, RunWithContext or RunWhere skips
+// the tasks whose Id succeeded according to the state; they are not processed or reported.
+// Call it before running the pool.
+func (wp *WorkerPool) LoadState(r io.Reader) error {
+	var state batchState
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return err
+	}
+	wp.resumed = make(map[int]bool, len(state.Completed))
+	for _, id := range state.Completed {
+		wp.resumed[id] = true
+	}
+	return nil
+}
+
+// taskIds returns the Ids of the given tasks in order
+func taskIds(tasks []Task) []int {
+	ids := make([]int, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.Id
+	}
+	return ids
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// TestSaveLoadStateRoundTrip saves the state of a batch with failing and cancelled tasks, loads it
+// into a fresh pool and checks that exactly the failed and unfinished tasks run again
+func TestSaveLoadStateRoundTrip(t *testing.T) {
+	tests := []struct {
+		name           string
+		fail           []int // Ids whose first run fails
+		cancelAt       int   // Id whose first run cancels the batch, 0 to let it complete
+		wantCompleted  []int
+		wantUnfinished []int
+	}{
+		{name: "all succeed", wantCompleted: []int{1, 2, 3, 4, 5, 6}, wantUnfinished: []int{}},
+		{name: "failing task", fail: []int{3}, wantCompleted: []int{1, 2, 4, 5, 6}, wantUnfinished: []int{3}},
+		{name: "failing and cancelled", fail: []int{2}, cancelAt: 4, wantCompleted: []int{1, 3}, wantUnfinished: []int{2, 4, 5, 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var mu sync.Mutex
+			var ran []int
+			newTasks := func(first bool) []Task {
+				tasks := make([]Task, 6)
+				for i := range tasks {
+					id := i + 1
+					tasks[i] = Task{Id: id, Work: func(done <-chan struct{}) (any, error) {
+						mu.Lock()
+						ran = append(ran, id)
+						mu.Unlock()
+						switch {
+						case first && id == tt.cancelAt:
+							cancel()
+							return nil, ErrTaskCancelled
+						case first && slices.Contains(tt.fail, id):
+							return nil, errors.New("import failed")
+						}
+						return nil, nil
+					}}
+				}
+				return tasks
+			}
+
+			// Concurrency 1 processes the tasks in order, so the cancellation point is fixed
+			first := WorkerPool{Tasks: newTasks(true), Concurrency: 1}
+			_, _ = first.RunWithContext(ctx)
+			var saved bytes.Buffer
+			if err := first.SaveState(&saved); err != nil {
+				t.Fatal(err)
+			}
+			var state batchState
+			if err := json.Unmarshal(saved.Bytes(), &state); err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(state.Unfinished)
+			if !slices.Equal(state.Completed, tt.wantCompleted) || !slices.Equal(state.Unfinished, tt.wantUnfinished) {
+				t.Fatalf("saved completed %v, unfinished %v; want %v, %v",
+					state.Completed, state.Unfinished, tt.wantCompleted, tt.wantUnfinished)
+			}
+
+			ran = nil
+			resumed := WorkerPool{Tasks: newTasks(false), Concurrency: 1}
+			if err := resumed.LoadState(&saved); err != nil {
+				t.Fatal(err)
+			}
+			resumed.Run()
+			if !slices.Equal(ran, tt.wantUnfinished) {
+				t.Errorf("resumed run processed %v, want %v", ran, tt.wantUnfinished)
+			}
+			var after bytes.Buffer
+			if err := resumed.SaveState(&after); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(after.Bytes(), &state); err != nil {
+				t.Fatal(err)
+			}
+			if len(state.Completed) != 6 || len(state.Unfinished) != 0 {
+				t.Errorf("state after resume has completed %v, unfinished %v, want all 6 completed",
+					state.Completed, state.Unfinished)
+			}
+		})
+	}
+}
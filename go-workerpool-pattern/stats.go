@@ -0,0 +1,86 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+/*
+Runtime statistics of the single-type WorkerPool.
+Counters are updated atomically by the workers, so Stats can be called at any time,
+including while tasks are being processed.
+*/
+
+// PoolStats is a snapshot of the WorkerPool counters
+type PoolStats struct {
+	Processed   int64         // Tasks that finished processing, successfully or not
+	Failed      int64         // Processed tasks that ended with an error
+	AvgDuration time.Duration // Average processing time of the processed tasks, retries included
+	P50         time.Duration // Median processing time, from the Durations histogram
+	P95         time.Duration // 95th percentile processing time
+	P99         time.Duration // 99th percentile processing time
+	Hedged      int64         // Attempts for which a hedge duplicate was dispatched
+	HedgeWins   int64         // Hedged attempts won by the duplicate rather than the original
+	RetriesLeft int64         // Retries left in the RetryBudget, -1 when unlimited
+	Dropped     int64         // Results dropped because the consumer did not take them within ResultTimeout
+	Pruned      int64         // Follow-up tasks not enqueued because of MaxFollowUpDepth or MaxFollowUps
+	Expired     int64         // Tasks dropped because they waited longer than MaxQueueAge
+	Concurrency int           // Tasks allowed to be processed at once, tuned over time with MaxConcurrency
+}
+
+// poolCounters holds the live counters behind PoolStats
+type poolCounters struct {
+	processed  atomic.Int64
+	failed     atomic.Int64
+	totalNanos atomic.Int64
+	hedged     atomic.Int64
+	hedgeWins  atomic.Int64
+	retries    atomic.Int64 // Retries taken from the RetryBudget, may overshoot it by rejected attempts
+	dropped    atomic.Int64 // Results dropped after ResultTimeout
+	pruned     atomic.Int64 // Follow-ups over the FollowUp limits
+	expired    atomic.Int64 // Tasks dropped after MaxQueueAge
+	durations  DurationHistogram
+}
+
+// record counts a processed task
+func (c *poolCounters) record(elapsed time.Duration, err error) {
+	c.processed.Add(1)
+	c.totalNanos.Add(int64(elapsed))
+	c.durations.Observe(elapsed)
+	if err != nil {
+		c.failed.Add(1)
+	}
+}
+
+// Stats returns a snapshot of the pool's counters
+func (wp *WorkerPool) Stats() PoolStats {
+	stats := PoolStats{
+		Processed: wp.counters.processed.Load(),
+		Failed:    wp.counters.failed.Load(),
+		Hedged:    wp.counters.hedged.Load(),
+		HedgeWins: wp.counters.hedgeWins.Load(),
+		Dropped:   wp.counters.dropped.Load(),
+		Pruned:    wp.counters.pruned.Load(),
+		Expired:   wp.counters.expired.Load(),
+	}
+	stats.Concurrency = wp.workerCount()
+	if wp.adaptive != nil {
+		stats.Concurrency = wp.adaptive.current()
+	}
+	stats.RetriesLeft = -1
+	if wp.RetryBudget > 0 {
+		stats.RetriesLeft = max(int64(wp.RetryBudget)-wp.counters.retries.Load(), 0)
+	}
+	if stats.Processed > 0 {
+		stats.AvgDuration = time.Duration(wp.counters.totalNanos.Load() / stats.Processed)
+		stats.P50 = wp.counters.durations.Percentile(50)
+		stats.P95 = wp.counters.durations.Percentile(95)
+		stats.P99 = wp.counters.durations.Percentile(99)
+	}
+	return stats
+}
+
+// Durations returns the live histogram of task processing times, for other percentiles
+func (wp *WorkerPool) Durations() *DurationHistogram {
+	return &wp.counters.durations
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+Drain-and-report summary returned by NewWorkerPool.Run.
+Timestamps are kept in atomics so workers can record completions without a lock,
+per-type counters and the longest task are guarded by a mutex.
+*/
+
+// TypeStats holds the counters of a single task type
+type TypeStats struct {
+	Count  int // Number of tasks of this type that were dispatched
+	Failed int // Number of tasks of this type that failed or were fast-failed
+
+	LastError           error // Most recent error of this type, kept after later successes
+	ConsecutiveFailures int   // Failures since the last success of this type, reset to 0 by a success
+}
+
+// Summary describes a completed batch of the multi-type worker pool
+type Summary struct {
+	Total           int                  // Total number of tasks in the batch
+	ByType          map[string]TypeStats // Counters per task type
+	WallClock       time.Duration        // Time from the first dispatch to the last completion
+	Longest         MultiTask            // Task that took the longest to process
+	LongestDuration time.Duration        // Processing time of the longest task
+}
+
+// summaryTracker collects the data for a Summary while the pool is running
+type summaryTracker struct {
+	firstDispatch  atomic.Int64 // Unix nanoseconds of the first dispatch
+	lastCompletion atomic.Int64 // Unix nanoseconds of the latest completion
+
+	mu              sync.Mutex
+	byType          map[string]TypeStats
+	longest         MultiTask
+	longestDuration time.Duration
+}
+
+// dispatched marks the start of the batch, only the first call has an effect
+func (st *summaryTracker) dispatched() {
+	st.firstDispatch.CompareAndSwap(0, time.Now().UnixNano())
+}
+
+// completed records a finished task with its processing time and outcome
+func (st *summaryTracker) completed(task MultiTask, d time.Duration, err error) {
+	now := time.Now().UnixNano()
+	for {
+		last := st.lastCompletion.Load()
+		if now <= last || st.lastCompletion.CompareAndSwap(last, now) {
+			break
+		}
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.byType == nil {
+		st.byType = make(map[string]TypeStats)
+	}
+	stats := st.byType[typeNameOf(task)]
+	stats.Count++
+	if err != nil {
+		stats.Failed++
+		stats.LastError = err
+		stats.ConsecutiveFailures++
+	} else {
+		stats.ConsecutiveFailures = 0
+	}
+	st.byType[typeNameOf(task)] = stats
+	if st.longest == nil || d > st.longestDuration {
+		st.longest = task
+		st.longestDuration = d
+	}
+}
+
+// summary builds the Summary of the batch
+func (st *summaryTracker) summary(total int) Summary {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	byType := make(map[string]TypeStats, len(st.byType))
+	for name, stats := range st.byType {
+		byType[name] = stats
+	}
+	s := Summary{
+		Total:           total,
+		ByType:          byType,
+		Longest:         st.longest,
+		LongestDuration: st.longestDuration,
+	}
+	if first, last := st.firstDispatch.Load(), st.lastCompletion.Load(); first != 0 && last >= first {
+		s.WallClock = time.Duration(last - first)
+	}
+	return s
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// outcomeTask is a MultiTask of a given type that fails with err, if set
+type outcomeTask struct {
+	kind string
+	err  error
+}
+
+func (o *outcomeTask) Process()          {}
+func (o *outcomeTask) ProcessErr() error { return o.err }
+func (o *outcomeTask) TypeName() string  { return o.kind }
+
+// TestFailureStreaks runs sequences of successes and failures on a single worker and checks
+// per type that a failure streak increments ConsecutiveFailures, a success resets it, and
+// LastError keeps the latest error either way
+func TestFailureStreaks(t *testing.T) {
+	fail := func(kind string, n int) *outcomeTask {
+		return &outcomeTask{kind: kind, err: fmt.Errorf("%s %d: data source timeout", kind, n)}
+	}
+	ok := func(kind string) *outcomeTask { return &outcomeTask{kind: kind} }
+	tests := []struct {
+		name    string
+		tasks   []MultiTask
+		want    map[string]TypeStats
+		lastErr map[string]string // Message of LastError per type, empty for nil
+	}{
+		{"ongoing streak", []MultiTask{ok("report"), fail("report", 1), fail("report", 2), fail("report", 3)},
+			map[string]TypeStats{"report": {Count: 4, Failed: 3, ConsecutiveFailures: 3}},
+			map[string]string{"report": "report 3: data source timeout"}},
+		{"success resets the streak", []MultiTask{fail("report", 1), fail("report", 2), ok("report")},
+			map[string]TypeStats{"report": {Count: 3, Failed: 2, ConsecutiveFailures: 0}},
+			map[string]string{"report": "report 2: data source timeout"}},
+		{"new streak after a success", []MultiTask{fail("report", 1), ok("report"), fail("report", 2)},
+			map[string]TypeStats{"report": {Count: 3, Failed: 2, ConsecutiveFailures: 1}},
+			map[string]string{"report": "report 2: data source timeout"}},
+		{"no failures", []MultiTask{ok("report"), ok("report")},
+			map[string]TypeStats{"report": {Count: 2}},
+			map[string]string{"report": ""}},
+		{"streaks are per type", []MultiTask{fail("email", 1), ok("report"), fail("email", 2), fail("report", 1), ok("email")},
+			map[string]TypeStats{"email": {Count: 3, Failed: 2, ConsecutiveFailures: 0}, "report": {Count: 2, Failed: 1, ConsecutiveFailures: 1}},
+			map[string]string{"email": "email 2: data source timeout", "report": "report 1: data source timeout"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wp := NewWorkerPool{Concurrency: 1, MultiTasks: tt.tasks}
+			summary, err := wp.Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(summary.ByType) != len(tt.want) {
+				t.Errorf("stats for %d types, want %d", len(summary.ByType), len(tt.want))
+			}
+			for kind, want := range tt.want {
+				got := summary.ByType[kind]
+				if got.Count != want.Count || got.Failed != want.Failed || got.ConsecutiveFailures != want.ConsecutiveFailures {
+					t.Errorf("%s: count %d, failed %d, consecutive %d; want %d, %d, %d", kind,
+						got.Count, got.Failed, got.ConsecutiveFailures, want.Count, want.Failed, want.ConsecutiveFailures)
+				}
+				switch msg := tt.lastErr[kind]; {
+				case msg == "" && got.LastError != nil:
+					t.Errorf("%s: LastError = %v, want nil", kind, got.LastError)
+				case msg != "" && (got.LastError == nil || got.LastError.Error() != msg):
+					t.Errorf("%s: LastError = %v, want %q", kind, got.LastError, msg)
+				}
+			}
+		})
+	}
+}
+
+// TestLastErrorIsTaskError checks that LastError is the error the task returned, so callers can
+// match it with errors.Is
+func TestLastErrorIsTaskError(t *testing.T) {
+	sentinel := errors.New("quota exceeded")
+	wp := NewWorkerPool{Concurrency: 1, MultiTasks: []MultiTask{&outcomeTask{kind: "upload", err: fmt.Errorf("upload: %w", sentinel)}}}
+	summary, err := wp.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := summary.ByType["upload"].LastError; !errors.Is(got, sentinel) {
+		t.Errorf("LastError = %v, want it to wrap %v", got, sentinel)
+	}
+}
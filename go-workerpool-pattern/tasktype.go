@@ -0,0 +1,50 @@
+package main
+
+import "reflect"
+
+/*
+Optional abilities of multi-type tasks.
+A MultiTask only has to implement Process. Tasks opt into more through extra interfaces the
+pool detects at run time, like ResultTask and CompensableTask: a FallibleTask reports failures,
+which drive the retries, the circuit breaker and the failure metrics, and a NamedTask names its
+type for the per-type weights, breaker and summary. Existing task types keep compiling unchanged.
+*/
+
+// FallibleTask is implemented by multi-type tasks that can fail. A Go type cannot have two
+// Process methods with different signatures, so the failing variant is ProcessErr; the pool
+// calls it instead of Process. Process is still required to be a MultiTask and typically calls
+// ProcessErr and drops the error.
+type FallibleTask interface {
+	MultiTask
+	ProcessErr() error
+}
+
+// NamedTask is implemented by multi-type tasks that name their type. Tasks of different Go types
+// returning the same name share the per-type weight, breaker and statistics.
+type NamedTask interface {
+	MultiTask
+	TypeName() string
+}
+
+// typeNameOf returns the TypeName of a NamedTask, or else the name of the task's Go type
+// (e.g. "EmailTask" for an *EmailTask), looking through wrappers such as WithDependencies
+func typeNameOf(task MultiTask) string {
+	if t, ok := taskAs[NamedTask](task); ok {
+		return t.TypeName()
+	}
+	for {
+		w, ok := task.(interface{ Unwrap() MultiTask })
+		if !ok {
+			break
+		}
+		task = w.Unwrap()
+	}
+	t := reflect.TypeOf(task)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Name() == "" {
+		return t.String()
+	}
+	return t.Name()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// plainTask only implements the original MultiTask interface
+type plainTask struct{ ran bool }
+
+func (p *plainTask) Process() { p.ran = true }
+
+// failingTask is a FallibleTask named "flaky"
+type failingTask struct{ err error }
+
+func (f *failingTask) Process()          { _ = f.ProcessErr() }
+func (f *failingTask) ProcessErr() error { return f.err }
+func (f *failingTask) TypeName() string  { return "flaky" }
+
+// TestMultiTaskOptionalInterfaces runs plain tasks next to tasks opting into FallibleTask and
+// NamedTask, directly and behind WithDependencies, and checks how each is counted and named
+func TestMultiTaskOptionalInterfaces(t *testing.T) {
+	errBoom := errors.New("boom")
+	tests := []struct {
+		name       string
+		task       MultiTask
+		wantFailed bool
+		wantType   string
+	}{
+		{"plain task", &plainTask{}, false, "plainTask"},
+		{"fallible task succeeding", &failingTask{}, false, "flaky"},
+		{"fallible task failing", &failingTask{err: errBoom}, true, "flaky"},
+		{"wrapped plain task", WithDependencies("a", &plainTask{}), false, "plainTask"},
+		{"wrapped fallible task", WithDependencies("b", &failingTask{err: errBoom}), true, "flaky"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := typeNameOf(tt.task); got != tt.wantType {
+				t.Errorf("typeNameOf = %q, want %q", got, tt.wantType)
+			}
+			wp := NewWorkerPool{MultiTasks: []MultiTask{tt.task}, Concurrency: 1}
+			if _, err := wp.Run(); err != nil {
+				t.Fatal(err)
+			}
+			m := wp.Metrics()
+			if failed := m.Failed == 1; failed != tt.wantFailed || m.Processed+m.Failed != 1 {
+				t.Errorf("Metrics = %d processed, %d failed, want failed %t", m.Processed, m.Failed, tt.wantFailed)
+			}
+			if p, ok := taskAs[*plainTask](tt.task); ok && !p.ran {
+				t.Error("Process of the plain task was not called")
+			}
+		})
+	}
+}
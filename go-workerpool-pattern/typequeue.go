@@ -0,0 +1,116 @@
+package main
+
+import "sync"
+
+/*
+Per-type fair sharing of the multi-type pool's workers.
+With WeightByType set, tasks are not sent down a single FIFO channel, where a long backlog of one
+type would hold every worker until it drained. Each type gets its own queue instead, and a worker
+that becomes free takes the next task from the type holding the fewest workers relative to its
+weight. While several types have work pending, each of them therefore occupies a share of the
+workers proportional to its weight; a type that has nothing pending leaves its share to the
+others, so no worker idles while any task is waiting.
+*/
+
+// typeQueues holds the pending tasks per type and the number of workers each type occupies
+type typeQueues struct {
+	mu      sync.Mutex
+	cond    *sync.Cond             // Signalled when a task is pushed or the queues are closed
+	weights map[string]int         // Share per type, missing or non-positive weights count as 1
+	types   []string               // Types in order of first appearance, breaking ties
+	pending map[string][]MultiTask // Queued tasks per type in arrival order
+	running map[string]int         // Workers occupied by each type
+	served  map[string]int         // Tasks handed out per type, breaking ties between equal shares
+	closed  bool                   // Set once no more tasks will be pushed
+}
+
+// newTypeQueues creates empty queues sharing the workers by weights
+func newTypeQueues(weights map[string]int) *typeQueues {
+	q := &typeQueues{
+		weights: weights,
+		pending: make(map[string][]MultiTask),
+		running: make(map[string]int),
+		served:  make(map[string]int),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// weight returns the share of a type
+func (q *typeQueues) weight(name string) int {
+	return max(q.weights[name], 1)
+}
+
+// push queues a task behind the other tasks of its type
+func (q *typeQueues) push(task MultiTask) {
+	name := typeNameOf(task)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.pending[name]; !ok {
+		q.types = append(q.types, name)
+	}
+	q.pending[name] = append(q.pending[name], task)
+	q.cond.Signal()
+}
+
+// close wakes the waiting workers once the queues have drained
+func (q *typeQueues) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// pop blocks until a task is pending and returns the one of the type furthest below its share
+// of the workers, counting the caller as a worker of that type until end. It reports false once
+// the queues are closed and drained.
+func (q *typeQueues) pop() (MultiTask, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		next := ""
+		for _, name := range q.types {
+			if len(q.pending[name]) == 0 {
+				continue
+			}
+			// compare running/weight and then served/weight without dividing
+			if next == "" || q.less(name, next) {
+				next = name
+			}
+		}
+		if next != "" {
+			task := q.pending[next][0]
+			q.pending[next] = q.pending[next][1:]
+			q.running[next]++
+			q.served[next]++
+			return task, true
+		}
+		if q.closed {
+			return nil, false
+		}
+		q.cond.Wait()
+	}
+}
+
+// less reports whether type a is further below its share than type b, caller must hold mu
+func (q *typeQueues) less(a, b string) bool {
+	wa, wb := q.weight(a), q.weight(b)
+	if ra, rb := q.running[a]*wb, q.running[b]*wa; ra != rb {
+		return ra < rb
+	}
+	return q.served[a]*wb < q.served[b]*wa
+}
+
+// begin counts a worker running a task it did not pop, e.g. one parked behind a ResourceKey
+func (q *typeQueues) begin(task MultiTask) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.running[typeNameOf(task)]++
+}
+
+// end releases the worker counted for a task by pop or begin
+func (q *typeQueues) end(task MultiTask) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.running[typeNameOf(task)]--
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// shareTask is a named task recording how many tasks of its type run at once
+type shareTask struct {
+	name  string
+	stats *shareStats
+}
+
+// shareStats tracks the running and finished tasks per type
+type shareStats struct {
+	mu         sync.Mutex
+	running    map[string]int
+	maxRunning map[string]int
+	finished   map[string]int
+	longDone   int // Long tasks finished when the last short task finished
+}
+
+func (s *shareTask) TypeName() string { return s.name }
+
+func (s *shareTask) Process() {
+	st := s.stats
+	st.mu.Lock()
+	st.running[s.name]++
+	st.maxRunning[s.name] = max(st.maxRunning[s.name], st.running[s.name])
+	st.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	st.mu.Lock()
+	st.running[s.name]--
+	st.finished[s.name]++
+	if s.name == "short" {
+		st.longDone = st.finished["long"]
+	}
+	st.mu.Unlock()
+}
+
+// TestWeightByTypeSharesWorkers queues a long backlog of one type ahead of a few tasks of another
+// and checks that the short type still gets its weighted share of the workers while the backlog
+// is pending, instead of waiting for it to drain
+func TestWeightByTypeSharesWorkers(t *testing.T) {
+	const workers, long, short = 4, 40, 12
+	tests := []struct {
+		name      string
+		weights   map[string]int
+		wantShare int // Workers the short type occupies at most while long tasks are pending
+	}{
+		{"equal weights", map[string]int{"long": 1, "short": 1}, 2},
+		{"short weighted higher", map[string]int{"long": 1, "short": 3}, 3},
+		{"long weighted higher", map[string]int{"long": 3, "short": 1}, 1},
+		{"missing weight counts as 1", map[string]int{"long": 1}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := &shareStats{running: map[string]int{}, maxRunning: map[string]int{}, finished: map[string]int{}}
+			var tasks []MultiTask
+			for range long {
+				tasks = append(tasks, &shareTask{name: "long", stats: stats})
+			}
+			for range short {
+				tasks = append(tasks, &shareTask{name: "short", stats: stats})
+			}
+			wp := NewWorkerPool{MultiTasks: tasks, Concurrency: workers, WeightByType: tt.weights}
+			if _, err := wp.Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := stats.maxRunning["short"]; got != tt.wantShare {
+				t.Errorf("short tasks ran on up to %d workers, want %d", got, tt.wantShare)
+			}
+			// with its share the short type finishes within short/share rounds, while the long
+			// type only got the remaining workers for those rounds
+			rounds := (short + tt.wantShare - 1) / tt.wantShare
+			if limit := rounds * (workers - tt.wantShare); stats.longDone > limit {
+				t.Errorf("%d long tasks finished before the last short one, want at most %d", stats.longDone, limit)
+			}
+			if stats.finished["long"] != long || stats.finished["short"] != short {
+				t.Errorf("finished %v, want %d long and %d short", stats.finished, long, short)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+Validation of the WorkerPool options.
+Many options only apply to one mode of the pool: custom Workers own processing, a Queue (or
+Stack, Prioritize) replaces the per-worker affinity channels, Deterministic runs a single worker.
+Instead of silently ignoring an option its mode does not support, Start and Run reject the
+combination with an error listing every conflict, so a misconfigured pool fails before running.
+*/
+
+// ErrInvalidConfig is wrapped by the errors of Validate for options that cannot be combined
+var ErrInvalidConfig = errors.New("invalid worker pool configuration")
+
+// Validate reports the options that conflict with each other, joined into one error whose parts
+// all wrap ErrInvalidConfig, or nil if the configuration is consistent. Start and Run call it
+// before starting any worker. Mutually exclusive options:
+//   - NewWorker excludes the per-task processing options: MaxRetries, TaskTimeout,
+//     CumulativeTimeout, HedgeAfter, CostBudget, MaxInFlight, MaxConcurrency, BeforeProcess,
+//     AfterProcess, Queue, Stack, Prioritize, Partitioner, RampUp, ShutdownOrder,
+//     MaxTasksPerWorker, Settings and Deterministic.
+//   - Queue, Stack and Prioritize exclude each other and the Partitioner; tasks with an Affinity
+//     cannot be submitted to them, nor to custom Workers.
+//   - Deterministic excludes Queue, Stack, Prioritize, Partitioner and MaxConcurrency; tasks
+//     with an Affinity cannot be submitted to it.
+//   - RampUp excludes a ShutdownOrder other than ShutdownConcurrent.
+//
+// Options that only refine another one need it: AgingRate needs Prioritize, MinConcurrency and
+// AdaptInterval need MaxConcurrency, OnWorkerStop needs a ShutdownOrder, MaxFollowUpDepth and
+// MaxFollowUps need FollowUp.
+func (wp *WorkerPool) Validate() error {
+	var errs []error
+	conflict := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidConfig}, args...)...))
+	}
+
+	if wp.NewWorker != nil {
+		for _, opt := range []struct {
+			name string
+			set  bool
+		}{
+			{"MaxRetries", wp.MaxRetries > 0},
+			{"TaskTimeout", wp.TaskTimeout > 0},
+			{"CumulativeTimeout", wp.CumulativeTimeout > 0},
+			{"HedgeAfter", wp.HedgeAfter > 0},
+			{"CostBudget", wp.CostBudget > 0},
+			{"MaxInFlight", wp.MaxInFlight > 0},
+			{"MaxConcurrency", wp.MaxConcurrency > 0},
+			{"BeforeProcess", wp.BeforeProcess != nil},
+			{"AfterProcess", wp.AfterProcess != nil},
+			{"Queue", wp.Queue != nil},
+			{"Stack", wp.Stack},
+			{"Prioritize", wp.Prioritize},
+			{"Partitioner", wp.Partitioner != nil},
+			{"RampUp", wp.RampUp > 0},
+			{"ShutdownOrder", wp.ShutdownOrder != ShutdownConcurrent},
+			{"MaxTasksPerWorker", wp.MaxTasksPerWorker > 0},
+			{"Settings", wp.Settings != nil},
+			{"Deterministic", wp.Deterministic},
+		} {
+			if opt.set {
+				conflict("NewWorker cannot be combined with %s, custom Workers own processing", opt.name)
+			}
+		}
+	}
+
+	queues := 0
+	for _, set := range []bool{wp.Queue != nil, wp.Stack, wp.Prioritize} {
+		if set {
+			queues++
+		}
+	}
+	if queues > 1 {
+		conflict("only one of Queue, Stack and Prioritize can be set")
+	}
+	if queues > 0 && wp.Partitioner != nil {
+		conflict("Partitioner cannot be combined with Queue, Stack or Prioritize")
+	}
+	if wp.Deterministic && (queues > 0 || wp.Partitioner != nil || wp.MaxConcurrency > 0) {
+		conflict("Deterministic cannot be combined with Queue, Stack, Prioritize, Partitioner or MaxConcurrency")
+	}
+	if wp.RampUp > 0 && wp.ShutdownOrder != ShutdownConcurrent {
+		conflict("RampUp cannot be combined with ShutdownOrder, a worker still waiting to start would hold up the shutdown")
+	}
+
+	if wp.AgingRate != 0 && !wp.Prioritize {
+		conflict("AgingRate needs Prioritize")
+	}
+	if (wp.MinConcurrency > 0 || wp.AdaptInterval > 0) && wp.MaxConcurrency <= 0 {
+		conflict("MinConcurrency and AdaptInterval need MaxConcurrency")
+	}
+	if wp.MaxConcurrency > 0 && wp.MinConcurrency > wp.MaxConcurrency {
+		conflict("MinConcurrency %d exceeds MaxConcurrency %d", wp.MinConcurrency, wp.MaxConcurrency)
+	}
+	if wp.OnWorkerStop != nil && wp.ShutdownOrder == ShutdownConcurrent {
+		conflict("OnWorkerStop needs a ShutdownOrder")
+	}
+	if (wp.MaxFollowUpDepth > 0 || wp.MaxFollowUps > 0) && wp.FollowUp == nil {
+		conflict("MaxFollowUpDepth and MaxFollowUps need FollowUp")
+	}
+
+	for _, task := range wp.Tasks {
+		if err := wp.validateTask(task); err != nil {
+			errs = append(errs, err)
+			break
+		}
+	}
+	if wp.pipe != nil {
+		if err := wp.pipe.dst.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("piped pool: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateTask reports a task whose Affinity the pool cannot honour
+func (wp *WorkerPool) validateTask(task Task) error {
+	if task.Affinity != 0 && (wp.Deterministic || wp.NewWorker != nil || wp.Queue != nil || wp.Stack || wp.Prioritize) {
+		return fmt.Errorf("%w: task %d has an Affinity, which Deterministic, NewWorker, Queue, Stack and Prioritize do not support",
+			ErrInvalidConfig, task.Id)
+	}
+	return nil
+}
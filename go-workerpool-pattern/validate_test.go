@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestValidateRejectsConflictingOptions checks that option combinations the pool would silently
+// ignore are rejected by Validate, Run and Start, before any task runs
+func TestValidateRejectsConflictingOptions(t *testing.T) {
+	newWorker := func(int) Worker { return ProcessWorker{} }
+	tests := []struct {
+		name      string
+		configure func(wp *WorkerPool)
+		wantErr   string // Part of the error message, empty for a valid configuration
+	}{
+		{"defaults", func(wp *WorkerPool) {}, ""},
+		{"compatible options", func(wp *WorkerPool) { wp.Stack = true; wp.MaxRetries = 2; wp.RampUp = time.Millisecond }, ""},
+		{"NewWorker with retries", func(wp *WorkerPool) { wp.NewWorker = newWorker; wp.MaxRetries = 1 }, "NewWorker cannot be combined with MaxRetries"},
+		{"NewWorker with hooks", func(wp *WorkerPool) { wp.NewWorker = newWorker; wp.BeforeProcess = func(Task) any { return nil } }, "NewWorker cannot be combined with BeforeProcess"},
+		{"Stack and Prioritize", func(wp *WorkerPool) { wp.Stack = true; wp.Prioritize = true }, "only one of Queue, Stack and Prioritize"},
+		{"Queue with Partitioner", func(wp *WorkerPool) { wp.Queue = NewFIFOQueue(4); wp.Partitioner = func(Task, int) int { return 0 } }, "Partitioner cannot be combined"},
+		{"Deterministic with Stack", func(wp *WorkerPool) { wp.Deterministic = true; wp.Stack = true }, "Deterministic cannot be combined"},
+		{"RampUp with ShutdownOrder", func(wp *WorkerPool) { wp.RampUp = time.Second; wp.ShutdownOrder = ShutdownHighestFirst }, "RampUp cannot be combined with ShutdownOrder"},
+		{"AgingRate without Prioritize", func(wp *WorkerPool) { wp.AgingRate = 5 }, "AgingRate needs Prioritize"},
+		{"MinConcurrency without MaxConcurrency", func(wp *WorkerPool) { wp.MinConcurrency = 1 }, "need MaxConcurrency"},
+		{"MinConcurrency above MaxConcurrency", func(wp *WorkerPool) { wp.MinConcurrency = 8; wp.MaxConcurrency = 4 }, "exceeds MaxConcurrency"},
+		{"OnWorkerStop without ShutdownOrder", func(wp *WorkerPool) { wp.OnWorkerStop = func(int, int) {} }, "OnWorkerStop needs a ShutdownOrder"},
+		{"MaxFollowUps without FollowUp", func(wp *WorkerPool) { wp.MaxFollowUps = 3 }, "need FollowUp"},
+		{"affinity task in Stack mode", func(wp *WorkerPool) { wp.Stack = true; wp.Tasks = []Task{{Id: 1, Affinity: 3}} }, "task 1 has an Affinity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newPool := func() *WorkerPool {
+				wp := &WorkerPool{Concurrency: 2}
+				tt.configure(wp)
+				return wp
+			}
+			err := newPool().Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() = %v, want ErrInvalidConfig mentioning %q", err, tt.wantErr)
+			}
+
+			var ran atomic.Int32
+			work := func(done <-chan struct{}) (any, error) { ran.Add(1); return nil, nil }
+			run := newPool()
+			run.Tasks = append([]Task{{Id: 100, Work: work}}, run.Tasks...)
+			if err := run.Run(); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Run() = %v, want ErrInvalidConfig", err)
+			}
+			if _, err := run.RunWithContext(context.Background()); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("RunWithContext() = %v, want ErrInvalidConfig", err)
+			}
+			if n := ran.Load(); n != 0 {
+				t.Errorf("%d tasks ran with an invalid configuration, want none", n)
+			}
+
+			streaming := newPool()
+			if err := streaming.Start(); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Start() = %v, want ErrInvalidConfig", err)
+			}
+			if err := streaming.Submit(Task{Id: 1, Work: work}); !errors.Is(err, ErrPoolClosed) {
+				t.Errorf("Submit after a rejected Start = %v, want ErrPoolClosed", err)
+			}
+		})
+	}
+}
+
+// TestSubmitRejectsAffinityWithoutRouting submits an affinity task to a started pool whose mode
+// cannot pin it to a worker
+func TestSubmitRejectsAffinityWithoutRouting(t *testing.T) {
+	testutil.LeakCheck(t)
+	wp := &WorkerPool{Concurrency: 2, Prioritize: true}
+	if err := wp.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer wp.Close()
+	work := func(done <-chan struct{}) (any, error) { return nil, nil }
+	if err := wp.Submit(Task{Id: 1, Affinity: 7, Work: work}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Submit of an affinity task = %v, want ErrInvalidConfig", err)
+	}
+	if err := wp.Submit(Task{Id: 2, Work: work}); err != nil {
+		t.Errorf("Submit of a plain task = %v, want nil", err)
+	}
+}
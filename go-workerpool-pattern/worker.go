@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/*
+Custom worker logic for the WorkerPool.
+The built-in worker loop processes each task with Process. A Worker replaces that loop, so each
+worker can hold state for its whole life, e.g. open a database connection once and close it on
+exit. The pool starts one Worker per worker slot and feeds them all from the task channel; a
+collector matches the results to their tasks and runs the usual result handling.
+*/
+
+// Worker is custom worker logic run by the pool. Run receives tasks until the channel is closed
+// and must send exactly one Result per task, with the task's Id, before returning. ctx is
+// cancelled when the pool is cancelled.
+type Worker interface {
+	Run(ctx context.Context, tasks <-chan Task, results chan<- Result)
+}
+
+// ProcessWorker is the default Worker: it runs every task with Process and has no setup
+type ProcessWorker struct{}
+
+// Run processes the tasks one at a time until the tasks channel is closed
+func (ProcessWorker) Run(ctx context.Context, tasks <-chan Task, results chan<- Result) {
+	for task := range tasks {
+		value, err := task.Process(ctx.Done())
+		results <- Result{TaskId: task.Id, Value: value, Err: err}
+	}
+}
+
+// handedOut is a task given to a custom Worker and when it was given
+type handedOut struct {
+	task  Task
+	start time.Time
+}
+
+// startCustomWorkers starts n Workers created by NewWorker. A feeder hands them the tasks from
+// the task channel, skipping cancelled and expired ones and holding back while the pool is paused, and a
+// collector finishes every task once its Worker reported the result. The pool's drain waits for
+// the Workers to return, so their teardown is done when Run or Close return.
+func (wp *WorkerPool) startCustomWorkers(n int) {
+	feed := make(chan Task)
+	results := make(chan Result)
+	var pending SafeMap[int, []handedOut] // Tasks handed out per Id, oldest first
+
+	go func() {
+		defer close(feed)
+		for task := range wp.TaskChan {
+			wp.control.awaitResume(wp.ctx.Done())
+			var skip error
+			switch {
+			case !wp.batch.start(task), wp.taskContext(task).Err() != nil:
+				skip = ErrTaskCancelled
+			case wp.expired(task):
+				skip = ErrTaskExpired
+			}
+			if skip != nil {
+				err := &TaskError{TaskId: task.Id, Err: skip}
+				wp.finish(task, Result{TaskId: task.Id, Err: err}, 0)
+				wp.taskDone()
+				continue
+			}
+			pending.Update(task.Id, func(tasks []handedOut, _ bool) []handedOut {
+				return append(tasks, handedOut{task: task, start: wp.clock().Now()})
+			})
+			wp.running.begin(task.Id)
+			feed <- task
+		}
+	}()
+
+	var running sync.WaitGroup
+	for i := range n {
+		worker := wp.NewWorker(i)
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			worker.Run(wp.ctx, feed, results)
+		}()
+	}
+	wp.custom.Add(1)
+	go func() {
+		running.Wait()
+		close(results)
+	}()
+
+	go func() {
+		defer wp.custom.Done()
+		for result := range results {
+			run := handedOut{task: Task{Id: result.TaskId}, start: wp.clock().Now()}
+			pending.Update(result.TaskId, func(tasks []handedOut, _ bool) []handedOut {
+				if len(tasks) == 0 {
+					return nil
+				}
+				run = tasks[0]
+				return tasks[1:]
+			})
+			wp.running.end(result.TaskId)
+			elapsed := wp.clock().Now().Sub(run.start)
+			wp.counters.record(elapsed, result.Err)
+			logTask(wp.taskContext(run.task), "task finished", -1, result.TaskId, 1, elapsed, result.Err)
+			completed := result.Err == nil || wp.taskContext(run.task).Err() == nil
+			if result.Err != nil {
+				result.Err = &TaskError{TaskId: result.TaskId, Attempt: 1, Err: result.Err}
+			}
+			if completed {
+				wp.batch.complete(run.task, result)
+			}
+			wp.finish(run.task, result, 1)
+			wp.taskDone()
+		}
+	}()
+}
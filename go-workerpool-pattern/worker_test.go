@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// connWorker is a custom Worker opening a (simulated) connection before its loop and closing it
+// on exit, counting both per worker id
+type connWorker struct {
+	id             int
+	mu             *sync.Mutex
+	opened, closed map[int]int
+}
+
+func (w connWorker) Run(ctx context.Context, tasks <-chan Task, results chan<- Result) {
+	w.mu.Lock()
+	w.opened[w.id]++
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		w.closed[w.id]++
+		w.mu.Unlock()
+	}()
+
+	for task := range tasks {
+		results <- Result{TaskId: task.Id, Value: fmt.Sprintf("row %d via connection %d", task.Id, w.id)}
+	}
+}
+
+// TestCustomWorkerSetupOnce runs batches and streams through custom Workers and checks that
+// every worker opened its connection exactly once and closed it before Run or Close returned,
+// and that every task got exactly one result from one of the workers
+func TestCustomWorkerSetupOnce(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		tasks       int
+		streaming   bool // Start, Submit and Close instead of Run
+	}{
+		{"more tasks than workers", 3, 9, false},
+		{"fewer tasks than workers", 4, 1, false},
+		{"no tasks", 2, 0, false},
+		{"streaming", 3, 20, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			var mu sync.Mutex
+			opened, closed := map[int]int{}, map[int]int{}
+			results := map[int][]any{}
+			wp := &WorkerPool{
+				Concurrency: tt.concurrency,
+				NewWorker:   func(id int) Worker { return connWorker{id: id, mu: &mu, opened: opened, closed: closed} },
+				OnResult: func(task Task, result Result) {
+					mu.Lock()
+					results[task.Id] = append(results[task.Id], result.Value)
+					mu.Unlock()
+				},
+			}
+			if tt.streaming {
+				if err := wp.Start(); err != nil {
+					t.Fatal(err)
+				}
+				for i := range tt.tasks {
+					if err := wp.Submit(Task{Id: i + 1}); err != nil {
+						t.Fatal(err)
+					}
+				}
+				wp.Close()
+			} else {
+				for i := range tt.tasks {
+					wp.Tasks = append(wp.Tasks, Task{Id: i + 1})
+				}
+				if err := wp.Run(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for id := range tt.concurrency {
+				if opened[id] != 1 || closed[id] != 1 {
+					t.Errorf("worker %d opened %d and closed %d connections, want 1 and 1", id, opened[id], closed[id])
+				}
+			}
+			if len(opened) != tt.concurrency {
+				t.Errorf("%d workers started, want %d", len(opened), tt.concurrency)
+			}
+			for id := 1; id <= tt.tasks; id++ {
+				if len(results[id]) != 1 {
+					t.Errorf("task %d got results %v, want exactly one", id, results[id])
+				}
+			}
+		})
+	}
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,53 +17,875 @@ from a shared channel, providing controlled concurrency and resource management.
 Note: This implementation supports only one Task type at a time.
 */
 
-// Task represents a unit of work to be processed by the worker pool
+// Task represents a unit of work to be processed by the worker pool.
+// The zero value is a valid task: it has Id 0 and, without Work, simulates processing.
+// Ids are not required to be unique, but reporting by Id (e.g. Completed) cannot tell duplicates apart.
 type Task struct {
-	Id int
+	Id       int
+	Affinity int                                     // Optional affinity key, tasks with the same non-zero key run on the same worker
+	Weight   int                                     // Optional relative cost of the task, see Cost
+	Priority int                                     // Optional priority, higher runs first when the pool's Prioritize is set
+	Work     func(done <-chan struct{}) (any, error) // Optional work producing a value, nil simulates processing
+	Tags     map[string]string                       // Optional metadata, e.g. region=us, used to select tasks with RunWhere
+	Group    string                                  // Optional name of the sub-batch the task belongs to, see CancelGroup
+
+	Idempotent bool      // Whether running the task twice is safe, required for hedging (see WorkerPool.HedgeAfter)
+	hedge      *hedgeRun // Set on hedge duplicates, links them to the attempt they race against
+
+	future   *Future[Result] // Set by SubmitFuture, resolved with the task's result
+	depth    int             // Number of FollowUp calls that led to the task, 0 for a submitted task
+	queuedAt time.Time       // When the task entered the queue, set when the pool's MaxQueueAge is set
+
+	untracked bool // Set on the runs of a Scheduler, which are left out of the batch bookkeeping
+}
+
+// Cost returns how heavy the task is, used by the pool's CostBudget. Defaults to 1.
+func (t *Task) Cost() int {
+	return max(t.Weight, 1)
 }
 
-// Process way to process the tasks
-func (t *Task) Process() {
+// AffinityKey returns the key used to route the task to a sticky worker, 0 means no affinity
+func (t *Task) AffinityKey() int {
+	return t.Affinity
+}
+
+// Process way to process the tasks, returning the value produced by the task.
+// done is closed when the pool is cancelled; tasks that respect it should stop early.
+func (t *Task) Process(done <-chan struct{}) (any, error) {
+	if t.Work != nil {
+		return t.Work(done)
+	}
 
-	// Simulate task processing time
+	// Simulate task processing time, giving up early if the pool is cancelled
 	fmt.Println("Processing task with ID:", t.Id)
-	time.Sleep(5 * time.Second)
+	select {
+	case <-time.After(5 * time.Second):
+		return nil, nil
+	case <-done:
+		return nil, ErrTaskCancelled
+	}
 }
 
 // WorkerPool definition
 type WorkerPool struct {
-	Tasks       []Task         // Tasks to be processed
-	Concurrency int            // Number of concurrent workers
-	TaskChan    chan Task      // Channel for distributing tasks to workers
-	wg          sync.WaitGroup // WaitGroup to synchronize worker completion
+	Tasks       []Task             // Tasks to be processed
+	Concurrency int                // Number of concurrent workers
+	TaskChan    chan Task          // Channel for distributing tasks to workers
+	wg          sync.WaitGroup     // WaitGroup to synchronize worker completion
+	idle        idleTracker        // Outstanding tasks, counted next to wg, behind WaitIdle
+	running     inFlightTracker    // Tasks being processed, behind InFlight
+	delays      *delayQueue        // Holds tasks submitted with a delay until they are due
+	affinity    []chan Task        // Per-worker channels for tasks with an affinity key
+	ctx         context.Context    // Cancelled by Cancel or by the parent context of RunWithContext
+	cancel      context.CancelFunc // Cancels ctx
+	mu          sync.Mutex         // Guards cancel, cancelled and closed, these may be used from any goroutine
+	cancelled   bool               // Whether Cancel was called, so a pool cancelled before start stays cancelled
+	closed      bool               // Whether the pool stopped accepting tasks
+	shutdown    sync.Once          // Ensures the pool is shut down only once, explicitly or when idle
+	done        chan struct{}      // Closed once the pool has shut down
+	idleReset   chan struct{}      // Restarts the IdleTimeout countdown on every submission
+	idleClosed  atomic.Bool        // Whether the shutdown was triggered by IdleTimeout
+	batch       batchTracker       // Records which submitted tasks completed
+	resumed     map[int]bool       // Ids completed before a restart, loaded by LoadState and skipped by Run
+	control     workerControl      // Control signals (pause, resume) the workers select on
+	groups      taskGroups         // Per-group contexts behind CancelGroup
+	pipe        *pipeTarget        // Downstream pool fed with the results, set by Pipe
+	upstream    *WorkerPool        // Pool piping its results into this one, cancelled together with it
+
+	// Settings holds live settings read by every worker on each loop iteration, so they can be
+	// changed with Settings.Store while the pool runs: e.g. lower ActiveWorkers to back off a
+	// struggling downstream and raise it again later. An inactive worker finishes its current
+	// task and idles; affinity tasks pinned to it wait until it is active again, so Run and Close
+	// do not return while they are stranded. Nil disables live settings.
+	Settings *AtomicConfig[PoolSettings]
+
+	// Deadline aborts the whole batch once the wall-clock time passes: in-flight tasks are
+	// cancelled through their done channel and queued tasks are skipped. Unlike TaskTimeout it
+	// bounds the total time of the batch. Completed and Unfinished tell which tasks made it.
+	// The zero time means no deadline.
+	Deadline time.Time
+
+	// Clock is the source of time for timeouts, the Deadline, backoff, hedging, delayed tasks,
+	// IdleTimeout, RampUp and the adaptive controller. Tests can set a FakeClock and advance it
+	// instead of sleeping. Nil uses the real clock.
+	Clock Clock
+
+	// IdleTimeout shuts a streaming pool down automatically once no task has been submitted
+	// for this long, so Close does not have to be called. Every submission resets the timer and
+	// the shutdown still drains all submitted tasks. Zero disables the idle shutdown.
+	IdleTimeout time.Duration
+
+	// StallTimeout arms a watchdog against deadlocks, e.g. every worker blocked in Process on
+	// something only a queued task would provide while the producer blocks on a full queue.
+	// Once tasks are outstanding but none completed for this long, the pool is cancelled and
+	// RunWithContext returns a StallError (matching ErrPossibleDeadlock) with the stacks of all
+	// goroutines; Stalled reports it in streaming mode. Set it well above the slowest task and
+	// the longest SubmitAfter delay. A paused pool is not watched. Zero disables the watchdog.
+	StallTimeout time.Duration
+	stall        *StallError // Set by the watchdog, guarded by mu
+
+	// HeartbeatInterval is how often Heartbeat ticks while tasks keep finishing, 1s by default.
+	// A watchdog should allow more than the slowest task between two heartbeats.
+	HeartbeatInterval time.Duration
+	heartbeat         chan time.Time // Channel returned by Heartbeat, nil until it is called
+
+	// CostBudget limits the total Cost of the tasks being processed at once instead of only their
+	// count, so two heavy tasks may run while ten cheap ones could. Workers wait for budget in FIFO
+	// order before processing a task. Zero disables the limit.
+	CostBudget int
+	costs      *weightedSemaphore // Enforces CostBudget
+
+	// MaxInFlight limits how many tasks are processed at the same time, independently of
+	// Concurrency: e.g. 16 workers keep pulling from the queue but only 4 run Process at once.
+	// Workers wait in FIFO order. Zero means every worker may process a task.
+	MaxInFlight int
+	inFlight    *weightedSemaphore // Enforces MaxInFlight
+
+	// MaxConcurrency enables adaptive concurrency: MaxConcurrency workers are started but the
+	// number processing at once is tuned every AdaptInterval (default 100ms) between
+	// MinConcurrency and MaxConcurrency, starting at Concurrency. It grows by one while work is
+	// waiting and halves when more than 10% of the tasks fail or latency doubles (AIMD).
+	// Stats reports the current value. Zero disables it.
+	MinConcurrency int
+	MaxConcurrency int
+	AdaptInterval  time.Duration
+	adaptive       *adaptiveLimiter // Enforces the adaptive limit, nil when disabled
+
+	// RampUp staggers worker startup over this window instead of launching every worker at
+	// once: the first starts immediately and another one every RampUp/Concurrency, so a cold
+	// downstream is not hit by a burst of Concurrency requests. Tasks queue until a worker is
+	// free, affinity tasks until their worker has started. Zero starts all workers instantly.
+	RampUp time.Duration
+
+	// MaxTasksPerWorker recycles a worker after it processed this many tasks: it exits and a fresh
+	// worker takes over its slot (and affinity channel), dropping any per-worker state it built.
+	// The handoff happens between tasks, so no task is lost. Zero means never recycle.
+	MaxTasksPerWorker int
+
+	MaxRetries int                             // Number of times a failed task is retried
+	Backoff    func(attempt int) time.Duration // Optional wait before retry attempt n (starting at 1), e.g. JitteredBackoff
+
+	// PanicPolicy decides what happens when Process panics. PanicCrash (the default) re-raises the
+	// panic and crashes the program, PanicRecoverFail turns it into an error wrapping
+	// ErrTaskPanicked that fails the task without retrying, and PanicRecoverRetry retries it like
+	// any other failure. AfterProcess sees the panic under every policy.
+	PanicPolicy PanicPolicy
+
+	// RetryBudget caps the total number of retries across all tasks. Once it is spent failed
+	// tasks fail fast instead of retrying, so a downstream outage does not turn into a retry
+	// storm. Stats reports the retries left. Zero means unlimited.
+	RetryBudget int
+
+	// HedgeAfter dispatches a duplicate of an Idempotent task's attempt to another worker once it
+	// has run this long, and takes whichever copy finishes first, cancelling the other. The
+	// waiting worker keeps its slot, so a duplicate only runs if another worker is free.
+	// Zero disables hedging. Stats reports how often it triggered.
+	HedgeAfter time.Duration
+	counters   poolCounters // Live counters behind Stats
+
+	// TaskTimeout bounds every attempt separately: each retry gets a fresh deadline, so a task
+	// with MaxRetries 2 and a 1s TaskTimeout may run for about 3s. Attempts that exceed it fail
+	// with ErrTaskTimeout and are retried like any other failure.
+	// CumulativeTimeout bounds the whole task instead, all attempts and backoff waits included:
+	// once it passes the running attempt is cancelled, no retry follows and the task fails with
+	// ErrTaskTimeout. Both may be set. Zero disables either limit.
+	TaskTimeout       time.Duration
+	CumulativeTimeout time.Duration
+
+	// MaxQueueAge drops tasks that waited in the queue longer than this instead of processing
+	// them stale, for real-time workloads where old work is worthless. The age is checked when a
+	// worker dequeues the task, counting from the moment it was queued (for delayed tasks, when
+	// they became due). Expired tasks report ErrTaskExpired and are counted in Stats().Expired.
+	// Zero disables the limit.
+	MaxQueueAge time.Duration
+
+	// BeforeProcess and AfterProcess are optional hooks called around every Process call (every
+	// attempt) on the worker goroutine, e.g. to start and end tracing spans. The value returned by
+	// BeforeProcess is an opaque handle passed to AfterProcess together with the attempt's error.
+	// AfterProcess also fires when the attempt times out, is cancelled or panics.
+	BeforeProcess func(Task) any
+	AfterProcess  func(Task, any, error)
+
+	// OnResult is called with the result of each task as soon as it is processed, so results
+	// can be streamed (e.g. into a database) without waiting for the whole batch.
+	// It runs on the worker goroutine that processed the task, so it may be called concurrently
+	// by several workers and must be safe for concurrent use. A slow callback holds up its worker.
+	// Run and Close return only after every callback has returned.
+	OnResult func(Task, Result)
+	results  atomic.Pointer[resultStream] // Results channel opened by ResultsCtx
+	collect  func(Task, Result, int)      // Internal result hook (with attempts) used by RunMap, RunWithReport and Reduce, set before start
+
+	// FollowUp is called with every task's result, after OnResult, and returns tasks to enqueue
+	// into the same pool (nil for none), for recursive workflows such as crawling a page and
+	// enqueueing the links found on it. Follow-ups are processed like submitted tasks, also while
+	// Run or Close wait for the batch, which only ends once the expansion has finished.
+	// It runs on the worker goroutine and must be safe for concurrent use.
+	// MaxFollowUpDepth limits how many follow-ups may be chained from a submitted task and
+	// MaxFollowUps caps their total number; tasks beyond either limit are not enqueued and
+	// are counted in Stats().Pruned. Zero means no limit, so a FollowUp that always returns a task
+	// needs one of them to terminate.
+	FollowUp         func(Task, Result) []Task
+	MaxFollowUpDepth int
+	MaxFollowUps     int
+	followUps        int // Follow-ups enqueued so far, guarded by mu
+
+	// ResultTimeout bounds how long a worker waits to hand a result to the ResultsCtx / RunStream
+	// channel. A result the consumer did not take in time is dropped and counted in
+	// Stats().Dropped, so a stuck consumer cannot block the workers. Unlike TaskTimeout it does
+	// not bound processing. Zero waits for the consumer indefinitely.
+	ResultTimeout time.Duration
+
+	// OnProgress is called exactly once per completed task (successful, failed or skipped
+	// because of cancellation) with the running count, e.g. to render "12/20 complete".
+	// total is the batch size for Run and -1 in streaming mode, where it is unknown.
+	// Calls are serialized, so the callback needs no locking, but a slow one holds up every worker.
+	OnProgress func(done, total int)
+	progress   progressTracker // Running count behind OnProgress
+
+	// ShutdownOrder stops the workers one at a time in a defined order when the pool shuts down
+	// (Run returns, Close, idle or cancellation), instead of all at once. Each worker waits on its
+	// own stop channel after the last task and OnWorkerStop is called with its index and the
+	// number of tasks its slot processed, in stop order, before the next one is released.
+	ShutdownOrder ShutdownOrder
+	OnWorkerStop  func(worker, tasks int)
+	stops         *workerStops // Per-worker stop channels, nil with ShutdownConcurrent
+
+	// Partitioner chooses the worker that processes a task, given the number of workers, instead
+	// of the affinity key mapping: tasks it maps to the same index run on the same worker, one at
+	// a time in dispatch order. A negative index sends the task to the shared channel. Nil keeps
+	// the default (affinity tasks pinned, the rest shared). KeyPartitioner hashes a string key.
+	// It cannot be combined with Queue, Stack, Prioritize or Deterministic (see Validate).
+	Partitioner func(Task, int) int
+
+	// Stack dispatches the most recently submitted task first (LIFO) instead of the oldest,
+	// which keeps latency low for fresh work. Queued tasks are kept in a bounded stack instead
+	// of the task channel, so tasks with an Affinity are rejected. Under sustained load old tasks
+	// can starve: they are only processed once the workers catch up with new submissions.
+	// It cannot be combined with Prioritize, Queue or Deterministic.
+	Stack bool
+
+	// Prioritize dispatches the queued task with the highest Task.Priority first, the oldest
+	// first among equal priorities. Like Stack, it keeps queued tasks in a bounded queue instead
+	// of the task channel and rejects tasks with an Affinity.
+	// Strict priorities starve low-priority tasks under a steady stream of higher ones, so
+	// AgingRate raises the priority of a waiting task by that much per second of waiting (e.g.
+	// 10 lets a priority 0 task overtake newly queued priority 5 tasks after half a second).
+	// Zero keeps priorities strict; AgingRate needs Prioritize. Prioritize cannot be combined with
+	// Stack, Queue or Deterministic.
+	Prioritize bool
+	AgingRate  float64
+
+	// Queue replaces the task channel with a custom store for the queued tasks, e.g. a priority
+	// or disk-backed queue (see Queue, NewFIFOQueue and NewLIFOQueue). Tasks with an Affinity are
+	// rejected. The pool closes it once drained, so a Queue serves a single run. It cannot be
+	// combined with Stack, Prioritize or Deterministic.
+	Queue Queue
+	queue Queue // Queue used by the workers instead of the task channel, nil with the default channels
+
+	// Deterministic processes tasks strictly one at a time in submission order, so the output
+	// is reproducible (e.g. for golden-output tests of the demos). It effectively disables
+	// concurrency: a single worker runs regardless of Concurrency, and tasks with an Affinity,
+	// Queue, Stack, Prioritize, Partitioner and MaxConcurrency are rejected.
+	// Not meant for production use.
+	Deterministic bool
+
+	// NewWorker replaces the built-in worker loop with custom Workers: the pool calls it once per
+	// worker (with the worker's index) and runs the returned Worker, e.g. one that opens a
+	// database connection at startup and closes it on exit. ProcessWorker is the plain default.
+	// The Worker owns processing, so Validate rejects the per-task options of the pool (retries,
+	// timeouts, hedging, the process hooks, CostBudget, MaxInFlight, MaxConcurrency, affinity,
+	// Queue, Stack, Prioritize, Partitioner, RampUp, ShutdownOrder, MaxTasksPerWorker, Settings),
+	// and Restart has no effect. Result handling, cancellation of queued tasks, Pause and the Stats
+	// counts work as usual.
+	NewWorker func(id int) Worker
+	custom    sync.WaitGroup // Custom Workers and their collector, the drain waits for their teardown
 }
 
-// worker continuously processes tasks from the task channel until channel is closed
-func (wp *WorkerPool) worker() {
-	for task := range wp.TaskChan {
-		task.Process()
-		wp.wg.Done()
+// worker continuously processes tasks from the shared task channel and its own affinity
+// channel until both channels are closed, or from the Queue (Stack, Prioritize or a custom one)
+// until it is closed.
+// It selects on the control channel as well, so it stops taking tasks as soon as it is paused.
+func (wp *WorkerPool) worker(id int) {
+	// per-key state this worker has built, affinity routing guarantees it is reused
+	warm := make(map[int]bool)
+	processed := 0
+
+	tasks, sticky := wp.TaskChan, wp.affinity[id]
+	generation := wp.control.current()
+	for {
+		if wp.control.current() != generation {
+			// Restart was called, hand the slot over to a fresh worker between tasks
+			Log.Info("worker restarted", slog.Int("worker_id", id), slog.Int("tasks", processed))
+			go wp.worker(id)
+			return
+		}
+		wp.awaitActive(id)
+		var task Task
+		var ok bool
+		if wp.queue != nil {
+			// the queue decides the order (newest, most urgent, ...); it cannot be selected on,
+			// so a pause is observed before and right after popping
+			wp.control.awaitResume(wp.ctx.Done())
+			if task, ok = wp.queue.Pop(); !ok {
+				wp.retire(id)
+				return
+			}
+			wp.control.awaitResume(wp.ctx.Done())
+		} else {
+			if tasks == nil && sticky == nil {
+				wp.retire(id)
+				return
+			}
+			paused, changed := wp.control.state()
+			if paused && wp.ctx.Err() == nil {
+				// wait for the next control change (a resume or a restart), then re-check
+				select {
+				case <-changed:
+				case <-wp.ctx.Done():
+				}
+				continue
+			}
+			select {
+			case <-changed:
+				// control state changed, re-check it before taking a task
+				continue
+			case task, ok = <-tasks:
+				if !ok {
+					tasks = nil
+					continue
+				}
+			case task, ok = <-sticky:
+				if !ok {
+					sticky = nil
+					continue
+				}
+				if key := task.AffinityKey(); key != 0 && !warm[key] {
+					Log.Debug("worker building cache", slog.Int("worker_id", id), slog.Int("affinity_key", key))
+					warm[key] = true
+				}
+			}
+		}
+		if task.hedge != nil {
+			wp.processHedge(task)
+		} else {
+			wp.process(id, task)
+		}
+		wp.taskDone()
+		wp.pace()
+
+		if wp.stops != nil {
+			wp.stops.tasks[id]++
+		}
+		processed++
+		if wp.MaxTasksPerWorker > 0 && processed >= wp.MaxTasksPerWorker {
+			// hand the slot over to a fresh worker before reading the next task
+			Log.Debug("worker recycled", slog.Int("worker_id", id), slog.Int("tasks", processed))
+			go wp.worker(id)
+			return
+		}
+	}
+}
+
+// process runs a single task and hands its result to the OnResult callback and the ResultsCtx channel.
+// Tasks still queued when the pool is cancelled, or cancelled individually with CancelTask or
+// as a group with CancelGroup, are not started and report ErrTaskCancelled. Tasks that waited
+// longer than MaxQueueAge are not started either and report ErrTaskExpired.
+// Errors are wrapped in a TaskError carrying the task Id and the number of attempts made.
+// worker is the index of the worker processing the task, used for logging.
+func (wp *WorkerPool) process(worker int, task Task) {
+	var value any
+	var err error
+	attempts := 0
+	ctx := wp.taskContext(task)
+	switch {
+	case !wp.batch.start(task):
+		err = ErrTaskCancelled
+	case ctx.Err() != nil:
+		err = ErrTaskCancelled
+	case wp.expired(task):
+		err = ErrTaskExpired
+	case !wp.acquirePermits(ctx, task):
+		err = ErrTaskCancelled
+	default:
+		start := wp.clock().Now()
+		wp.running.begin(task.Id)
+		value, attempts, err = wp.runWithRetries(ctx, task)
+		wp.running.end(task.Id)
+		elapsed := wp.clock().Now().Sub(start)
+		wp.counters.record(elapsed, err)
+		logTask(ctx, "task finished", worker, task.Id, attempts, elapsed, err)
+		wp.releasePermits(task, elapsed, err)
+	}
+	completed := attempts > 0 && (err == nil || ctx.Err() == nil)
+	if err != nil {
+		err = &TaskError{TaskId: task.Id, Attempt: attempts, Err: err}
+	}
+	result := Result{TaskId: task.Id, Value: value, Err: err}
+	if completed {
+		wp.batch.complete(task, result)
+	}
+	wp.finish(task, result, attempts)
+}
+
+// acquirePermits waits for the CostBudget, MaxInFlight and adaptive concurrency permits of a task,
+// in that order. It returns false without holding any permit if ctx is done first.
+func (wp *WorkerPool) acquirePermits(ctx context.Context, task Task) bool {
+	if wp.costs != nil && !wp.costs.acquire(ctx.Done(), task.Cost()) {
+		return false
+	}
+	if wp.inFlight != nil && !wp.inFlight.acquire(ctx.Done(), 1) {
+		if wp.costs != nil {
+			wp.costs.release(task.Cost())
+		}
+		return false
+	}
+	if wp.adaptive != nil && !wp.adaptive.acquire(ctx.Done()) {
+		if wp.inFlight != nil {
+			wp.inFlight.release(1)
+		}
+		if wp.costs != nil {
+			wp.costs.release(task.Cost())
+		}
+		return false
+	}
+	return true
+}
+
+// releasePermits returns the permits taken by acquirePermits once the task ran for elapsed and
+// ended with err, which the adaptive limiter uses to adjust the concurrency
+func (wp *WorkerPool) releasePermits(task Task, elapsed time.Duration, err error) {
+	if wp.adaptive != nil {
+		wp.adaptive.release(elapsed, err)
+	}
+	if wp.inFlight != nil {
+		wp.inFlight.release(1)
+	}
+	if wp.costs != nil {
+		wp.costs.release(task.Cost())
+	}
+}
+
+// finish hands the result of a processed task to the future, the callbacks, the ResultsCtx
+// channel and the downstream pool, and counts the task as completed for OnProgress
+func (wp *WorkerPool) finish(task Task, result Result, attempts int) {
+	if task.future != nil {
+		task.future.resolve(result)
+	}
+	if wp.collect != nil {
+		wp.collect(task, result, attempts)
+	}
+	if wp.OnResult != nil {
+		wp.OnResult(task, result)
+	}
+	wp.enqueueFollowUps(task, result)
+	if s := wp.results.Load(); s != nil {
+		var timeout <-chan time.Time
+		if wp.ResultTimeout > 0 {
+			timer := wp.clock().NewTimer(wp.ResultTimeout)
+			defer timer.Stop()
+			timeout = timer.C()
+		}
+		if s.send(result, timeout) {
+			wp.counters.dropped.Add(1)
+		}
+	}
+	wp.forward(result)
+	wp.progress.completed(wp.OnProgress)
+}
+
+// dispatch routes a task to the worker owning its affinity key (or chosen by the Partitioner),
+// or to the shared channel (the Queue in Stack, Prioritize or custom Queue mode).
+// Keys are mapped to workers by key modulo Concurrency, so changing the number of workers
+// remaps keys and the new owners have to rebuild their per-key state.
+func (wp *WorkerPool) dispatch(task Task) {
+	task = wp.stampQueued(task)
+	if wp.queue != nil {
+		wp.queue.Push(task)
+		return
 	}
+	wp.queueFor(task) <- task
 }
 
-// Run executes all tasks using the configured number of workers
-func (wp *WorkerPool) Run() {
-	// initialize the task channel
-	wp.TaskChan = make(chan Task, len(wp.Tasks))
+// queueFor returns the channel a task is sent to: the affinity channel of its worker or the
+// shared task channel
+func (wp *WorkerPool) queueFor(task Task) chan Task {
+	if wp.NewWorker != nil {
+		return wp.TaskChan // custom workers all read the shared channel
+	}
+	idx := wp.route(task, len(wp.affinity))
+	if idx < 0 || wp.Deterministic {
+		return wp.TaskChan
+	}
+	return wp.affinity[idx]
+}
 
-	// start workers
-	for i := 0; i < wp.Concurrency; i++ {
-		go wp.worker()
+// affinityWorker returns the index of the worker owning an affinity key among n workers
+func affinityWorker(key, n int) int {
+	idx := key % n
+	if idx < 0 {
+		idx += n
+	}
+	return idx
+}
+
+// Run executes all tasks using the configured number of workers. It returns the error that
+// ended the batch early like RunWithContext (Cancel, Deadline, StallTimeout), or the error of
+// Validate without running anything if the options conflict.
+func (wp *WorkerPool) Run() error {
+	return wp.runBatch(context.Background())
+}
+
+// RunWithContext executes all tasks like Run, cancelling them when ctx is cancelled.
+// This is the context-based alternative to Cancel: in-flight tasks see their done channel
+// closed, queued tasks are skipped, and it returns after the workers drain with the
+// cancellation error (nil if the batch completed, context.DeadlineExceeded once Deadline passed,
+// a StallError once the StallTimeout watchdog fired, the error of Validate if the options conflict).
+// The results of the tasks that completed (successfully or with an error) are returned in
+// completion order, also when the batch was cancelled midway; skipped tasks and tasks cancelled
+// while running are left out. Every result was recorded before the workers drained, so the
+// slice is complete when it is returned.
+func (wp *WorkerPool) RunWithContext(ctx context.Context) ([]Result, error) {
+	wp.batch.keepResults()
+	err := wp.runBatch(ctx)
+	return wp.batch.completedResults(), err
+}
+
+// runBatch executes all tasks like RunWithContext without keeping their results, for the runs
+// that hand the results to a hook instead (Run, Reduce, RunMap, RunWithReport, ForEach)
+func (wp *WorkerPool) runBatch(ctx context.Context) error {
+	total := 0
+	for _, task := range wp.Tasks {
+		if !wp.resumed[task.Id] {
+			total++
+		}
+	}
+	wp.progress.setTotal(total)
+	_, err := wp.run(ctx, nil)
+	return err
+}
+
+// run executes the tasks matching pred (all of them if pred is nil) and returns the others.
+// pred is evaluated for each task right before it is enqueued. Tasks completed before a
+// restart (see LoadState) are neither run nor returned.
+func (wp *WorkerPool) run(ctx context.Context, pred func(Task) bool) (skipped []Task, err error) {
+	if err := wp.start(ctx); err != nil {
+		return slices.Clone(wp.Tasks), err
 	}
 
 	// send tasks to the tasks channel
-	wp.wg.Add(len(wp.Tasks))
 	for _, task := range wp.Tasks {
-		wp.TaskChan <- task
+		if wp.resumed[task.Id] {
+			continue
+		}
+		if pred != nil && !pred(task) {
+			skipped = append(skipped, task)
+			continue
+		}
+		wp.Submit(task)
 	}
-	// close the task channel after all tasks are sent to the channel to avoid deadlock
-	close(wp.TaskChan)
 
 	// wait for all tasks to complete
+	wp.stop(false)
+	err = ctxErr(wp.ctx)
+	if stall := wp.Stalled(); stall != nil {
+		err = stall
+	}
+	wp.cancel()
+	return skipped, err
+}
+
+// workerCount returns the number of workers to start, a single one in deterministic mode
+func (wp *WorkerPool) workerCount() int {
+	if wp.Deterministic {
+		return 1
+	}
+	if wp.MaxConcurrency > 0 {
+		return wp.MaxConcurrency
+	}
+	return wp.Concurrency
+}
+
+// Start initializes the task channel and launches the workers.
+// Tasks can then be added with Submit or SubmitAfter until Close is called.
+// If the options conflict it returns the error of Validate and starts nothing; Submit then
+// returns ErrPoolClosed.
+func (wp *WorkerPool) Start() error {
+	return wp.start(context.Background())
+}
+
+// Cancel signals all in-flight tasks to stop through the done channel passed to Process.
+// Tasks that respect done exit early, queued tasks are skipped, and Run returns once the
+// workers drain. This is the channel-based alternative to RunWithContext.
+func (wp *WorkerPool) Cancel() {
+	wp.mu.Lock()
+	wp.cancelled = true
+	if wp.cancel != nil {
+		wp.cancel()
+	}
+	wp.mu.Unlock()
+
+	// a downstream pool is cancelled through its context, an upstream one explicitly
+	if wp.upstream != nil {
+		wp.upstream.Cancel()
+	}
+}
+
+// ShouldStop reports whether the pool was cancelled (Cancel, the context of RunWithContext or
+// the Deadline), for compute-heavy tasks without a natural point to select on done: they can
+// call it every few iterations and return early. It is cooperative and best-effort: nothing
+// interrupts a task that does not check it, and Close is a graceful drain that does not make
+// it true. A task's done channel also closes on its TaskTimeout, which ShouldStop does not see.
+func (wp *WorkerPool) ShouldStop() bool {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	return wp.cancelled || (wp.ctx != nil && wp.ctx.Err() != nil)
+}
+
+// start initializes the channels and launches the workers, bound to the given parent context,
+// unless Validate rejects the options
+func (wp *WorkerPool) start(parent context.Context) error {
+	if err := wp.Validate(); err != nil {
+		wp.mu.Lock()
+		wp.closed = true
+		wp.mu.Unlock()
+		return err
+	}
+
+	wp.mu.Lock()
+	if !wp.Deadline.IsZero() {
+		wp.ctx, wp.cancel = withDeadline(parent, wp.clock(), wp.Deadline)
+	} else {
+		wp.ctx, wp.cancel = context.WithCancel(parent)
+	}
+	if wp.cancelled {
+		wp.cancel()
+	}
+	wp.done = make(chan struct{})
+	if wp.heartbeat != nil {
+		go wp.beat(wp.heartbeat)
+	}
+	if wp.CostBudget > 0 {
+		wp.costs = newWeightedSemaphore(wp.CostBudget)
+	}
+	if wp.MaxInFlight > 0 {
+		wp.inFlight = newWeightedSemaphore(wp.MaxInFlight)
+	}
+	if wp.MaxConcurrency > 0 && !wp.Deterministic {
+		wp.adaptive = newAdaptiveLimiter(wp.MinConcurrency, wp.MaxConcurrency, wp.Concurrency)
+		go wp.adapt(wp.adaptive)
+	}
+	if wp.IdleTimeout > 0 {
+		wp.idleReset = make(chan struct{}, 1)
+		go wp.watchIdle()
+	}
+	if wp.StallTimeout > 0 {
+		go wp.watchStalls()
+	}
+	wp.mu.Unlock()
+
+	// initialize the task channel, large enough for the batch or one task per worker
+	workers := wp.workerCount()
+	wp.TaskChan = make(chan Task, max(len(wp.Tasks), workers))
+	switch {
+	case wp.Deterministic, wp.NewWorker != nil:
+	case wp.Queue != nil:
+		wp.queue = wp.Queue
+	case wp.Prioritize:
+		wp.queue = newPriorityQueue(max(len(wp.Tasks), workers), wp.clock(), wp.AgingRate)
+	case wp.Stack:
+		wp.queue = newTaskStack(max(len(wp.Tasks), workers))
+	}
+	wp.delays = newDelayQueue(wp.ctx.Done(), wp.clock(), wp.dispatch)
+	if wp.pipe != nil {
+		_ = wp.pipe.dst.start(wp.ctx) // validated together with this pool
+	}
+
+	if wp.NewWorker != nil {
+		wp.startCustomWorkers(workers)
+		return nil
+	}
+	if wp.ShutdownOrder != ShutdownConcurrent {
+		wp.stops = newWorkerStops(workers)
+	}
+
+	// start workers, each with its own channel for affinity tasks, staggered over RampUp
+	wp.affinity = make([]chan Task, workers)
+	for i := 0; i < workers; i++ {
+		wp.affinity[i] = make(chan Task, workers)
+		if wp.RampUp <= 0 || i == 0 {
+			go wp.worker(i)
+			continue
+		}
+		// a pool closed before the worker's turn has nothing left for it
+		delay := wp.clock().NewTimer(time.Duration(i) * wp.RampUp / time.Duration(workers))
+		go func() {
+			select {
+			case <-delay.C():
+				wp.worker(i)
+			case <-wp.done:
+				delay.Stop()
+			}
+		}()
+	}
+	return nil
+}
+
+// watchIdle shuts the pool down once no task has been submitted for IdleTimeout
+func (wp *WorkerPool) watchIdle() {
+	for {
+		timer := wp.clock().NewTimer(wp.IdleTimeout)
+		select {
+		case <-wp.idleReset:
+			timer.Stop()
+		case <-wp.done:
+			timer.Stop()
+			return
+		case <-timer.C():
+			wp.mu.Lock()
+			closed := wp.closed
+			wp.mu.Unlock()
+			if !closed {
+				wp.stop(true)
+				wp.cancel()
+			}
+			return
+		}
+	}
+}
+
+// Submit sends a task to the workers, blocking while the task channel is full.
+// Tasks with an affinity key always go to the same worker, the rest are load-balanced.
+// It returns ErrPoolClosed once the pool has been closed or shut down when idle.
+func (wp *WorkerPool) Submit(task Task) error {
+	if err := wp.accept(task); err != nil {
+		return err
+	}
+	wp.dispatch(task)
+	return nil
+}
+
+// TrySubmit sends a task to the workers like Submit, but gives up with ErrQueueFull if the queue
+// stays full for d, so a producer under backpressure is never blocked indefinitely. A task
+// that was not queued is not part of the batch: it is not processed, reported or counted.
+func (wp *WorkerPool) TrySubmit(task Task, d time.Duration) error {
+	if err := wp.accept(task); err != nil {
+		return err
+	}
+	task = wp.stampQueued(task)
+	timer := wp.clock().NewTimer(d)
+	defer timer.Stop()
+	expired := timer.C()
+	if wp.queue != nil {
+		q, ok := wp.queue.(boundedQueue)
+		if !ok {
+			// a custom Queue cannot give up on a push, wait for it like Submit
+			wp.queue.Push(task)
+			return nil
+		}
+		if q.pushWithin(task, expired) {
+			return nil
+		}
+	} else {
+		select {
+		case wp.queueFor(task) <- task:
+			return nil
+		case <-expired:
+		}
+	}
+
+	// undo accept, the task never reached the queue
+	wp.batch.withdraw(task)
+	wp.taskDone()
+	return ErrQueueFull
+}
+
+// SubmitAfter holds the task in the delay queue and releases it to the workers once d has elapsed.
+// Multiple delayed tasks are released in due-time order.
+func (wp *WorkerPool) SubmitAfter(task Task, d time.Duration) error {
+	return wp.SubmitAt(task, wp.clock().Now().Add(d))
+}
+
+// SubmitAt holds the task in the delay queue and releases it to the workers at the given time
+func (wp *WorkerPool) SubmitAt(task Task, at time.Time) error {
+	if err := wp.accept(task); err != nil {
+		return err
+	}
+	wp.delays.push(task, at)
+	return nil
+}
+
+// accept registers a new task unless the pool is closed, and resets the idle timer
+func (wp *WorkerPool) accept(task Task) error {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	if wp.closed {
+		return ErrPoolClosed
+	}
+	if err := wp.validateTask(task); err != nil {
+		return err
+	}
+	wp.addTask()
+	wp.batch.submit(task)
+	if wp.idleReset != nil {
+		select {
+		case wp.idleReset <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Close waits for all submitted tasks, including delayed ones, to complete and stops the workers.
+// No tasks may be submitted after Close. Closing a pool that already shut down when idle is a no-op.
+func (wp *WorkerPool) Close() {
+	wp.stop(false)
+	wp.cancel()
+}
+
+// Done returns a channel that is closed once the pool has shut down, explicitly or when idle
+func (wp *WorkerPool) Done() <-chan struct{} {
+	return wp.done
+}
+
+// ClosedByIdleTimeout reports whether the pool shut down because of IdleTimeout rather than Close
+func (wp *WorkerPool) ClosedByIdleTimeout() bool {
+	return wp.idleClosed.Load()
+}
+
+// stop rejects new tasks, drains the submitted ones and stops the workers. Only the first call
+// shuts the pool down, later calls wait until that shutdown has finished.
+func (wp *WorkerPool) stop(idle bool) {
+	wp.shutdown.Do(func() {
+		wp.mu.Lock()
+		wp.closed = true
+		wp.mu.Unlock()
+
+		wp.drain()
+		wp.idleClosed.Store(idle)
+		close(wp.done)
+	})
+	<-wp.done
+}
+
+// drain waits for all submitted tasks to complete and closes the channels so the workers exit
+func (wp *WorkerPool) drain() {
+	// wait for all tasks to complete, delayed tasks are counted from the moment they are submitted
 	wp.wg.Wait()
+
+	// close the task channel after all tasks are processed so the workers exit
+	wp.delays.close()
+	close(wp.TaskChan)
+	if wp.queue != nil {
+		wp.queue.Close()
+	}
+	for _, ch := range wp.affinity {
+		close(ch)
+	}
+	wp.stopWorkers()
+	wp.custom.Wait()
+
+	// every result was piped, let the downstream pool finish its tasks
+	if wp.pipe != nil {
+		wp.pipe.dst.Close()
+	}
 }
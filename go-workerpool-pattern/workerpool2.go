@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -28,6 +33,11 @@ func (e *EmailTask) Process() {
 	time.Sleep(1 * time.Second)
 }
 
+// TypeName returns the type name of email tasks
+func (e *EmailTask) TypeName() string {
+	return "email"
+}
+
 // ImageProcessingTask definition
 type ImageProcessingTask struct {
 	ImageURL string
@@ -39,26 +49,197 @@ func (e *ImageProcessingTask) Process() {
 	time.Sleep(4 * time.Second)
 }
 
+// TypeName returns the type name of image processing tasks
+func (e *ImageProcessingTask) TypeName() string {
+	return "image"
+}
+
 // NewWorkerPool definition
 type NewWorkerPool struct {
-	MultiTasks    []MultiTask    // MultiTask to be processed
-	Concurrency   int            // Number of concurrent workers
-	MultiTaskChan chan MultiTask // Channel for distributing multiple tasks to workers
-	wg            sync.WaitGroup // WaitGroup to synchronize worker completion
+	MultiTasks    []MultiTask     // MultiTask to be processed
+	Concurrency   int             // Number of concurrent workers
+	MultiTaskChan chan MultiTask  // Channel for distributing multiple tasks to workers
+	WeightByType  map[string]int  // Optional share of the workers per type while several types are pending, see typeQueues
+	Breaker       *CircuitBreaker // Optional circuit breaker that fast-fails types that keep failing
+	wg            sync.WaitGroup  // WaitGroup to synchronize worker completion
+	processed     atomic.Int64    // Number of tasks processed successfully
+	failed        atomic.Int64    // Number of tasks that failed, see FallibleTask
+	rejected      atomic.Int64    // Number of tasks fast-failed by an open circuit breaker
+	summary       summaryTracker  // Collects the Summary returned by Run
+	completions   chan completion // Reports finished tasks to the dependency scheduler, nil without dependencies
+	resources     keySerializer   // Serializes tasks sharing a ResourceKey
+	results       resultCollector // Collects the values of ResultTasks
+	compensated   atomic.Int64    // Number of failed CompensableTasks rolled back successfully
+	compensations compensations   // Collects the failed compensations
+	typed         *typeQueues     // Per-type queues the workers pull from, nil without WeightByType
+
+	// MaxRetries is the number of times a task that fails (see FallibleTask) is run again
+	// before it counts as failed. A CompensableTask is only compensated once its last attempt
+	// failed. Tasks fast-failed by the Breaker or skipped for a failed dependency are not retried.
+	MaxRetries int
+
+	// RejectNilTasks makes Run fail with ErrNilTask if MultiTasks contains a nil entry,
+	// instead of skipping it
+	RejectNilTasks bool
+}
+
+// ErrNilTask is returned by Run for a nil entry in MultiTasks when RejectNilTasks is set
+var ErrNilTask = errors.New("nil task")
+
+// completion reports the outcome of a finished task to the dependency scheduler
+type completion struct {
+	task MultiTask
+	err  error
+}
+
+// Metrics is a snapshot of the multi-type worker pool counters
+type Metrics struct {
+	Processed int64                   // Tasks processed successfully
+	Failed    int64                   // Tasks that failed, see FallibleTask
+	Rejected  int64                   // Tasks fast-failed by an open circuit breaker
+	Breakers  map[string]BreakerState // Circuit breaker state per task type
+
+	Compensated int64 // Failed CompensableTasks whose Compensate succeeded, see CompensationErrors for the others
+}
+
+// Metrics returns a snapshot of the pool counters and circuit breaker states
+func (wp *NewWorkerPool) Metrics() Metrics {
+	m := Metrics{
+		Processed: wp.processed.Load(),
+		Failed:    wp.failed.Load(),
+		Rejected:  wp.rejected.Load(),
+
+		Compensated: wp.compensated.Load(),
+	}
+	if wp.Breaker != nil {
+		m.Breakers = wp.Breaker.States()
+	}
+	return m
 }
 
 // worker continuously processes tasks from the task channel until channel is closed
 func (wp *NewWorkerPool) worker() {
-	for task := range wp.MultiTaskChan {
-		task.Process()
-		wp.wg.Done()
+	for task, ok := wp.next(); ok; task, ok = wp.next() {
+		key := resourceKeyOf(task)
+		if key == "" {
+			wp.finish(task)
+			continue
+		}
+		if !wp.resources.acquire(key, task) {
+			// another worker holds the key and will run the task once it is done
+			if wp.typed != nil {
+				wp.typed.end(task)
+			}
+			continue
+		}
+		wp.finish(task)
+		for task, ok = wp.resources.next(key); ok; task, ok = wp.resources.next(key) {
+			if wp.typed != nil {
+				wp.typed.begin(task)
+			}
+			wp.finish(task)
+		}
+	}
+}
+
+// next returns the next task for a worker, from the per-type queues with WeightByType and from
+// the task channel otherwise. It reports false once every task has been handed out.
+func (wp *NewWorkerPool) next() (MultiTask, bool) {
+	if wp.typed != nil {
+		return wp.typed.pop()
+	}
+	task, ok := <-wp.MultiTaskChan
+	return task, ok
+}
+
+// dispatch hands a task to the workers
+func (wp *NewWorkerPool) dispatch(task MultiTask) {
+	if wp.typed != nil {
+		wp.typed.push(task)
+		return
+	}
+	wp.MultiTaskChan <- task
+}
+
+// finish processes a task and reports its completion
+func (wp *NewWorkerPool) finish(task MultiTask) {
+	err := wp.process(task)
+	if wp.typed != nil {
+		wp.typed.end(task)
 	}
+	if wp.completions != nil {
+		wp.completions <- completion{task: task, err: err}
+	}
+	wp.wg.Done()
 }
 
-// Run executes all tasks using the configured number of workers
-func (wp *NewWorkerPool) Run() {
-	// initialize the task channel
-	wp.MultiTaskChan = make(chan MultiTask, len(wp.MultiTasks))
+// process runs a single task, consulting the circuit breaker of its type when one is configured.
+// A failed task is retried up to MaxRetries times and then compensated if it is a
+// CompensableTask. The value of a ResultTask is collected for Results.
+func (wp *NewWorkerPool) process(task MultiTask) error {
+	name := typeNameOf(task)
+	if wp.Breaker != nil && !wp.Breaker.Allow(name) {
+		wp.rejected.Add(1)
+		wp.summary.completed(task, 0, ErrCircuitOpen)
+		Log.Warn("task skipped", slog.String("task_type", name), slog.Any("error", ErrCircuitOpen))
+		return ErrCircuitOpen
+	}
+
+	start := time.Now()
+	value, hasResult, err := runMultiTask(task)
+	for retry := 1; err != nil && retry <= wp.MaxRetries; retry++ {
+		Log.Info("task retried", slog.String("task_type", name), slog.Int("retry", retry),
+			slog.Int("max_retries", wp.MaxRetries), slog.Any("error", err))
+		value, hasResult, err = runMultiTask(task)
+	}
+	elapsed := time.Since(start)
+	wp.summary.completed(task, elapsed, err)
+	attrs := []slog.Attr{slog.String("task_type", name), slog.Duration("duration", elapsed)}
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	Log.LogAttrs(context.Background(), level, "task finished", attrs...)
+	if hasResult {
+		wp.results.add(MultiTaskResult{Task: task, Value: value, Err: err})
+	}
+	if wp.Breaker != nil {
+		wp.Breaker.Record(name, err)
+	}
+	if err != nil {
+		wp.failed.Add(1)
+		wp.compensate(task, err)
+		return err
+	}
+	wp.processed.Add(1)
+	return nil
+}
+
+// Run executes all tasks using the configured number of workers and returns a summary of the batch.
+// Tasks declaring dependencies only run once their dependencies finished; an invalid dependency
+// graph (unknown or duplicate IDs, cycles) is reported as an error before any task runs.
+// Nil entries in MultiTasks are skipped, or rejected with ErrNilTask when RejectNilTasks is set.
+func (wp *NewWorkerPool) Run() (Summary, error) {
+	tasks, err := wp.validTasks()
+	if err != nil {
+		return Summary{}, err
+	}
+
+	var graph *taskGraph
+	if hasDependencies(tasks) {
+		if graph, err = newTaskGraph(tasks); err != nil {
+			return Summary{}, err
+		}
+		wp.completions = make(chan completion, len(tasks))
+	}
+
+	// initialize the task channel, or the per-type queues sharing the workers by weight
+	if len(wp.WeightByType) > 0 {
+		wp.typed = newTypeQueues(wp.WeightByType)
+	} else {
+		wp.MultiTaskChan = make(chan MultiTask, len(tasks))
+	}
 
 	// start workers
 	for i := 0; i < wp.Concurrency; i++ {
@@ -66,13 +247,75 @@ func (wp *NewWorkerPool) Run() {
 	}
 
 	// send tasks to the tasks channel
-	wp.wg.Add(len(wp.MultiTasks))
-	for _, task := range wp.MultiTasks {
-		wp.MultiTaskChan <- task
+	wp.wg.Add(len(tasks))
+	wp.summary.dispatched()
+	if graph != nil {
+		wp.dispatchGraph(graph)
+	} else {
+		for _, task := range tasks {
+			wp.dispatch(task)
+		}
 	}
 	// close the task channel after all tasks are sent to the channel to avoid deadlock
-	close(wp.MultiTaskChan)
+	if wp.typed != nil {
+		wp.typed.close()
+	} else {
+		close(wp.MultiTaskChan)
+	}
 
 	// wait for all tasks to complete
 	wp.wg.Wait()
+	return wp.summary.summary(len(tasks)), nil
+}
+
+// validTasks returns MultiTasks without nil entries, or ErrNilTask if RejectNilTasks is set
+// and there is one. Both nil interfaces and typed nil pointers (e.g. (*EmailTask)(nil)) count.
+func (wp *NewWorkerPool) validTasks() ([]MultiTask, error) {
+	tasks := make([]MultiTask, 0, len(wp.MultiTasks))
+	for i, task := range wp.MultiTasks {
+		if !isNilTask(task) {
+			tasks = append(tasks, task)
+			continue
+		}
+		if wp.RejectNilTasks {
+			return nil, fmt.Errorf("%w at index %d", ErrNilTask, i)
+		}
+		Log.Warn("nil task skipped", slog.Int("index", i))
+	}
+	return tasks, nil
+}
+
+// isNilTask reports whether a task is a nil interface or wraps a nil pointer
+func isNilTask(task MultiTask) bool {
+	if task == nil {
+		return true
+	}
+	v := reflect.ValueOf(task)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+// dispatchGraph sends tasks to the workers as their dependencies complete and skips the tasks
+// whose dependencies failed. It returns once every task has been dispatched or skipped.
+func (wp *NewWorkerPool) dispatchGraph(graph *taskGraph) {
+	remaining := len(graph.tasks)
+	for _, i := range graph.roots() {
+		wp.dispatch(graph.tasks[i])
+		remaining--
+	}
+	for remaining > 0 {
+		c := <-wp.completions
+		ready, skipped := graph.complete(c.task, c.err)
+		for _, i := range skipped {
+			task := graph.tasks[i]
+			wp.failed.Add(1)
+			wp.summary.completed(task, 0, ErrDependencyFailed)
+			Log.Warn("task skipped", slog.String("task_type", typeNameOf(task)), slog.Any("error", ErrDependencyFailed))
+			wp.wg.Done()
+			remaining--
+		}
+		for _, i := range ready {
+			wp.dispatch(graph.tasks[i])
+			remaining--
+		}
+	}
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// countedTask is a MultiTask counting its runs
+type countedTask struct {
+	runs *atomic.Int32
+}
+
+func (c *countedTask) Process() { c.runs.Add(1) }
+
+// TestNilMultiTasks runs batches with nil entries, as nil interfaces and as typed nil pointers,
+// and checks that they are skipped, or rejected before anything runs with RejectNilTasks
+func TestNilMultiTasks(t *testing.T) {
+	var typedNil *countedTask
+	tests := []struct {
+		name      string
+		nils      []MultiTask // Nil entries mixed into two valid tasks
+		reject    bool
+		wantRuns  int32
+		wantIndex string // Index named by ErrNilTask, empty if the batch runs
+	}{
+		{"no nil entries", nil, false, 2, ""},
+		{"nil interface skipped", []MultiTask{nil}, false, 2, ""},
+		{"typed nil pointer skipped", []MultiTask{typedNil}, false, 2, ""},
+		{"both skipped", []MultiTask{nil, typedNil}, false, 2, ""},
+		{"nil interface rejected", []MultiTask{nil}, true, 0, "index 1"},
+		{"typed nil pointer rejected", []MultiTask{typedNil}, true, 0, "index 1"},
+		{"rejection without nil entries", nil, true, 2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runs atomic.Int32
+			tasks := []MultiTask{&countedTask{&runs}}
+			tasks = append(tasks, tt.nils...)
+			tasks = append(tasks, &countedTask{&runs})
+
+			wp := NewWorkerPool{MultiTasks: tasks, Concurrency: 2, RejectNilTasks: tt.reject}
+			summary, err := wp.Run()
+			switch {
+			case tt.wantIndex == "" && err != nil:
+				t.Fatalf("Run() = %v, want nil", err)
+			case tt.wantIndex != "" && (!errors.Is(err, ErrNilTask) || !strings.Contains(err.Error(), tt.wantIndex)):
+				t.Fatalf("Run() = %v, want ErrNilTask at %s", err, tt.wantIndex)
+			}
+			if got := runs.Load(); got != tt.wantRuns || summary.Total != int(tt.wantRuns) {
+				t.Errorf("%d tasks ran, summary total %d; want %d", got, summary.Total, tt.wantRuns)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go_concurrency_helpers/testutil"
+)
+
+// TestMaxTasksPerWorker runs ten tasks on a single worker and checks from the pool's debug
+// records that the worker is replaced after exactly MaxTasksPerWorker tasks, without losing or
+// reordering a task across the handoff
+func TestMaxTasksPerWorker(t *testing.T) {
+	const tasks = 10
+	tests := []struct {
+		name         string
+		maxTasks     int
+		wantRecycled int
+	}{
+		{"never recycle", 0, 0},
+		{"after every task", 1, 10},
+		{"after three tasks", 3, 3},
+		{"after the whole batch", 10, 1},
+		{"limit above the batch", 20, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var records []record
+			previous := Log
+			Log = slog.New(recordingHandler{level: slog.LevelDebug, mu: &mu, records: &records})
+			defer func() { Log = previous }()
+
+			var ran []int
+			wp := WorkerPool{Concurrency: 1, MaxTasksPerWorker: tt.maxTasks}
+			for i := range tasks {
+				wp.Tasks = append(wp.Tasks, Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+					mu.Lock()
+					ran = append(ran, i+1)
+					mu.Unlock()
+					return nil, nil
+				}})
+			}
+			if err := wp.Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if want := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}; !slices.Equal(ran, want) {
+				t.Errorf("processed %v, want %v", ran, want)
+			}
+			recycled := 0
+			for _, r := range records {
+				if r.msg != "worker recycled" {
+					continue
+				}
+				recycled++
+				if n := r.attrs["tasks"].Int64(); n != int64(tt.maxTasks) {
+					t.Errorf("worker recycled after %d tasks, want %d", n, tt.maxTasks)
+				}
+			}
+			if recycled != tt.wantRecycled {
+				t.Errorf("worker recycled %d times, want %d", recycled, tt.wantRecycled)
+			}
+		})
+	}
+}
+
+// TestZeroValueTasks checks that zero-value Tasks are valid: Id 0 is tracked like any other Id,
+// and a Task without Work runs the simulated processing, which gives up when the pool is cancelled
+func TestZeroValueTasks(t *testing.T) {
+	work := func(done <-chan struct{}) (any, error) { return "ok", nil }
+	tests := []struct {
+		name           string
+		tasks          []Task
+		wantErr        error
+		wantCompleted  []int
+		wantUnfinished []int
+	}{
+		{"zero Id with Work", []Task{{Work: work}, {Id: 1, Work: work}}, nil, []int{0, 1}, []int{}},
+		{"zero Task cancelled while simulating", []Task{{}}, context.Canceled, []int{}, []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			wp := WorkerPool{Tasks: tt.tasks, Concurrency: 2}
+			if tt.wantErr != nil {
+				time.AfterFunc(20*time.Millisecond, cancel)
+			}
+			results, err := wp.RunWithContext(ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RunWithContext() = %v, want %v", err, tt.wantErr)
+			}
+			completed, unfinished := wp.Completed(), wp.Unfinished()
+			slices.Sort(completed)
+			if !slices.Equal(completed, tt.wantCompleted) || !slices.Equal(unfinished, tt.wantUnfinished) {
+				t.Errorf("completed %v, unfinished %v; want %v, %v", completed, unfinished, tt.wantCompleted, tt.wantUnfinished)
+			}
+			if len(results) != len(tt.wantCompleted) {
+				t.Errorf("%d results, want %d", len(results), len(tt.wantCompleted))
+			}
+		})
+	}
+}
+
+// TestRampUpStaggersWorkers keeps every worker busy with a task that holds it and checks on a
+// FakeClock that the workers start RampUp/Concurrency apart, or all at once without RampUp
+func TestRampUpStaggersWorkers(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		rampUp      time.Duration
+		wantStarts  []time.Duration // Fake time at which each worker took its first task
+	}{
+		{"no ramp-up", 4, 0, []time.Duration{0, 0, 0, 0}},
+		{"4 workers over 400ms", 4, 400 * time.Millisecond, []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}},
+		{"3 workers over 1s", 3, time.Second, []time.Duration{0, time.Second / 3, 2 * time.Second / 3}},
+		{"single worker", 1, time.Second, []time.Duration{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			clock := NewFakeClock(time.Unix(0, 0))
+			begin := clock.Now()
+			var mu sync.Mutex
+			var starts []time.Duration
+			release := make(chan struct{})
+			wp := &WorkerPool{Concurrency: tt.concurrency, RampUp: tt.rampUp, Clock: clock}
+			for i := range tt.concurrency {
+				wp.Tasks = append(wp.Tasks, Task{Id: i + 1, Work: func(done <-chan struct{}) (any, error) {
+					mu.Lock()
+					starts = append(starts, clock.Now().Sub(begin))
+					mu.Unlock()
+					<-release
+					return nil, nil
+				}})
+			}
+			finished := make(chan struct{})
+			go func() {
+				wp.Run()
+				close(finished)
+			}()
+			started := func() int {
+				mu.Lock()
+				defer mu.Unlock()
+				return len(starts)
+			}
+
+			// advance to the next worker's turn once the previous ones took their task
+			for i := range tt.wantStarts {
+				for wait := time.Now().Add(5 * time.Second); started() < i+1; time.Sleep(time.Millisecond) {
+					if time.Now().After(wait) {
+						t.Fatalf("%d workers started at %v, want %d", started(), clock.Now().Sub(begin), i+1)
+					}
+					if next := begin.Add(tt.rampUp * time.Duration(i) / time.Duration(tt.concurrency)); clock.Now().Before(next) {
+						clock.Advance(next.Sub(clock.Now()))
+					}
+				}
+			}
+			close(release)
+			<-finished
+
+			if !slices.Equal(starts, tt.wantStarts) {
+				t.Errorf("workers started at %v, want %v", starts, tt.wantStarts)
+			}
+		})
+	}
+}
+
+// TestRampUpClosedEarly closes a pool before its last workers' turn and checks that their
+// delayed start is abandoned without leaking a goroutine or a timer
+func TestRampUpClosedEarly(t *testing.T) {
+	testutil.LeakCheck(t)
+	clock := NewFakeClock(time.Unix(0, 0))
+	wp := &WorkerPool{Concurrency: 4, RampUp: time.Minute, Clock: clock}
+	if err := wp.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wp.Submit(Task{Id: 1, Work: func(done <-chan struct{}) (any, error) { return nil, nil }}); err != nil {
+		t.Fatal(err)
+	}
+	wp.Close()
+	for wait := time.Now().Add(5 * time.Second); clock.Waiters() > 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(wait) {
+			t.Fatalf("%d timers still on the clock after Close", clock.Waiters())
+		}
+	}
+}
+
+// TestTrySubmitFullQueue fills the bounded queue while the only worker is stuck, then checks on
+// a FakeClock that TrySubmit gives up with ErrQueueFull exactly after its wait, or succeeds if a
+// slot frees up in time, in every bounded queue mode
+func TestTrySubmitFullQueue(t *testing.T) {
+	const wait = 50 * time.Millisecond
+	tests := []struct {
+		name     string
+		stack    bool
+		priority bool
+		freeSlot bool // Release the stuck worker before the wait is over
+	}{
+		{"shared channel stays full", false, false, false},
+		{"stack stays full", true, false, false},
+		{"priority queue stays full", false, true, false},
+		{"shared channel drains in time", false, false, true},
+		{"stack drains in time", true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			clock := NewFakeClock(time.Unix(0, 0))
+			wp := &WorkerPool{Concurrency: 1, Clock: clock, Stack: tt.stack, Prioritize: tt.priority}
+			if err := wp.Start(); err != nil {
+				t.Fatal(err)
+			}
+			started, release := make(chan struct{}), make(chan struct{})
+			noop := func(done <-chan struct{}) (any, error) { return nil, nil }
+			if err := wp.Submit(Task{Id: 1, Work: func(done <-chan struct{}) (any, error) {
+				close(started)
+				<-release
+				return nil, nil
+			}}); err != nil {
+				t.Fatal(err)
+			}
+			<-started
+			if err := wp.Submit(Task{Id: 2, Work: noop}); err != nil {
+				t.Fatal(err)
+			}
+
+			errc := make(chan error, 1)
+			go func() { errc <- wp.TrySubmit(Task{Id: 3, Work: noop}, wait) }()
+			for deadline := time.Now().Add(5 * time.Second); clock.Waiters() == 0; time.Sleep(time.Millisecond) {
+				if time.Now().After(deadline) {
+					t.Fatal("TrySubmit never waited on the clock")
+				}
+			}
+			clock.Advance(wait - time.Millisecond)
+			select {
+			case err := <-errc:
+				t.Fatalf("TrySubmit returned %v before its wait was over", err)
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			if tt.freeSlot {
+				close(release)
+			} else {
+				clock.Advance(time.Millisecond)
+			}
+			var err error
+			select {
+			case err = <-errc:
+			case <-time.After(5 * time.Second):
+				t.Fatal("TrySubmit still blocked")
+			}
+			if !tt.freeSlot {
+				close(release)
+			}
+			wp.Close()
+
+			want, completed := ErrQueueFull, []int{1, 2}
+			if tt.freeSlot {
+				want, completed = nil, []int{1, 2, 3}
+			}
+			if !errors.Is(err, want) || (want == nil && err != nil) {
+				t.Errorf("TrySubmit() = %v, want %v", err, want)
+			}
+			if got := wp.Completed(); !slices.Equal(got, completed) {
+				t.Errorf("completed %v, want %v", got, completed)
+			}
+		})
+	}
+}
+
+// TestShouldStopYield runs a busy task that never selects on its done channel but checks
+// ShouldStop on every iteration, and checks that it yields when the pool is cancelled and only
+// then: a graceful drain or a TaskTimeout leaves ShouldStop false
+func TestShouldStopYield(t *testing.T) {
+	tests := []struct {
+		name      string
+		pool      func(clock *FakeClock) *WorkerPool
+		trigger   func(wp *WorkerPool, clock *FakeClock, cancel context.CancelFunc)
+		open      bool // Submit to a started pool and Close it last, instead of RunWithContext
+		wantYield bool
+	}{
+		{"Cancel", func(*FakeClock) *WorkerPool { return &WorkerPool{Concurrency: 1} },
+			func(wp *WorkerPool, _ *FakeClock, _ context.CancelFunc) { wp.Cancel() }, false, true},
+		{"context cancelled", func(*FakeClock) *WorkerPool { return &WorkerPool{Concurrency: 1} },
+			func(_ *WorkerPool, _ *FakeClock, cancel context.CancelFunc) { cancel() }, false, true},
+		{"Deadline passed", func(clock *FakeClock) *WorkerPool {
+			return &WorkerPool{Concurrency: 1, Clock: clock, Deadline: clock.Now().Add(time.Minute)}
+		}, func(_ *WorkerPool, clock *FakeClock, _ context.CancelFunc) { clock.Advance(time.Minute) }, false, true},
+		{"graceful drain", func(*FakeClock) *WorkerPool { return &WorkerPool{Concurrency: 1} },
+			func(*WorkerPool, *FakeClock, context.CancelFunc) {}, false, false},
+		// the timed out attempt keeps running in the background, a closed pool would stop it
+		{"TaskTimeout", func(clock *FakeClock) *WorkerPool {
+			return &WorkerPool{Concurrency: 1, Clock: clock, TaskTimeout: time.Minute}
+		}, func(_ *WorkerPool, clock *FakeClock, _ context.CancelFunc) { clock.Advance(time.Minute) }, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.LeakCheck(t)
+			clock := NewFakeClock(time.Unix(0, 0))
+			wp := tt.pool(clock)
+			started, finish := make(chan struct{}), make(chan struct{})
+			yielded := make(chan bool, 1)
+			task := Task{Id: 1, Work: func(done <-chan struct{}) (any, error) {
+				close(started)
+				for {
+					if wp.ShouldStop() {
+						yielded <- true
+						return nil, ErrTaskCancelled
+					}
+					select {
+					case <-finish:
+						yielded <- false
+						return nil, nil
+					default:
+					}
+				}
+			}}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			ran := make(chan struct{})
+			if tt.open {
+				if err := wp.Start(); err != nil {
+					t.Fatal(err)
+				}
+				if err := wp.Submit(task); err != nil {
+					t.Fatal(err)
+				}
+				close(ran)
+				defer wp.Close()
+			} else {
+				wp.Tasks = []Task{task}
+				go func() {
+					defer close(ran)
+					wp.RunWithContext(ctx)
+				}()
+			}
+
+			<-started
+			tt.trigger(wp, clock, cancel)
+			select {
+			case y := <-yielded:
+				if !tt.wantYield {
+					t.Fatalf("task returned with yield %v before it was told to finish", y)
+				}
+			case <-time.After(20 * time.Millisecond):
+				if tt.wantYield {
+					t.Fatal("task did not yield after the pool was cancelled")
+				}
+				close(finish)
+				if <-yielded {
+					t.Error("task yielded, want ShouldStop false")
+				}
+			}
+			<-ran
+		})
+	}
+}